@@ -1,11 +1,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/bscott/ts-chat/internal/server"
 	"github.com/spf13/pflag"
@@ -17,24 +19,77 @@ var (
 	Commit  = "unknown"
 )
 
-// Default configuration values
-const (
-	defaultPort        = 2323
-	defaultRoomName    = "Chat Room"
-	defaultMaxUsers    = 10
-	defaultHostname    = "chatroom"
-	defaultHistorySize = 50
-)
-
 type config struct {
-	Port            int
-	RoomName        string
-	MaxUsers        int
-	EnableTailscale bool
-	HostName        string
-	EnableHistory   bool
-	HistorySize     int
-	PlainText       bool
+	Ports              []int
+	ListenAddr         string
+	AlsoListenTCP      bool
+	RoomName           string
+	MaxUsers           int
+	MaxConnections     int
+	EnableTailscale    bool
+	HostName           string
+	AuthKeyFile        string
+	TSConnectTimeout   time.Duration
+	EnableHistory      bool
+	HistorySize        int
+	HistoryMaxAge      time.Duration
+	HistoryBudget      int64
+	HistoryReplay      int
+	HistorySystem      bool
+	HistoryKinds       string
+	PlainText          bool
+	Accessible         bool
+	Hyperlinks         bool
+	Compact            bool
+	Encoding           string
+	LineEnding         string
+	BannerFile         string
+	BannerWidth        int
+	BannerAnimate      bool
+	BannerAnimateDelay time.Duration
+	MOTDFile           string
+	PinnedFile         string
+	FeedbackFile       string
+	AuditLog           string
+	StatusPort         int
+	StatsInterval      time.Duration
+	NickHandshake      bool
+	WriteTimeout       time.Duration
+	ShutdownTimeout    time.Duration
+	HeartbeatInterval  time.Duration
+	IdleTimeout        time.Duration
+	IdleWarning        time.Duration
+	SingleSession      bool
+	MinNicknameLen     int
+	MaxNicknameLen     int
+	MaxMessageLen      int
+	Scrollback         int
+	JoinLeaveCoalesce  time.Duration
+	QuietJoins         bool
+	NickCaseFold       bool
+	AdminToken         string
+	ReservedNicks      string
+	ReservedNicksFile  string
+	ReservedNickPass   string
+	AnnouncePersist    bool
+	AuthMode           string
+	AuthPassword       string
+	ConnectChallenge   string
+	MaxNickAttempts    int
+	ReconnectInterval  time.Duration
+	ReadPollInterval   time.Duration
+	ReadBufferSize     int
+	WriteBufferSize    int
+	RoomRateLimit      float64
+	JoinCooldown       time.Duration
+	FloodKick          int
+	FloodKickWindow    time.Duration
+	FloodBanDuration   time.Duration
+	QuietHours         string
+	QuietTimezone      string
+	OnJoinCmd          string
+	OnLeaveCmd         string
+	BusURL             string
 }
 
 func main() {
@@ -53,27 +108,91 @@ func main() {
 	log.Printf("Chat Tails %s (commit: %s)", Version, Commit)
 
 	if cfg.EnableTailscale {
-		log.Printf("Starting with hostname: %s, port: %d", cfg.HostName, cfg.Port)
-		
+		log.Printf("Starting with hostname: %s, %s", cfg.HostName, server.FormatPorts(cfg.Ports))
+
 		// Check for auth key
 		if os.Getenv("TS_AUTHKEY") == "" {
 			log.Println("Warning: TS_AUTHKEY environment variable not set. Tailscale mode may not work properly.")
 			log.Println("Set TS_AUTHKEY=tskey-... to authenticate with Tailscale")
 		}
 	} else {
-		log.Printf("Starting Chat Tails on port: %d", cfg.Port)
+		log.Printf("Starting Chat Tails on %s", server.FormatPorts(cfg.Ports))
 	}
 
 	// Create and start the chat server
 	chatServer, err := server.NewServer(server.Config{
-		Port:            cfg.Port,
-		RoomName:        cfg.RoomName,
-		MaxUsers:        cfg.MaxUsers,
-		EnableTailscale: cfg.EnableTailscale,
-		HostName:        cfg.HostName,
-		EnableHistory:   cfg.EnableHistory,
-		HistorySize:     cfg.HistorySize,
-		PlainText:       cfg.PlainText,
+		Port:               cfg.Ports[0],
+		ExtraPorts:         cfg.Ports[1:],
+		ListenAddr:         cfg.ListenAddr,
+		AlsoListenTCP:      cfg.AlsoListenTCP,
+		RoomName:           cfg.RoomName,
+		MaxUsers:           cfg.MaxUsers,
+		MaxConnections:     cfg.MaxConnections,
+		EnableTailscale:    cfg.EnableTailscale,
+		HostName:           cfg.HostName,
+		AuthKeyFile:        cfg.AuthKeyFile,
+		TSConnectTimeout:   cfg.TSConnectTimeout,
+		EnableHistory:      cfg.EnableHistory,
+		HistorySize:        cfg.HistorySize,
+		HistoryMaxAge:      cfg.HistoryMaxAge,
+		HistoryBudget:      cfg.HistoryBudget,
+		HistoryReplay:      cfg.HistoryReplay,
+		HistorySystem:      cfg.HistorySystem,
+		HistoryKinds:       cfg.HistoryKinds,
+		PlainText:          cfg.PlainText,
+		Accessible:         cfg.Accessible,
+		Hyperlinks:         cfg.Hyperlinks,
+		Compact:            cfg.Compact,
+		Encoding:           cfg.Encoding,
+		LineEnding:         cfg.LineEnding,
+		BannerFile:         cfg.BannerFile,
+		BannerWidth:        cfg.BannerWidth,
+		BannerAnimate:      cfg.BannerAnimate,
+		BannerAnimateDelay: cfg.BannerAnimateDelay,
+		MOTDFile:           cfg.MOTDFile,
+		PinnedFile:         cfg.PinnedFile,
+		FeedbackFile:       cfg.FeedbackFile,
+		AuditLog:           cfg.AuditLog,
+		StatusPort:         cfg.StatusPort,
+		Version:            Version,
+		StatsInterval:      cfg.StatsInterval,
+		NickHandshake:      cfg.NickHandshake,
+		WriteTimeout:       cfg.WriteTimeout,
+		ShutdownTimeout:    cfg.ShutdownTimeout,
+		HeartbeatInterval:  cfg.HeartbeatInterval,
+		IdleTimeout:        cfg.IdleTimeout,
+		IdleWarning:        cfg.IdleWarning,
+		SingleSession:      cfg.SingleSession,
+		MinNicknameLen:     cfg.MinNicknameLen,
+		MaxNicknameLen:     cfg.MaxNicknameLen,
+		MaxMessageLen:      cfg.MaxMessageLen,
+		Scrollback:         cfg.Scrollback,
+		JoinLeaveCoalesce:  cfg.JoinLeaveCoalesce,
+		QuietJoins:         cfg.QuietJoins,
+		NickCaseFold:       cfg.NickCaseFold,
+		AdminToken:         cfg.AdminToken,
+		ReservedNicks:      cfg.ReservedNicks,
+		ReservedNicksFile:  cfg.ReservedNicksFile,
+		ReservedNickPass:   cfg.ReservedNickPass,
+		AnnouncePersist:    cfg.AnnouncePersist,
+		AuthMode:           cfg.AuthMode,
+		AuthPassword:       cfg.AuthPassword,
+		ConnectChallenge:   cfg.ConnectChallenge,
+		MaxNickAttempts:    cfg.MaxNickAttempts,
+		ReconnectInterval:  cfg.ReconnectInterval,
+		ReadPollInterval:   cfg.ReadPollInterval,
+		ReadBufferSize:     cfg.ReadBufferSize,
+		WriteBufferSize:    cfg.WriteBufferSize,
+		RoomRateLimit:      cfg.RoomRateLimit,
+		JoinCooldown:       cfg.JoinCooldown,
+		FloodKick:          cfg.FloodKick,
+		FloodKickWindow:    cfg.FloodKickWindow,
+		FloodBanDuration:   cfg.FloodBanDuration,
+		QuietHours:         cfg.QuietHours,
+		QuietTimezone:      cfg.QuietTimezone,
+		OnJoinCmd:          cfg.OnJoinCmd,
+		OnLeaveCmd:         cfg.OnLeaveCmd,
+		BusURL:             cfg.BusURL,
 	})
 	if err != nil {
 		log.Fatalf("Failed to create server: %v", err)
@@ -87,11 +206,16 @@ func main() {
 	}()
 
 	if cfg.EnableTailscale {
-		log.Printf("Chat server started. Users can connect via: telnet %s.ts.net %d", cfg.HostName, cfg.Port)
-	} else {
-		log.Printf("Chat server started. Users can connect via: telnet localhost %d", cfg.Port)
+		log.Printf("Chat server started. Users can connect via: telnet %s.ts.net %d", cfg.HostName, cfg.Ports[0])
 	}
-	
+	if !cfg.EnableTailscale || cfg.AlsoListenTCP {
+		host := "localhost"
+		if cfg.ListenAddr != "" {
+			host = cfg.ListenAddr
+		}
+		log.Printf("Chat server started. Users can connect via: telnet %s %d", host, cfg.Ports[0])
+	}
+
 	log.Print("Press Ctrl+C to stop the server")
 
 	// Wait for interrupt signal
@@ -101,7 +225,12 @@ func main() {
 
 	log.Print("Shutting down server...")
 	if err := chatServer.Stop(); err != nil {
-		log.Printf("Error shutting down server: %v", err)
+		if errors.Is(err, server.ErrShutdownTimeout) {
+			log.Printf("Forced shutdown after %s: %v", cfg.ShutdownTimeout, err)
+		} else {
+			log.Printf("Error shutting down server: %v", err)
+		}
+		os.Exit(1)
 	}
 	os.Exit(0)
 }
@@ -109,16 +238,79 @@ func main() {
 func parseFlags() (config, bool) {
 	var cfg config
 	var showVersion bool
+	defaults := server.DefaultConfig()
 
 	// Define command-line flags
-	pflag.IntVarP(&cfg.Port, "port", "p", defaultPort, "TCP port to listen on")
-	pflag.StringVarP(&cfg.RoomName, "room-name", "r", defaultRoomName, "Chat room name")
-	pflag.IntVarP(&cfg.MaxUsers, "max-users", "m", defaultMaxUsers, "Maximum allowed users")
+	pflag.IntSliceVarP(&cfg.Ports, "port", "p", []int{defaults.Port}, "TCP port to listen on; repeat or comma-separate to listen on several, e.g. 23,2323")
+	pflag.StringVarP(&cfg.ListenAddr, "listen-addr", "l", "", "Address to bind in TCP mode, e.g. 127.0.0.1 (default all interfaces)")
+	pflag.StringVarP(&cfg.RoomName, "room-name", "r", defaults.RoomName, "Chat room name")
+	pflag.IntVarP(&cfg.MaxUsers, "max-users", "m", defaults.MaxUsers, "Maximum allowed users (at least 1)")
+	pflag.IntVar(&cfg.MaxConnections, "max-total-connections", 0, "Maximum open connections across the server, including users still logging in (0 disables)")
 	pflag.BoolVarP(&cfg.EnableTailscale, "tailscale", "t", false, "Enable Tailscale mode")
-	pflag.StringVarP(&cfg.HostName, "hostname", "H", defaultHostname, "Tailscale hostname (only used if --tailscale is enabled)")
+	pflag.BoolVar(&cfg.AlsoListenTCP, "also-listen-tcp", false, "In Tailscale mode, also accept plain TCP connections on --listen-addr and --port")
+	pflag.StringVarP(&cfg.HostName, "hostname", "H", defaults.HostName, "Tailscale hostname (only used if --tailscale is enabled)")
+	pflag.StringVar(&cfg.AuthKeyFile, "ts-authkey-file", "", "Read the Tailscale auth key from this file instead of $TS_AUTHKEY")
+	pflag.DurationVar(&cfg.TSConnectTimeout, "ts-connect-timeout", defaults.TSConnectTimeout, "Keep retrying Tailscale bring-up with backoff for this long (0 tries once)")
 	pflag.BoolVar(&cfg.EnableHistory, "history", false, "Enable message history for new users")
-	pflag.IntVar(&cfg.HistorySize, "history-size", defaultHistorySize, "Number of messages to keep in history")
+	pflag.IntVar(&cfg.HistorySize, "history-size", defaults.HistorySize, "Number of messages to keep in history")
+	pflag.BoolVar(&cfg.HistorySystem, "history-include-system", defaults.HistorySystem, "Keep join/leave and other system notices in history (false still shows them live)")
+	pflag.StringVar(&cfg.HistoryKinds, "history-kinds", "", "Comma-separated message kinds kept in history: user, action, system, join, leave, presence, announcement (default all)")
+	pflag.IntVar(&cfg.HistoryReplay, "history-replay-count", defaults.HistoryReplay, "Recent messages shown to users when they join (0 shows none; -1 shows all stored)")
+	pflag.DurationVar(&cfg.HistoryMaxAge, "history-max-age", 0, "Drop history messages older than this, e.g. 24h (0 keeps them until pushed out by size)")
+	pflag.Int64Var(&cfg.HistoryBudget, "history-memory-budget", 0, "Approximate bytes of history kept across all rooms; the least recently active rooms lose their oldest messages first (0 disables)")
 	pflag.BoolVar(&cfg.PlainText, "plain-text", false, "Disable ANSI formatting (for Windows telnet compatibility)")
+	pflag.StringVar(&cfg.BannerFile, "banner-file", "", "Path to a file with a custom welcome banner")
+	pflag.StringVar(&cfg.Encoding, "encoding", defaults.Encoding, "Character encoding for clients: utf-8, cp437, latin1 or cp1252")
+	pflag.StringVar(&cfg.LineEnding, "line-ending", defaults.LineEnding, "Line ending for plain-text output: crlf (telnet) or lf (pipes and scripts)")
+	pflag.BoolVar(&cfg.Accessible, "a11y", false, "Use a color-blind-safe, high-contrast theme with text prefixes such as [SYS] and [YOU]")
+	pflag.BoolVar(&cfg.Compact, "compact", false, "Start clients in compact mode for small screens: no banner or boxes, one-line notices (/compact off restores the full layout)")
+	pflag.BoolVar(&cfg.Hyperlinks, "hyperlinks", false, "Make http(s) URLs in chat clickable with OSC 8 escape sequences (plain-text clients see the raw URL)")
+	pflag.IntVar(&cfg.BannerWidth, "banner-width", 0, "Center the banner for line-mode clients in this many columns, using a one-line title if it doesn't fit (0 leaves it as is)")
+	pflag.BoolVar(&cfg.BannerAnimate, "banner-animate", false, "Print the welcome banner line by line for a retro BBS feel (not in plain-text mode)")
+	pflag.DurationVar(&cfg.BannerAnimateDelay, "banner-animate-delay", defaults.BannerAnimateDelay, "Pause between banner lines with --banner-animate")
+	pflag.StringVar(&cfg.MOTDFile, "motd-file", "", "Path to a file with a message of the day shown at join")
+	pflag.StringVar(&cfg.PinnedFile, "pinned-file", "", "Path to a file whose lines are shown as pinned messages ahead of the history replay at join")
+	pflag.StringVar(&cfg.FeedbackFile, "feedback-file", "", "Append /feedback submissions to this file (default writes them to the server log)")
+	pflag.StringVar(&cfg.AuditLog, "audit-log", "", "Append moderation actions (admin, announce, disconnect, closeroom, flood kicks) to this file as JSON lines")
+	pflag.IntVar(&cfg.StatusPort, "status-port", 0, "Serve expvar debug variables at /debug/vars on this port, bound to --listen-addr or loopback (0 disables)")
+	pflag.DurationVar(&cfg.StatsInterval, "stats-interval", 0, "Log connection and message stats at this interval, e.g. 1m (0 disables)")
+	pflag.BoolVar(&cfg.NickHandshake, "allow-nick-handshake", false, "Let plain-text clients send \"NICK <name>\" as their first line to skip the nickname prompt")
+	pflag.DurationVar(&cfg.WriteTimeout, "write-timeout", defaults.WriteTimeout, "Disconnect clients whose connection blocks a write for longer than this (0 disables)")
+	pflag.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", defaults.ShutdownTimeout, "How long to wait for connections to close on shutdown before exiting with an error")
+	pflag.DurationVar(&cfg.HeartbeatInterval, "heartbeat-interval", 0, "Send a keepalive to every telnet client at this interval and disconnect dead peers, e.g. 30s (0 disables)")
+	pflag.DurationVar(&cfg.IdleTimeout, "idle-timeout", 0, "Disconnect users who send nothing for this long, e.g. 30m (0 disables)")
+	pflag.DurationVar(&cfg.IdleWarning, "idle-warning", defaults.IdleWarning, "Warn users this long before an idle disconnect; sending anything cancels it (0 skips the warning)")
+	pflag.BoolVar(&cfg.SingleSession, "single-session", false, "Disconnect a Tailscale user's older session when they connect again")
+	pflag.IntVar(&cfg.MinNicknameLen, "min-nickname-len", defaults.MinNicknameLen, "Minimum nickname length")
+	pflag.IntVar(&cfg.MaxNicknameLen, "max-nickname-len", defaults.MaxNicknameLen, "Maximum nickname length")
+	pflag.IntVar(&cfg.MaxMessageLen, "max-message-len", defaults.MaxMessageLen, "Maximum message length in characters")
+	pflag.IntVar(&cfg.Scrollback, "scrollback", defaults.Scrollback, "Messages kept in each TUI client's scrollback (0 keeps all)")
+	pflag.DurationVar(&cfg.JoinLeaveCoalesce, "join-leave-coalesce", 0, "Batch join/leave notices within this window into one summary, e.g. 2s (0 disables)")
+	pflag.BoolVar(&cfg.QuietJoins, "quiet-joins", false, "Start clients in do-not-disturb mode with join/leave notices hidden (/dnd off shows them)")
+	pflag.BoolVar(&cfg.NickCaseFold, "nick-case-insensitive", false, "Treat nicknames differing only in case as the same, so \"Alice\" can't join alongside \"alice\"")
+	pflag.StringVar(&cfg.AdminToken, "admin-token", "", "Token that grants admin commands via /admin (defaults to $CHAT_ADMIN_TOKEN; empty disables admins)")
+	pflag.StringVar(&cfg.ReservedNicks, "reserved-nicks", "", "Comma-separated nicknames that need --reserved-nick-password to take")
+	pflag.StringVar(&cfg.ReservedNicksFile, "reserved-nicks-file", "", "File of reserved nicknames, one per line, each optionally followed by bcrypt:<hash> or sha256:<hex> (its own password) and admin")
+	pflag.StringVar(&cfg.ReservedNickPass, "reserved-nick-password", "", "Password for reserved nicknames without their own hash (defaults to $CHAT_RESERVED_NICK_PASSWORD)")
+	pflag.BoolVar(&cfg.AnnouncePersist, "announce-persist", false, "Keep /announce notices in history so late joiners see them")
+	pflag.StringVar(&cfg.AuthMode, "auth-mode", defaults.AuthMode, "How connections authenticate: none, password or tailscale")
+	pflag.StringVar(&cfg.AuthPassword, "auth-password", "", "Shared password for --auth-mode password (defaults to $CHAT_PASSWORD)")
+	pflag.StringVar(&cfg.ConnectChallenge, "connect-challenge", "", "Ask connections without a Tailscale identity a quick question before the nickname prompt to keep out bots: word or math (empty disables)")
+	pflag.IntVar(&cfg.MaxNickAttempts, "max-nick-attempts", defaults.MaxNickAttempts, "Disconnect clients after this many rejected nicknames (0 disables)")
+	pflag.DurationVar(&cfg.ReconnectInterval, "reconnect-interval", 0, "Minimum time between connections from the same IP, e.g. 1s (0 disables)")
+	pflag.DurationVar(&cfg.ReadPollInterval, "read-poll-interval", defaults.ReadPollInterval, "How often line-mode reads wake up to check for shutdown; lower is more responsive but wakes more often (0 disables)")
+	pflag.IntVar(&cfg.ReadBufferSize, "read-buffer-size", defaults.ReadBufferSize, "Bytes buffered per line-mode connection for reading (512 to 1048576)")
+	pflag.IntVar(&cfg.WriteBufferSize, "write-buffer-size", defaults.WriteBufferSize, "Bytes buffered per line-mode connection for writing; larger means fewer writes during history replay (512 to 1048576)")
+	pflag.Float64Var(&cfg.RoomRateLimit, "room-rate-limit", 0, "Room-wide chat messages per second; extra messages are dropped with a busy notice (0 disables)")
+	pflag.DurationVar(&cfg.JoinCooldown, "join-cooldown", 0, "Delay after joining before a user's first chat message is accepted, e.g. 2s; commands are exempt (0 disables)")
+	pflag.IntVar(&cfg.FloodKick, "flood-kick", 0, "Disconnect a user after this many rate-limit violations within --flood-kick-window (0 disables)")
+	pflag.DurationVar(&cfg.FloodKickWindow, "flood-kick-window", defaults.FloodKickWindow, "Window in which rate-limit violations count towards --flood-kick")
+	pflag.DurationVar(&cfg.FloodBanDuration, "flood-ban-duration", 0, "Refuse connections from the IP of a user kicked for flooding for this long, e.g. 10m (0 disables)")
+	pflag.StringVar(&cfg.QuietHours, "quiet-hours", "", "Daily window when only admins can post, e.g. 22:00-07:00 (empty disables)")
+	pflag.StringVar(&cfg.QuietTimezone, "quiet-timezone", "", "Time zone for --quiet-hours, e.g. Europe/Berlin (default the server's local zone)")
+	pflag.StringVar(&cfg.OnJoinCmd, "on-join-cmd", "", "Command run (without a shell) with the nickname and address whenever someone joins")
+	pflag.StringVar(&cfg.OnLeaveCmd, "on-leave-cmd", "", "Command run (without a shell) with the nickname and address whenever someone leaves")
+	pflag.StringVar(&cfg.BusURL, "bus-url", "", "Share the room with other servers over a pub/sub bus, e.g. redis://localhost:6379 (empty disables)")
 	pflag.BoolVarP(&showVersion, "version", "v", false, "Show version information")
 
 	// Display help message
@@ -129,5 +321,26 @@ func parseFlags() (config, bool) {
 	}
 
 	pflag.Parse()
+
+	// Honor the NO_COLOR convention (https://no-color.org) unless the flag was given explicitly
+	if !pflag.CommandLine.Changed("plain-text") && os.Getenv("NO_COLOR") != "" {
+		cfg.PlainText = true
+	}
+
+	// Prefer the environment so the password doesn't show up in process listings
+	if cfg.AuthPassword == "" {
+		cfg.AuthPassword = os.Getenv("CHAT_PASSWORD")
+	}
+	if cfg.AdminToken == "" {
+		cfg.AdminToken = os.Getenv("CHAT_ADMIN_TOKEN")
+	}
+	if cfg.ReservedNickPass == "" {
+		cfg.ReservedNickPass = os.Getenv("CHAT_RESERVED_NICK_PASSWORD")
+	}
+
+	if len(cfg.Ports) == 0 {
+		cfg.Ports = []int{defaults.Port}
+	}
+
 	return cfg, showVersion
-}
\ No newline at end of file
+}
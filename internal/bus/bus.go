@@ -0,0 +1,43 @@
+// Package bus carries chat events between chat-tails processes so that
+// several servers can share one logical room.
+package bus
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// MessageBus is a topic-based publish/subscribe transport. Payloads are
+// opaque; every subscriber of a topic, including the publisher's own
+// subscriptions, receives each payload published to it.
+type MessageBus interface {
+	// Publish sends payload to every subscriber of topic
+	Publish(topic string, payload []byte) error
+	// Subscribe returns a channel of payloads published to topic and a function
+	// that unsubscribes and closes the channel
+	Subscribe(topic string) (<-chan []byte, func(), error)
+	// Close releases the bus and closes all subscription channels
+	Close() error
+}
+
+// subscriberBuffer is how many payloads a slow subscriber may fall behind
+// before further payloads are dropped
+const subscriberBuffer = 64
+
+// Open connects to the bus named by rawURL: "redis://[:password@]host:port"
+// for Redis pub/sub, or "memory://" for a bus local to this process.
+func Open(rawURL string) (MessageBus, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bus URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "redis":
+		return DialRedis(rawURL)
+	case "memory":
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unsupported bus URL scheme %q (use redis:// or memory://)", u.Scheme)
+	}
+}
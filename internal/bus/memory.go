@@ -0,0 +1,86 @@
+package bus
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned when publishing to or subscribing on a closed bus
+var ErrClosed = errors.New("bus closed")
+
+// Memory is a MessageBus within one process, for tests and single-server setups
+type Memory struct {
+	mu     sync.Mutex
+	subs   map[string][]chan []byte
+	closed bool
+}
+
+// NewMemory returns an empty in-process bus
+func NewMemory() *Memory {
+	return &Memory{subs: make(map[string][]chan []byte)}
+}
+
+// Publish delivers payload to every current subscriber of topic without
+// blocking; a subscriber whose buffer is full misses it
+func (m *Memory) Publish(topic string, payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	for _, ch := range m.subs[topic] {
+		select {
+		case ch <- append([]byte(nil), payload...):
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a subscriber for topic
+func (m *Memory) Subscribe(topic string) (<-chan []byte, func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, nil, ErrClosed
+	}
+	ch := make(chan []byte, subscriberBuffer)
+	m.subs[topic] = append(m.subs[topic], ch)
+
+	return ch, func() { m.unsubscribe(topic, ch) }, nil
+}
+
+// unsubscribe removes ch from topic and closes it if it is still registered
+func (m *Memory) unsubscribe(topic string, ch chan []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subs := m.subs[topic]
+	for i, sub := range subs {
+		if sub == ch {
+			m.subs[topic] = append(subs[:i], subs[i+1:]...)
+			close(ch)
+			return
+		}
+	}
+}
+
+// Close closes every subscription channel
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	for topic, subs := range m.subs {
+		for _, ch := range subs {
+			close(ch)
+		}
+		delete(m.subs, topic)
+	}
+	return nil
+}
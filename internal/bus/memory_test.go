@@ -0,0 +1,87 @@
+package bus
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// receive waits briefly for one payload
+func receive(t *testing.T, ch <-chan []byte) string {
+	t.Helper()
+	select {
+	case payload, ok := <-ch:
+		if !ok {
+			t.Fatal("Subscription closed unexpectedly")
+		}
+		return string(payload)
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a payload")
+		return ""
+	}
+}
+
+func TestMemoryPublishSubscribe(t *testing.T) {
+	b := NewMemory()
+	defer b.Close()
+
+	first, unsubscribeFirst, err := b.Subscribe("room")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	second, unsubscribeSecond, _ := b.Subscribe("room")
+	defer unsubscribeSecond()
+	other, unsubscribeOther, _ := b.Subscribe("other")
+	defer unsubscribeOther()
+
+	if err := b.Publish("room", []byte("hello")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if got := receive(t, first); got != "hello" {
+		t.Errorf("First subscriber got %q, want hello", got)
+	}
+	if got := receive(t, second); got != "hello" {
+		t.Errorf("Second subscriber got %q, want hello", got)
+	}
+	select {
+	case payload := <-other:
+		t.Errorf("Subscriber of another topic got %q", payload)
+	default:
+	}
+
+	unsubscribeFirst()
+	if _, ok := <-first; ok {
+		t.Error("Expected the channel to close on unsubscribe")
+	}
+	b.Publish("room", []byte("again"))
+	if got := receive(t, second); got != "again" {
+		t.Errorf("Remaining subscriber got %q, want again", got)
+	}
+}
+
+func TestMemoryClose(t *testing.T) {
+	b := NewMemory()
+	ch, unsubscribe, _ := b.Subscribe("room")
+
+	b.Close()
+	if _, ok := <-ch; ok {
+		t.Error("Expected Close to close subscriptions")
+	}
+	unsubscribe() // must not panic after Close
+
+	if err := b.Publish("room", []byte("late")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish() after Close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	b, err := Open("memory://")
+	if err != nil {
+		t.Fatalf("Open(memory://) error = %v", err)
+	}
+	b.Close()
+
+	if _, err := Open("nats://localhost:4222"); err == nil {
+		t.Error("Expected an unsupported scheme to be rejected")
+	}
+}
@@ -0,0 +1,350 @@
+package bus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Timeouts for talking to Redis
+const (
+	redisTimeout    = 5 * time.Second // connecting and each PUBLISH round trip
+	redisRetryDelay = time.Second     // wait before resubscribing after a lost connection
+)
+
+// Redis is a MessageBus backed by Redis PUBLISH/SUBSCRIBE. It speaks just
+// enough of the RESP protocol for that, so it needs no client library.
+type Redis struct {
+	addr     string
+	password string
+	closed   atomic.Bool
+
+	mu     sync.Mutex // guards the publishing connection
+	conn   net.Conn
+	reader *bufio.Reader
+
+	subMu sync.Mutex
+	subs  map[*redisSub]struct{}
+}
+
+// redisSub is one subscription, served by its own connection
+type redisSub struct {
+	topic string
+	ch    chan []byte
+	done  chan struct{}
+	once  sync.Once
+
+	mu   sync.Mutex
+	conn net.Conn // current connection, closed to stop the reader
+}
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// DialRedis connects to the Redis server at rawURL, given as
+// "redis://[:password@]host[:port]". It connects right away so a wrong
+// address fails at startup rather than on the first message.
+func DialRedis(rawURL string) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid Redis URL %q (want redis://host:port)", rawURL)
+	}
+
+	r := &Redis{
+		addr: u.Host,
+		subs: make(map[*redisSub]struct{}),
+	}
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		r.password, _ = u.User.Password()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn, r.reader, err = r.dial(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// dial opens a connection and authenticates it
+func (r *Redis) dial() (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", r.addr, redisTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to Redis at %s: %w", r.addr, err)
+	}
+	reader := bufio.NewReader(conn)
+
+	if r.password != "" {
+		conn.SetDeadline(time.Now().Add(redisTimeout))
+		defer conn.SetDeadline(time.Time{})
+		if _, err := redisCommand(conn, reader, "AUTH", r.password); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("failed to authenticate with Redis: %w", err)
+		}
+	}
+	return conn, reader, nil
+}
+
+// Publish sends payload to topic, reconnecting first if the last publish failed
+func (r *Redis) Publish(topic string, payload []byte) error {
+	if r.closed.Load() {
+		return ErrClosed
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		conn, reader, err := r.dial()
+		if err != nil {
+			return err
+		}
+		r.conn, r.reader = conn, reader
+	}
+
+	r.conn.SetDeadline(time.Now().Add(redisTimeout))
+	_, err := redisCommand(r.conn, r.reader, "PUBLISH", topic, string(payload))
+	if err != nil {
+		r.conn.Close()
+		r.conn = nil
+		return fmt.Errorf("failed to publish to Redis: %w", err)
+	}
+	return nil
+}
+
+// Subscribe opens a dedicated connection subscribed to topic. If the
+// connection drops it is re-established until unsubscribed; payloads
+// published meanwhile are missed.
+func (r *Redis) Subscribe(topic string) (<-chan []byte, func(), error) {
+	if r.closed.Load() {
+		return nil, nil, ErrClosed
+	}
+
+	conn, reader, err := r.subscribeConn(topic)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sub := &redisSub{
+		topic: topic,
+		ch:    make(chan []byte, subscriberBuffer),
+		done:  make(chan struct{}),
+	}
+	r.subMu.Lock()
+	r.subs[sub] = struct{}{}
+	r.subMu.Unlock()
+
+	go r.readSubscription(sub, conn, reader)
+
+	return sub.ch, func() { r.unsubscribe(sub) }, nil
+}
+
+// subscribeConn dials and subscribes a new connection to topic
+func (r *Redis) subscribeConn(topic string) (net.Conn, *bufio.Reader, error) {
+	conn, reader, err := r.dial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(redisTimeout))
+	if _, err := redisCommand(conn, reader, "SUBSCRIBE", topic); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, reader, nil
+}
+
+// readSubscription forwards payloads to sub.ch until the subscription is
+// stopped, resubscribing after connection errors. It closes sub.ch on return.
+func (r *Redis) readSubscription(sub *redisSub, conn net.Conn, reader *bufio.Reader) {
+	defer close(sub.ch)
+
+	for {
+		if !sub.setConn(conn) {
+			return
+		}
+
+		err := forwardMessages(reader, sub.ch)
+		conn.Close()
+
+		for {
+			select {
+			case <-sub.done:
+				return
+			case <-time.After(redisRetryDelay):
+			}
+			log.Printf("Redis subscription to %s lost (%v); resubscribing", sub.topic, err)
+			if conn, reader, err = r.subscribeConn(sub.topic); err == nil {
+				break
+			}
+		}
+	}
+}
+
+// setConn records the connection serving sub, or closes it and reports false
+// if the subscription has been stopped
+func (s *redisSub) setConn(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		conn.Close()
+		return false
+	default:
+		s.conn = conn
+		return true
+	}
+}
+
+// stop ends the subscription and closes its connection
+func (s *redisSub) stop() {
+	s.once.Do(func() { close(s.done) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// forwardMessages reads pushed messages until the connection fails. A
+// subscriber whose buffer is full misses payloads rather than stalling Redis.
+func forwardMessages(reader *bufio.Reader, ch chan<- []byte) error {
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return err
+		}
+
+		// Pushed messages are ["message", topic, payload]
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 3 {
+			continue
+		}
+		if kind, _ := parts[0].(string); kind != "message" {
+			continue
+		}
+		payload, _ := parts[2].(string)
+
+		select {
+		case ch <- []byte(payload):
+		default:
+		}
+	}
+}
+
+func (r *Redis) unsubscribe(sub *redisSub) {
+	r.subMu.Lock()
+	delete(r.subs, sub)
+	r.subMu.Unlock()
+
+	sub.stop()
+}
+
+// Close drops the publishing connection and stops every subscription; their
+// channels close once their readers have exited
+func (r *Redis) Close() error {
+	if r.closed.Swap(true) {
+		return nil
+	}
+
+	r.mu.Lock()
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+	r.mu.Unlock()
+
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+	for sub := range r.subs {
+		sub.stop()
+		delete(r.subs, sub)
+	}
+	return nil
+}
+
+// redisCommand sends a command as a RESP array of bulk strings and reads the reply
+func redisCommand(w io.Writer, reader *bufio.Reader, args ...string) (any, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return nil, err
+	}
+	return readReply(reader)
+}
+
+// readReply reads one RESP value: simple strings and bulk strings become
+// string, integers int64, arrays []any, nulls nil and error replies an error
+func readReply(reader *bufio.Reader) (any, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("malformed Redis reply %q", line)
+	}
+	body := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("malformed Redis bulk length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("malformed Redis array length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(reader); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected Redis reply %q", line)
+	}
+}
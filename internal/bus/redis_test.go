@@ -0,0 +1,148 @@
+package bus
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRedis serves just AUTH, PUBLISH and SUBSCRIBE
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu   sync.Mutex
+	subs map[string][]net.Conn
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	f := &fakeRedis{ln: ln, password: password, subs: make(map[string][]net.Conn)}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeRedis) url() string {
+	if f.password != "" {
+		return fmt.Sprintf("redis://:%s@%s", f.password, f.ln.Addr())
+	}
+	return "redis://" + f.ln.Addr().String()
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		parts, _ := reply.([]any)
+		args := make([]string, len(parts))
+		for i, part := range parts {
+			args[i], _ = part.(string)
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[1] != f.password {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			fmt.Fprint(conn, "+OK\r\n")
+		case "SUBSCRIBE":
+			f.mu.Lock()
+			f.subs[args[1]] = append(f.subs[args[1]], conn)
+			f.mu.Unlock()
+			fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(args[1]), args[1])
+		case "PUBLISH":
+			f.mu.Lock()
+			subs := f.subs[args[1]]
+			for _, sub := range subs {
+				fmt.Fprintf(sub, "*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(args[1]), args[1], len(args[2]), args[2])
+			}
+			f.mu.Unlock()
+			fmt.Fprintf(conn, ":%d\r\n", len(subs))
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+	}
+}
+
+func TestRedisPublishSubscribe(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+
+	b, err := DialRedis(server.url())
+	if err != nil {
+		t.Fatalf("DialRedis() error = %v", err)
+	}
+	defer b.Close()
+
+	ch, unsubscribe, err := b.Subscribe("chat-tails/room/lobby")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer unsubscribe()
+
+	payload := `{"type":"message","content":"line one\r\nline two"}`
+	if err := b.Publish("chat-tails/room/lobby", []byte(payload)); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if got := receive(t, ch); got != payload {
+		t.Errorf("Received %q, want %q", got, payload)
+	}
+
+	unsubscribe()
+	for range ch {
+	}
+}
+
+func TestRedisWrongPassword(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+
+	url := strings.Replace(server.url(), ":secret@", ":wrong@", 1)
+	if _, err := DialRedis(url); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("Expected an authentication error, got %v", err)
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	input := "+OK\r\n:42\r\n$-1\r\n*2\r\n$3\r\nfoo\r\n$0\r\n\r\n-ERR boom\r\n"
+	reader := bufio.NewReader(strings.NewReader(input))
+
+	want := []any{"OK", int64(42), nil, []any{"foo", ""}}
+	for i, w := range want {
+		got, err := readReply(reader)
+		if err != nil {
+			t.Fatalf("Reply %d: unexpected error %v", i, err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(w) {
+			t.Errorf("Reply %d = %#v, want %#v", i, got, w)
+		}
+	}
+	if _, err := readReply(reader); err == nil || err.Error() != "redis: ERR boom" {
+		t.Errorf("Expected the error reply, got %v", err)
+	}
+}
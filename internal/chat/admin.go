@@ -0,0 +1,61 @@
+package chat
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrPermissionDenied is returned when a non-admin uses an admin command
+var ErrPermissionDenied = errors.New("permission denied")
+
+// isAdmin reports whether this client has authenticated with the admin token
+func (c *Client) isAdmin() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.admin
+}
+
+// elevate grants admin rights when token matches the room's admin token, and
+// returns the reply to show the user
+func (c *Client) elevate(token string) (string, error) {
+	expected := c.room.AdminToken
+	token = strings.TrimSpace(token)
+	if expected == "" || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return "Invalid admin token.", ErrPermissionDenied
+	}
+
+	c.grantAdmin("")
+	return "You are now an admin.", nil
+}
+
+// grantAdmin gives the client admin rights and records it in the audit log
+func (c *Client) grantAdmin(reason string) {
+	c.mu.Lock()
+	c.admin = true
+	c.mu.Unlock()
+	c.room.recordAudit(AuditEvent{Actor: c.Nickname, Action: AuditAdmin, Reason: reason})
+}
+
+// announce broadcasts an admin announcement, returning an error message for the
+// user when it can't be sent
+func (c *Client) announce(text string) (string, error) {
+	if !c.isAdmin() {
+		return "Permission denied.", ErrPermissionDenied
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "Usage: /announce <text>", errors.New("invalid /announce command usage")
+	}
+
+	c.room.Broadcast(Message{
+		From:      c.Nickname,
+		Content:   text,
+		Timestamp: time.Now(),
+		Kind:      KindAnnouncement,
+	})
+	c.room.recordAudit(AuditEvent{Actor: c.Nickname, Action: AuditAnnounce, Reason: text})
+	return "", nil
+}
@@ -0,0 +1,80 @@
+package chat
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestElevate(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	client := NewTUIClient(nil, room)
+	if _, err := client.elevate("secret"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected no admins without a configured token, got %v", err)
+	}
+
+	room.AdminToken = "secret"
+	if _, err := client.elevate("wrong"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected wrong token to be rejected, got %v", err)
+	}
+	if client.isAdmin() {
+		t.Fatal("Expected client not to be an admin after a wrong token")
+	}
+
+	if _, err := client.elevate("secret"); err != nil {
+		t.Fatalf("elevate() error = %v", err)
+	}
+	if !client.isAdmin() {
+		t.Error("Expected client to be an admin")
+	}
+}
+
+func TestAnnounce(t *testing.T) {
+	tests := []struct {
+		name        string
+		persist     bool
+		wantHistory int
+	}{
+		{"not persisted", false, 0},
+		{"persisted", true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := NewRoom("Test Room", 5, true, 10, true)
+			room.AdminToken = "secret"
+			room.AnnouncePersist = tt.persist
+			defer room.Stop()
+
+			client := NewTUIClient(nil, room)
+			client.Nickname = "alice"
+
+			if reply, err := client.announce("maintenance"); !errors.Is(err, ErrPermissionDenied) || reply != "Permission denied." {
+				t.Fatalf("Expected non-admin to be denied, got %q, %v", reply, err)
+			}
+
+			msgs, unsubscribe := room.Subscribe()
+			defer unsubscribe()
+
+			client.elevate("secret")
+			if _, err := client.announce("maintenance"); err != nil {
+				t.Fatalf("announce() error = %v", err)
+			}
+
+			select {
+			case msg := <-msgs:
+				if msg.Kind != KindAnnouncement || msg.Content != "maintenance" {
+					t.Errorf("Got %+v, want announcement", msg)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Announcement was not broadcast")
+			}
+
+			if got := len(room.GetHistory()); got != tt.wantHistory {
+				t.Errorf("History has %d messages, want %d", got, tt.wantHistory)
+			}
+		})
+	}
+}
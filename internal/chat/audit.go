@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"log"
+	"time"
+)
+
+// Moderation actions recorded in the audit log
+const (
+	AuditAdmin      = "admin"
+	AuditAnnounce   = "announce"
+	AuditDisconnect = "disconnect"
+	AuditCloseRoom  = "closeroom"
+	AuditFloodKick  = "flood-kick"
+)
+
+// AuditEvent records who took a moderation action against whom, and when
+type AuditEvent struct {
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AuditSink records moderation actions apart from the chat transcript. It is
+// implemented by the server so the audit log can go to a file or elsewhere
+// without the room knowing.
+type AuditSink interface {
+	RecordAudit(AuditEvent) error
+}
+
+// recordAudit hands e to the room's audit sink, if there is one. A failure is
+// logged but doesn't undo the action.
+func (r *Room) recordAudit(e AuditEvent) {
+	if r.Audit == nil {
+		return
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = r.Clock.Now()
+	}
+	if err := r.Audit.RecordAudit(e); err != nil {
+		log.Printf("Failed to record %s by %s in the audit log: %v", e.Action, e.Actor, err)
+	}
+}
+
+// auditTarget names a user by nickname and address, whichever are known
+func auditTarget(nickname, addr string) string {
+	switch {
+	case nickname == "":
+		return addr
+	case addr == "":
+		return nickname
+	default:
+		return nickname + " (" + addr + ")"
+	}
+}
@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeAudit collects audit events
+type fakeAudit struct {
+	events []AuditEvent
+}
+
+func (f *fakeAudit) RecordAudit(e AuditEvent) error {
+	f.events = append(f.events, e)
+	return nil
+}
+
+func TestModerationIsAudited(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	clock := newFakeClock()
+	room.Clock = clock
+	room.AdminToken = "secret"
+	room.Connections = &fakeConnections{conns: []ConnectionInfo{{RemoteAddr: "100.64.0.1:4000", ConnectedAt: clock.Now()}}}
+	audit := &fakeAudit{}
+	room.Audit = audit
+
+	client := NewTUIClient(nil, room)
+	client.Nickname = "alice"
+
+	// Denied attempts aren't moderation actions
+	client.disconnectAddr("100.64.0.1:4000")
+	client.elevate("wrong")
+
+	client.elevate("secret")
+	client.announce("Restart at noon")
+	client.disconnectAddr("100.64.0.1:4000")
+
+	now := clock.Now()
+	want := []AuditEvent{
+		{Actor: "alice", Action: AuditAdmin, Timestamp: now},
+		{Actor: "alice", Action: AuditAnnounce, Reason: "Restart at noon", Timestamp: now},
+		{Actor: "alice", Action: AuditDisconnect, Target: "100.64.0.1:4000", Timestamp: now},
+	}
+	if !reflect.DeepEqual(audit.events, want) {
+		t.Errorf("Audit events = %+v, want %+v", audit.events, want)
+	}
+}
+
+func TestFloodKickIsAudited(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	audit := &fakeAudit{}
+	room.Audit = audit
+
+	client := NewTUIClient(nil, room)
+	client.Nickname = "mallory"
+	client.remoteAddr = "100.64.0.9:40000"
+	client.kickForFlooding()
+
+	if len(audit.events) != 1 {
+		t.Fatalf("Expected one audit event, got %+v", audit.events)
+	}
+	got := audit.events[0]
+	if got.Actor != "server" || got.Action != AuditFloodKick || got.Target != "mallory (100.64.0.9:40000)" || got.Reason != "flooding" {
+		t.Errorf("Unexpected flood kick event %+v", got)
+	}
+	if time.Since(got.Timestamp) > time.Minute {
+		t.Errorf("Expected the event to be timestamped now, got %v", got.Timestamp)
+	}
+}
@@ -0,0 +1,200 @@
+package chat
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrAuthFailed is returned when a connection fails authentication
+var ErrAuthFailed = errors.New("authentication failed")
+
+// ErrInputTooLong is returned when a line typed before the client reader is
+// attached runs past MaxMessageLength
+var ErrInputTooLong = errors.New("input too long")
+
+// Telnet negotiation that hides the password while it is typed: the server
+// claims echo and then doesn't echo, and gives it back afterwards
+var (
+	telnetEchoOff = []byte{telnetIAC, telnetWill, optEcho}
+	telnetEchoOn  = []byte{telnetIAC, telnetWont, optEcho}
+)
+
+// authTimeout bounds how long a connection may take to authenticate
+const authTimeout = 60 * time.Second
+
+// PromptFunc shows a prompt to the connecting user and returns the line they enter
+type PromptFunc func(prompt string) (string, error)
+
+// Authenticator decides whether a connection may enter the chat. It returns the
+// authenticated identity, or "" when the method doesn't establish one.
+type Authenticator interface {
+	Authenticate(conn net.Conn, prompt PromptFunc) (identity string, err error)
+}
+
+// NoAuth lets every connection in without establishing an identity
+type NoAuth struct{}
+
+// Authenticate always succeeds
+func (NoAuth) Authenticate(net.Conn, PromptFunc) (string, error) {
+	return "", nil
+}
+
+// PasswordAuth requires a shared password, allowing a few attempts
+type PasswordAuth struct {
+	Password string
+	Attempts int // defaults to 3
+}
+
+// Authenticate prompts for the shared password
+func (a PasswordAuth) Authenticate(conn net.Conn, prompt PromptFunc) (string, error) {
+	attempts := a.Attempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+
+	conn.Write(telnetEchoOff)
+	defer conn.Write(telnetEchoOn)
+
+	message := "Password: "
+	for i := 0; i < attempts; i++ {
+		answer, err := prompt(message)
+		conn.Write([]byte("\r\n")) // The user's Enter wasn't echoed either
+		if err != nil {
+			return "", err
+		}
+
+		if subtle.ConstantTimeCompare([]byte(answer), []byte(a.Password)) == 1 {
+			return "", nil
+		}
+		message = "Incorrect password. Password: "
+	}
+
+	return "", ErrAuthFailed
+}
+
+// AuthenticateConn runs auth over a raw connection before any client reader is
+// attached. Input is read unbuffered so nothing after the answer is consumed.
+func AuthenticateConn(conn net.Conn, auth Authenticator) (string, error) {
+	deadline := time.Now().Add(authTimeout)
+	conn.SetReadDeadline(deadline)
+	defer conn.SetReadDeadline(time.Time{}) // Clear deadline
+
+	lines := &telnetLineReader{conn: conn, deadline: deadline}
+	prompt := func(text string) (string, error) {
+		if _, err := conn.Write([]byte(text)); err != nil {
+			return "", fmt.Errorf("failed to write prompt: %w", err)
+		}
+		return lines.readLine()
+	}
+
+	identity, err := auth.Authenticate(conn, prompt)
+	if err != nil {
+		conn.Write([]byte("Authentication failed.\r\n"))
+		return "", err
+	}
+	return identity, nil
+}
+
+// crWait is how long to wait for the LF or NUL after a CR. Telnet sends the
+// pair together, so only a client ending lines with a bare CR waits this long.
+const crWait = 50 * time.Millisecond
+
+// telnetLineReader reads lines one byte at a time, skipping telnet IAC
+// sequences, so nothing past the current line is consumed before the client
+// reader is attached. A byte still pending when the caller is done is dropped;
+// that takes a bare-CR client typing within crWait of pressing Enter.
+type telnetLineReader struct {
+	conn     net.Conn
+	deadline time.Time // the caller's read deadline, restored after waiting on a CR
+	pending  []byte    // a byte read after a bare CR, which starts the next line
+}
+
+// readByte returns the pending byte, if any, or reads the next one
+func (r *telnetLineReader) readByte() (byte, error) {
+	if len(r.pending) > 0 {
+		b := r.pending[0]
+		r.pending = r.pending[1:]
+		return b, nil
+	}
+	buf := make([]byte, 1)
+	if _, err := r.conn.Read(buf); err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+// endCR finishes a line ended by CR, consuming the LF or NUL that telnet sends
+// after it. Any other byte belongs to the next line and is kept for it.
+func (r *telnetLineReader) endCR() {
+	wait := time.Now().Add(crWait)
+	if !r.deadline.IsZero() && r.deadline.Before(wait) {
+		wait = r.deadline
+	}
+	r.conn.SetReadDeadline(wait)
+	defer r.conn.SetReadDeadline(r.deadline)
+
+	buf := make([]byte, 1)
+	if n, _ := r.conn.Read(buf); n == 1 && buf[0] != '\n' && buf[0] != 0 {
+		r.pending = append(r.pending, buf[0])
+	}
+}
+
+// readLine reads one line. CRLF, CR NUL, bare CR and LF all end it. Lines
+// longer than MaxMessageLength bytes fail with ErrInputTooLong.
+func (r *telnetLineReader) readLine() (string, error) {
+	var line strings.Builder
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			return "", fmt.Errorf("failed to read input: %w", err)
+		}
+
+		switch {
+		case b == telnetIAC:
+			cmd, err := r.readByte()
+			if err != nil {
+				return "", fmt.Errorf("failed to read input: %w", err)
+			}
+			switch {
+			case cmd == telnetIAC:
+				if line.Len() >= MaxMessageLength {
+					return "", ErrInputTooLong
+				}
+				line.WriteByte(telnetIAC)
+			case cmd >= telnetWill && cmd <= 254:
+				// WILL/WONT/DO/DONT carry one option byte
+				if _, err := r.readByte(); err != nil {
+					return "", fmt.Errorf("failed to read input: %w", err)
+				}
+			case cmd == telnetSB:
+				// Skip subnegotiation up to IAC SE
+				for prev := byte(0); ; {
+					b, err := r.readByte()
+					if err != nil {
+						return "", fmt.Errorf("failed to read input: %w", err)
+					}
+					if prev == telnetIAC && b == telnetSE {
+						break
+					}
+					prev = b
+				}
+			}
+		case b == '\n':
+			return strings.TrimSpace(line.String()), nil
+		case b == '\r':
+			r.endCR()
+			return strings.TrimSpace(line.String()), nil
+		case b == 0:
+			// Stray NUL; CR NUL is handled by endCR
+		default:
+			if line.Len() >= MaxMessageLength {
+				return "", ErrInputTooLong
+			}
+			line.WriteByte(b)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package chat
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeAuth asks one question and approves or denies based on the answer
+type fakeAuth struct {
+	want     string
+	identity string
+}
+
+func (a fakeAuth) Authenticate(_ net.Conn, prompt PromptFunc) (string, error) {
+	answer, err := prompt("Token: ")
+	if err != nil {
+		return "", err
+	}
+	if answer != a.want {
+		return "", ErrAuthFailed
+	}
+	return a.identity, nil
+}
+
+func TestAuthenticateConn(t *testing.T) {
+	tests := []struct {
+		name         string
+		auth         Authenticator
+		input        string
+		wantIdentity string
+		wantErr      bool
+	}{
+		{"no auth", NoAuth{}, "", "", false},
+		{"fake approves", fakeAuth{want: "open sesame", identity: "alice@example.com"}, "open sesame\r\n", "alice@example.com", false},
+		{"fake denies", fakeAuth{want: "open sesame"}, "wrong\r\n", "", true},
+		{"telnet negotiation skipped", fakeAuth{want: "ok", identity: "bob"}, "\xff\xfb\x18o\xff\xfa\x18\x00xterm\xff\xf0k\r\n", "bob", false},
+		{"password correct", PasswordAuth{Password: "hunter2"}, "hunter2\n", "", false},
+		{"password second attempt", PasswordAuth{Password: "hunter2"}, "nope\nhunter2\n", "", false},
+		{"password exhausted", PasswordAuth{Password: "hunter2", Attempts: 2}, "a\nb\nhunter2\n", "", true},
+		{"password CR NUL", PasswordAuth{Password: "hunter2"}, "hunter2\r\x00", "", false},
+		{"password bare CR", PasswordAuth{Password: "hunter2"}, "hunter2\r", "", false},
+		{"retry after bare CR", PasswordAuth{Password: "hunter2"}, "nope\rhunter2\r", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer server.Close()
+			defer client.Close()
+
+			go io.Copy(io.Discard, client)
+			go client.Write([]byte(tt.input))
+
+			identity, err := AuthenticateConn(server, tt.auth)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AuthenticateConn() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrAuthFailed) {
+				t.Errorf("AuthenticateConn() error = %v, want ErrAuthFailed", err)
+			}
+			if identity != tt.wantIdentity {
+				t.Errorf("AuthenticateConn() identity = %q, want %q", identity, tt.wantIdentity)
+			}
+		})
+	}
+}
+
+func TestAuthenticateConnLeavesRemainingInput(t *testing.T) {
+	for _, enter := range []string{"\n", "\r\n", "\r\x00"} {
+		server, client := net.Pipe()
+		defer server.Close()
+		defer client.Close()
+
+		go io.Copy(io.Discard, client)
+		go client.Write([]byte("hunter2" + enter + "alice\n"))
+
+		if _, err := AuthenticateConn(server, PasswordAuth{Password: "hunter2"}); err != nil {
+			t.Fatalf("AuthenticateConn() error = %v", err)
+		}
+
+		line, err := bufio.NewReader(server).ReadString('\n')
+		if err != nil {
+			t.Fatalf("ReadString() error = %v", err)
+		}
+		if line != "alice\n" {
+			t.Errorf("Enter as %q: next line = %q, want %q", enter, line, "alice\n")
+		}
+	}
+}
+
+func TestAuthenticateConnRejectsLongInput(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	go io.Copy(io.Discard, client)
+	go client.Write([]byte(strings.Repeat("a", MaxMessageLength+1) + "\n"))
+
+	if _, err := AuthenticateConn(server, PasswordAuth{Password: "hunter2"}); !errors.Is(err, ErrInputTooLong) {
+		t.Errorf("AuthenticateConn() error = %v, want ErrInputTooLong", err)
+	}
+}
+
+func TestPasswordAuthHidesEcho(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	output := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(client)
+		output <- b
+	}()
+	go client.Write([]byte("hunter2\n"))
+
+	if _, err := AuthenticateConn(server, PasswordAuth{Password: "hunter2"}); err != nil {
+		t.Fatalf("AuthenticateConn() error = %v", err)
+	}
+	server.Close()
+
+	want := "\xff\xfb\x01Password: \r\n\xff\xfc\x01"
+	if got := string(<-output); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
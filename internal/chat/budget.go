@@ -0,0 +1,134 @@
+package chat
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// messageOverhead approximates the memory a stored Message takes besides its
+// text: the struct with its string and slice headers, ID and timestamp
+const messageOverhead = 160
+
+// budgetLowWater is the fraction of the limit a HistoryBudget evicts down to
+// once it is exceeded, so a busy room doesn't evict and log on every message
+const budgetLowWater = 0.9
+
+// messageSize estimates the memory msg holds in history
+func messageSize(msg Message) int64 {
+	n := messageOverhead + len(msg.From) + len(msg.Content) + len(msg.ReplyQuote)
+	for _, to := range msg.To {
+		n += 16 + len(to)
+	}
+	return int64(n)
+}
+
+// HistoryBudget caps the memory held by the histories of every room sharing
+// it. When the total passes the limit, the oldest messages of the least
+// recently active rooms are evicted until it is back under 90% of the limit.
+// Each room's own history size and age limits still apply.
+type HistoryBudget struct {
+	limit int64
+
+	mu    sync.Mutex
+	rooms map[*Room]bool
+}
+
+// NewHistoryBudget creates a budget of limit bytes, estimated per message
+func NewHistoryBudget(limit int64) *HistoryBudget {
+	return &HistoryBudget{limit: limit, rooms: make(map[*Room]bool)}
+}
+
+// SetHistoryBudget counts the room's history against b from now on. The room
+// leaves the budget when it stops.
+func (r *Room) SetHistoryBudget(b *HistoryBudget) {
+	b.mu.Lock()
+	b.rooms[r] = true
+	b.mu.Unlock()
+
+	r.historyMu.Lock()
+	r.historyBudget = b
+	r.historyMu.Unlock()
+	b.enforce()
+}
+
+// remove stops counting r against the budget
+func (b *HistoryBudget) remove(r *Room) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.rooms, r)
+}
+
+// Used returns the estimated bytes held by the histories sharing the budget
+func (b *HistoryBudget) Used() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var used int64
+	for room := range b.rooms {
+		bytes, _ := room.historyUsage()
+		used += bytes
+	}
+	return used
+}
+
+// enforce evicts history once the rooms together are over the limit. Rooms
+// are locked one at a time, after the budget, so no room lock is held while
+// another is taken.
+func (b *HistoryBudget) enforce() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	type usage struct {
+		room   *Room
+		active time.Time
+	}
+	var total int64
+	rooms := make([]usage, 0, len(b.rooms))
+	for room := range b.rooms {
+		bytes, active := room.historyUsage()
+		total += bytes
+		rooms = append(rooms, usage{room, active})
+	}
+	if total <= b.limit {
+		return
+	}
+
+	target := int64(float64(b.limit) * budgetLowWater)
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].active.Before(rooms[j].active) })
+	for _, u := range rooms {
+		if total <= target {
+			break
+		}
+		freed, dropped := u.room.evictHistory(total - target)
+		if dropped > 0 {
+			log.Printf("History memory budget exceeded (%d/%d bytes): evicted %d oldest messages (%d bytes) from %s",
+				total, b.limit, dropped, freed, u.room.Name)
+		}
+		total -= freed
+	}
+}
+
+// historyUsage returns the estimated bytes held by the room's history and
+// when a message was last added to it
+func (r *Room) historyUsage() (int64, time.Time) {
+	r.historyMu.RLock()
+	defer r.historyMu.RUnlock()
+	return r.history.bytes, r.historyActive
+}
+
+// evictHistory drops the oldest history messages until at least n bytes are
+// freed or the history is empty, and returns the bytes and messages dropped
+func (r *Room) evictHistory(n int64) (int64, int) {
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	var freed int64
+	dropped := 0
+	for freed < n && r.history.len() > 0 {
+		freed += r.history.dropOldest()
+		dropped++
+	}
+	return freed, dropped
+}
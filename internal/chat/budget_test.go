@@ -0,0 +1,83 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryBudgetEvictsLeastActiveRooms(t *testing.T) {
+	clock := newFakeClock()
+	msg := Message{Kind: KindUser, From: "alice", Content: strings.Repeat("x", 40)}
+	size := messageSize(msg)
+
+	// Room for twelve messages across all rooms
+	budget := NewHistoryBudget(12 * size)
+
+	rooms := make(map[string]*Room)
+	for _, name := range []string{"attic", "lobby", "garden"} {
+		room := NewRoom(name, 5, true, 100, true)
+		defer room.Stop()
+		room.Clock = clock
+		room.SetHistoryBudget(budget)
+		rooms[name] = room
+	}
+
+	// attic goes quiet first, then lobby; garden stays busy
+	for _, name := range []string{"attic", "lobby", "garden"} {
+		for range 4 {
+			rooms[name].addToHistory(msg)
+		}
+		clock.Advance(time.Minute)
+	}
+	if used := budget.Used(); used != 12*size {
+		t.Fatalf("Used() = %d, want %d before the budget is exceeded", used, 12*size)
+	}
+
+	// Two more messages in garden push the total over; the quietest room pays
+	rooms["garden"].addToHistory(msg)
+	rooms["garden"].addToHistory(msg)
+
+	got := map[string]int{}
+	for name, room := range rooms {
+		got[name] = len(room.GetHistory())
+	}
+	// The 13th message evicts down to 90% of the cap (10.8 messages), so three
+	// go from attic; the 14th then fits
+	if got["attic"] != 1 || got["lobby"] != 4 || got["garden"] != 6 {
+		t.Errorf("History lengths after eviction = %v, want attic 1, lobby 4, garden 6", got)
+	}
+	if used := budget.Used(); used > 12*size {
+		t.Errorf("Used() = %d, want at most the %d byte limit", used, 12*size)
+	}
+
+	// A stopped room no longer counts
+	rooms["lobby"].Stop()
+	if used := budget.Used(); used != 7*size {
+		t.Errorf("Used() after stopping lobby = %d, want %d", used, 7*size)
+	}
+}
+
+func TestHistoryBufferTracksBytes(t *testing.T) {
+	h := newHistoryBuffer(2)
+	short := Message{From: "a", Content: "hi"}
+	long := Message{From: "b", Content: strings.Repeat("y", 100)}
+
+	h.add(short)
+	h.add(long)
+	if h.bytes != messageSize(short)+messageSize(long) {
+		t.Errorf("bytes = %d after two adds, want %d", h.bytes, messageSize(short)+messageSize(long))
+	}
+
+	// Overwriting the oldest releases its size
+	h.add(long)
+	if h.bytes != 2*messageSize(long) {
+		t.Errorf("bytes = %d after overwrite, want %d", h.bytes, 2*messageSize(long))
+	}
+
+	h.dropOldest()
+	h.dropOldest()
+	if h.bytes != 0 || h.dropOldest() != 0 {
+		t.Errorf("bytes = %d after emptying, want 0", h.bytes)
+	}
+}
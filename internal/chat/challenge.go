@@ -0,0 +1,106 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Connect challenge modes
+const (
+	ChallengeWord = "word" // type back a word shown in the prompt
+	ChallengeMath = "math" // answer a sum of two digits
+)
+
+// challengeAttempts and challengeTimeout bound how long a connection may take
+// to answer the connect challenge
+const (
+	challengeAttempts = 3
+	challengeTimeout  = 30 * time.Second
+)
+
+// challengeWords are the words a word challenge asks for
+var challengeWords = []string{"SWORDFISH", "LIGHTHOUSE", "PENGUIN", "TELESCOPE", "MARMALADE"}
+
+// ErrChallengeFailed is returned when a connection gives too many wrong answers
+var ErrChallengeFailed = errors.New("connect challenge failed")
+
+// challenge is one question and the answer expected, compared case-insensitively
+type challenge struct {
+	question string
+	answer   string
+}
+
+// ConnectChallenge asks a connection a simple question before the nickname
+// prompt, to keep scripted bots off a public port. It is not meant to stop a
+// determined human.
+type ConnectChallenge struct {
+	next     func() challenge // a fresh question for each attempt
+	attempts int
+	timeout  time.Duration
+}
+
+// NewConnectChallenge returns the challenge for mode, ChallengeWord or
+// ChallengeMath
+func NewConnectChallenge(mode string) (*ConnectChallenge, error) {
+	var next func() challenge
+	switch mode {
+	case ChallengeWord:
+		next = wordChallenge
+	case ChallengeMath:
+		next = mathChallenge
+	default:
+		return nil, fmt.Errorf("unknown connect challenge %q (want %s or %s)", mode, ChallengeWord, ChallengeMath)
+	}
+	return &ConnectChallenge{next: next, attempts: challengeAttempts, timeout: challengeTimeout}, nil
+}
+
+func wordChallenge() challenge {
+	word := challengeWords[rand.IntN(len(challengeWords))]
+	return challenge{question: fmt.Sprintf("Type the word %s to continue: ", word), answer: word}
+}
+
+func mathChallenge() challenge {
+	a, b := rand.IntN(9)+1, rand.IntN(9)+1
+	return challenge{question: fmt.Sprintf("What is %d + %d? ", a, b), answer: strconv.Itoa(a + b)}
+}
+
+// Run challenges conn before any client reader is attached, reading input
+// unbuffered like AuthenticateConn. It returns nil once the user answers
+// correctly, ErrChallengeFailed after too many wrong answers, or the read
+// error if the user disconnects or runs out of time.
+func (c *ConnectChallenge) Run(conn net.Conn) error {
+	deadline := time.Now().Add(c.timeout)
+	conn.SetReadDeadline(deadline)
+	defer conn.SetReadDeadline(time.Time{}) // Clear deadline
+
+	lines := &telnetLineReader{conn: conn, deadline: deadline}
+
+	prefix := "To keep out bots, please answer a quick question.\r\n"
+	for i := 0; i < c.attempts; i++ {
+		q := c.next()
+		if _, err := conn.Write([]byte(prefix + q.question)); err != nil {
+			return fmt.Errorf("failed to write challenge: %w", err)
+		}
+
+		answer, err := lines.readLine()
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				conn.Write([]byte("\r\nTimed out. Goodbye.\r\n"))
+			}
+			return err
+		}
+		if strings.EqualFold(answer, q.answer) {
+			return nil
+		}
+		prefix = "That's not right. "
+	}
+
+	conn.Write([]byte("Too many wrong answers. Goodbye.\r\n"))
+	return ErrChallengeFailed
+}
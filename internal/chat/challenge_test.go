@@ -0,0 +1,87 @@
+package chat
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixedChallenge always asks 2 + 2
+func fixedChallenge() challenge {
+	return challenge{question: "What is 2 + 2? ", answer: "4"}
+}
+
+func TestConnectChallenge(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantErr  error
+		wantSeen string
+	}{
+		{"correct", "4\r\n", nil, "What is 2 + 2?"},
+		{"retry after wrong answer", "5\r\n4\r\n", nil, "That's not right. What is 2 + 2?"},
+		{"too many wrong answers", "1\r\n2\r\n3\r\n4\r\n", ErrChallengeFailed, "Too many wrong answers"},
+		{"CR NUL", "4\r\x00", nil, "What is 2 + 2?"},
+		{"bare CR", "5\r4\r", nil, "That's not right. What is 2 + 2?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer client.Close()
+
+			output := make(chan string, 1)
+			go func() {
+				out, _ := io.ReadAll(client)
+				output <- string(out)
+			}()
+			go client.Write([]byte(tt.input))
+
+			c := &ConnectChallenge{next: fixedChallenge, attempts: 3, timeout: time.Second}
+			err := c.Run(server)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+
+			server.Close()
+			if out := <-output; !strings.Contains(out, tt.wantSeen) {
+				t.Errorf("Expected output to contain %q, got %q", tt.wantSeen, out)
+			}
+		})
+	}
+}
+
+func TestConnectChallengeTimeout(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	go io.Copy(io.Discard, client)
+
+	c := &ConnectChallenge{next: fixedChallenge, attempts: 3, timeout: 50 * time.Millisecond}
+	start := time.Now()
+	if err := c.Run(server); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Run() error = %v, want a deadline error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run() took %s, want about the 50ms timeout", elapsed)
+	}
+}
+
+func TestNewConnectChallenge(t *testing.T) {
+	for _, mode := range []string{ChallengeWord, ChallengeMath} {
+		c, err := NewConnectChallenge(mode)
+		if err != nil {
+			t.Fatalf("NewConnectChallenge(%q) error = %v", mode, err)
+		}
+		if q := c.next(); q.question == "" || q.answer == "" {
+			t.Errorf("NewConnectChallenge(%q) produced %+v", mode, q)
+		}
+	}
+	if _, err := NewConnectChallenge("riddle"); err == nil {
+		t.Error("Expected an unknown mode to be rejected")
+	}
+}
@@ -2,29 +2,53 @@ package chat
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/text/unicode/norm"
 
 	"github.com/bscott/ts-chat/internal/ui"
 )
 
 // Constants for rate limiting and validation
 const (
-	MaxMessageLength = 1000            // Maximum message length in characters
+	MaxMessageLength = 1000            // Default maximum message length in characters
 	MessageRateLimit = 5               // Maximum messages per window
 	RateLimitWindow  = 5 * time.Second // Time window for rate limiting
-	MaxNicknameLen   = 20              // Maximum nickname length
-	MinNicknameLen   = 2               // Minimum nickname length
+	MaxNicknameLen   = 20              // Default maximum nickname length
+	MinNicknameLen   = 2               // Default minimum nickname length
+	MaxQuitReasonLen = 100             // Maximum length of a /quit parting message
+
+	MaxNicknameAttempts = 5    // Default number of rejected nicknames before disconnecting
+	DefaultScrollback   = 1000 // Default number of messages kept in the TUI viewport
+
+	DefaultReadPollInterval = 30 * time.Second // Default read deadline between shutdown checks in line mode
+	ReplayAllHistory        = -1               // HistoryReplayCount that replays all stored history at join
+
+	DefaultBufferSize = 4096    // Default size in bytes of a line-mode client's read and write buffers
+	MinBufferSize     = 512     // Smallest configurable client buffer
+	MaxBufferSize     = 1 << 20 // Largest configurable client buffer
 )
 
+// ErrTooManyNicknameAttempts is returned when a client keeps choosing rejected nicknames
+var ErrTooManyNicknameAttempts = errors.New("too many invalid nickname attempts")
+
+// errConnClosed is returned by writes to a client whose connection is already gone
+var errConnClosed = errors.New("connection closed")
+
 // ANSI escape codes for terminal control (plain-text mode only)
 const (
 	cursorUp      = "\033[1A"
@@ -33,31 +57,59 @@ const (
 	inputPrompt   = "> "
 )
 
+// Nickname handshake for scripted clients ("NICK <name>" as the first line)
+const (
+	nickHandshakePrefix = "NICK "
+	nickHandshakeWait   = 250 * time.Millisecond
+)
+
+// telnetNOP is IAC NOP: telnet clients swallow it, so it never shows on screen
+var telnetNOP = []byte{255, 241}
+
 // Telnet negotiation bytes for character-at-a-time mode
 var telnetNegotiation = []byte{
 	255, 251, 1, // IAC WILL ECHO
 	255, 251, 3, // IAC WILL SUPPRESS-GO-AHEAD
 	255, 253, 3, // IAC DO SUPPRESS-GO-AHEAD
+	255, 253, 31, // IAC DO NAWS, so the TUI learns the window size
 }
 
-// validateNickname checks if a nickname is valid
-func validateNickname(nickname string) error {
+// normalizeNickname trims a typed nickname and converts it to Unicode NFC, so
+// names that look the same are compared as the same
+func normalizeNickname(nickname string) string {
+	return norm.NFC.String(strings.TrimSpace(nickname))
+}
+
+// isInvisibleRune reports whether r is a control, format (zero-width, bidi
+// override) or combining character that could hide or disguise a nickname
+func isInvisibleRune(r rune) bool {
+	return unicode.IsControl(r) || unicode.In(r, unicode.Cf, unicode.Mn, unicode.Me)
+}
+
+// validateNickname checks if a nickname is valid and within the given length bounds
+func validateNickname(nickname string, minLen, maxLen int) error {
 	if nickname == "" {
 		return fmt.Errorf("Nickname cannot be empty. Please try again.")
 	}
 
-	if len(nickname) < MinNicknameLen {
-		return fmt.Errorf("Nickname must be at least %d characters.", MinNicknameLen)
+	// Checked before the character set so spoofing attempts get a specific error
+	// even if nicknames ever allow more of Unicode
+	if strings.IndexFunc(nickname, isInvisibleRune) >= 0 {
+		return fmt.Errorf("Nickname cannot contain invisible, control or combining characters.")
 	}
 
-	if len(nickname) > MaxNicknameLen {
-		return fmt.Errorf("Nickname must be at most %d characters.", MaxNicknameLen)
+	if n := len(nickname); n < minLen || n > maxLen {
+		return fmt.Errorf("Nickname must be between %d and %d characters (got %d).", minLen, maxLen, n)
 	}
 
 	if strings.ToLower(nickname) == "system" {
 		return fmt.Errorf("Nickname 'System' is reserved. Please choose another nickname.")
 	}
 
+	if origin, ok := reservedOrigin(nickname); ok {
+		return fmt.Errorf("Nicknames starting with '%s-' or '%s_' are reserved for %s messages. Please choose another nickname.", origin, origin, origin)
+	}
+
 	for _, r := range nickname {
 		if !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-') {
 			return fmt.Errorf("Nickname can only contain letters, numbers, underscores, and hyphens.")
@@ -67,18 +119,54 @@ func validateNickname(nickname string) error {
 	return nil
 }
 
+// ClientOptions holds per-connection settings decided by the server
+type ClientOptions struct {
+	PlainText bool   // send output without ANSI formatting
+	Identity  string // authenticated identity such as a Tailscale login (empty if unknown)
+	Transport string // how the client connected, e.g. "tcp" or "tailscale", for metrics
+	Telnet    bool   // the peer answered telnet negotiation, so it gets IAC NOP keepalives
+}
+
 // Client represents a chat client
 type Client struct {
 	Nickname          string
+	Identity          string // authenticated identity, used to detect duplicate sessions
+	Transport         string // how the client connected, e.g. "tcp" or "tailscale" (empty if unknown)
+	Telnet            bool   // the peer answered telnet negotiation; only these get heartbeats
 	conn              net.Conn
+	remoteAddr        string // peer address captured at connect, kept after conn is dropped
 	reader            *bufio.Reader
+	afterCR           bool      // last line ended in CR; drop a following LF or NUL
+	partial           []byte    // input read before a deadline interrupted the line
+	pagerBuffer       []string  // output lines still waiting for /more; owned by the read loop
+	termHeight        int       // terminal rows if the client reported them (0 if unknown)
+	pingSent          time.Time // when an unanswered /ping was sent; owned by the read loop
+	lastFeedback      time.Time // when /feedback last succeeded; owned by the read loop
+	outbox            *outbox   // messages waiting for the writer goroutine (line mode)
+	outboxOnce        sync.Once
+	heartbeatPending  atomic.Bool // a heartbeat is queued or being written
 	writer            *bufio.Writer
 	room              *Room
 	mu                sync.Mutex
 	fullRoomRejection bool
+	joinDone          chan struct{} // closed by the room once a Join has been processed
+	joined            bool          // set by the room goroutine once the client is a member
+	joinedAt          time.Time     // when the client became a member, guarded by room.mu
 	messageTimestamps []time.Time
+	floodStrikes      []time.Time // recent rate-limit violations, guarded by rateLimitMu
 	rateLimitMu       sync.Mutex
-	program           *tea.Program // set in TUI mode, nil in plain-text mode
+	program           *tea.Program    // set in TUI mode, nil in plain-text mode
+	tuiOut            *deadlineWriter // the TUI's connection writer, below the encoder
+	plainText         bool            // whether to send output without ANSI formatting
+	quitReason        string          // optional parting message given with /quit
+	suppressJoins     bool            // do-not-disturb: hide join/leave notices
+	admin             bool            // granted by /admin with the room's admin token
+	muted             bool            // /mute: hold back chat until /unmute
+	missed            int             // messages suppressed while muted
+	lastActive        time.Time       // last line the user sent, guarded by mu
+	idleWarned        bool            // the idle warning went out since lastActive, guarded by mu
+	idleKicked        bool            // disconnected for inactivity, guarded by mu
+	compact           bool            // /compact: dense output for small screens, guarded by mu
 }
 
 // NewTUIClient creates a client for TUI (bubbletea) mode.
@@ -86,19 +174,26 @@ type Client struct {
 func NewTUIClient(conn net.Conn, room *Room) *Client {
 	return &Client{
 		conn:              conn,
+		remoteAddr:        addrOf(conn),
 		room:              room,
 		messageTimestamps: make([]time.Time, 0, MessageRateLimit*2),
+		plainText:         room.PlainText,
+		suppressJoins:     room.QuietJoins,
+		compact:           room.Compact,
 	}
 }
 
 // Send delivers a message to this client. In TUI mode it uses program.Send(),
 // in plain-text mode it writes directly to the connection.
 func (c *Client) Send(msg Message) {
+	if !c.wantsMessage(msg) || c.suppressIfMuted(msg) {
+		return
+	}
 	if c.program != nil {
 		c.program.Send(ChatMsg{Message: msg})
 		return
 	}
-	c.sendMessage(msg)
+	c.enqueue(msg)
 }
 
 // --- TUI mode (bubbletea) ---
@@ -112,26 +207,45 @@ func (c *Client) RunTUI(ctx context.Context) {
 	// Brief pause to let telnet client process negotiation and send responses
 	time.Sleep(100 * time.Millisecond)
 
-	// Drain any IAC responses the telnet client sent back
+	// Read the IAC responses the telnet client sent back, keeping the window
+	// size if it answered DO NAWS already
+	var width, height int
+	var sized bool
+	var early []byte
 	if conn, ok := c.conn.(interface{ SetReadDeadline(time.Time) error }); ok {
 		conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
-		discard := make([]byte, 256)
-		c.conn.Read(discard)
+		early = make([]byte, 256)
+		n, _ := c.conn.Read(early)
+		early = early[:n]
+		width, height, sized = parseNAWS(early)
 		conn.SetReadDeadline(time.Time{}) // Clear deadline
 	}
 
-	// Wrap the connection in a reader that filters telnet IAC sequences
-	filteredInput := &telnetFilterReader{reader: c.conn}
+	// Wrap the connection in a reader that filters telnet IAC sequences,
+	// starting with the responses above so keys typed among them aren't lost
+	filteredInput := &telnetFilterReader{reader: io.MultiReader(bytes.NewReader(early), c.conn)}
 
 	model := NewChatModel(c)
 
+	c.tuiOut = &deadlineWriter{conn: c.conn, timeout: c.room.WriteTimeout}
 	p := tea.NewProgram(
 		model,
-		tea.WithInput(filteredInput),
-		tea.WithOutput(c.conn),
+		tea.WithInput(decodeReader(filteredInput, c.room.Encoding)),
+		tea.WithOutput(encodeWriter(c.tuiOut, c.room.Encoding)),
 	)
 	c.program = p
 
+	// Telnet never sends WindowSizeMsg; NAWS reports stand in for it, and the
+	// model keeps its 80x24 default for clients that don't send any.
+	// Send blocks until the program runs, hence the goroutines.
+	resize := func(width, height int) {
+		go p.Send(tea.WindowSizeMsg{Width: width, Height: height})
+	}
+	if sized {
+		resize(width, height)
+	}
+	filteredInput.onResize = resize
+
 	// Close connection when context is cancelled
 	go func() {
 		<-ctx.Done()
@@ -143,9 +257,58 @@ func (c *Client) RunTUI(ctx context.Context) {
 	}
 }
 
+// readLine reads one line of input without its terminator. CRLF, LF, bare CR
+// (sent by some telnet clients) and telnet's CR NUL all end a line; a bare CR
+// returns at once rather than waiting for an LF that may never come.
+// Backspace and DEL erase the previous character, for telnet clients that send
+// keystrokes without editing the line locally.
+// Input read before an error such as a read deadline is kept for the next call.
+func (c *Client) readLine() (string, error) {
+	for {
+		b, err := c.reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+
+		if c.afterCR {
+			c.afterCR = false
+			if b == '\n' || b == 0 {
+				continue
+			}
+		}
+
+		switch b {
+		case '\r':
+			c.afterCR = true
+			return c.takeLine(), nil
+		case '\n':
+			return c.takeLine(), nil
+		case '\b', 0x7f:
+			c.erase()
+			continue
+		}
+		c.partial = append(c.partial, b)
+	}
+}
+
+// erase drops the last character of the buffered line, if any
+func (c *Client) erase() {
+	_, size := utf8.DecodeLastRune(c.partial)
+	c.partial = c.partial[:len(c.partial)-size]
+}
+
+// takeLine returns the buffered line and resets the buffer
+func (c *Client) takeLine() string {
+	line := string(c.partial)
+	c.partial = c.partial[:0]
+	return line
+}
+
 // telnetFilterReader wraps an io.Reader and strips telnet IAC sequences.
+// Window size reports (NAWS) are passed to onResize when it is set.
 type telnetFilterReader struct {
-	reader io.Reader
+	reader   io.Reader
+	onResize func(width, height int)
 }
 
 func (r *telnetFilterReader) Read(p []byte) (int, error) {
@@ -168,6 +331,18 @@ func (r *telnetFilterReader) Read(p []byte) (int, error) {
 			} else if cmd >= 0xFB && cmd <= 0xFE {
 				// WILL/WONT/DO/DONT — skip 3 bytes (IAC + cmd + option)
 				i += 3
+			} else if cmd == telnetSB {
+				// Subnegotiation — skip through IAC SE, or the rest of this read
+				// if it was split across reads
+				end := bytes.Index(p[i:n], []byte{telnetIAC, telnetSE})
+				if end < 0 {
+					i = n
+					continue
+				}
+				if width, height, ok := parseNAWS(p[i : i+end+2]); ok && r.onResize != nil {
+					r.onResize(width, height)
+				}
+				i += end + 2
 			} else {
 				// Other IAC commands — skip 2 bytes
 				i += 2
@@ -185,149 +360,316 @@ func (r *telnetFilterReader) Read(p []byte) (int, error) {
 // --- Plain-text mode (legacy telnet) ---
 
 // NewPlainTextClient creates a client for plain-text mode with nickname negotiation.
-func NewPlainTextClient(conn net.Conn, room *Room) (*Client, error) {
-	client := &Client{
+func NewPlainTextClient(conn net.Conn, room *Room, opts ClientOptions) (*Client, error) {
+	client := newPlainTextClient(conn, room, opts)
+	if err := client.login(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// newPlainTextClient builds a line-mode client without touching the
+// connection, so tests can drive login, commands and rate limiting directly.
+// Input is filtered for telnet commands, such as the replies to echo
+// negotiation around a password prompt.
+func newPlainTextClient(conn net.Conn, room *Room, opts ClientOptions) *Client {
+	return &Client{
+		Identity:          opts.Identity,
+		Transport:         opts.Transport,
+		Telnet:            opts.Telnet,
 		conn:              conn,
-		reader:            bufio.NewReader(conn),
-		writer:            bufio.NewWriter(conn),
+		remoteAddr:        addrOf(conn),
+		reader:            bufio.NewReaderSize(decodeReader(&telnetFilterReader{reader: conn}, room.Encoding), bufferSize(room.ReadBufferSize)),
+		writer:            bufio.NewWriterSize(encodeWriter(conn, room.Encoding), bufferSize(room.WriteBufferSize)),
 		room:              room,
 		fullRoomRejection: false,
+		plainText:         opts.PlainText,
+		suppressJoins:     room.QuietJoins,
+		compact:           room.Compact,
 		messageTimestamps: make([]time.Time, 0, MessageRateLimit*2),
 	}
+}
 
-	if err := client.requestNickname(); err != nil {
+// bufferSize returns size, or DefaultBufferSize when it isn't set
+func bufferSize(size int) int {
+	if size <= 0 {
+		return DefaultBufferSize
+	}
+	return size
+}
+
+// login runs the line-mode handshake: probe rejection, nickname prompt, joining
+// the room and the welcome. The connection is closed on failure.
+func (c *Client) login() error {
+	conn, room := c.conn, c.room
+
+	if err := c.rejectProbe(); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("nickname request failed: %w", err)
+		return err
 	}
 
-	room.Join(client)
+	// requestNickname only reserves a nickname on success, so nothing to release here
+	if err := c.requestNickname(); err != nil {
+		conn.Close()
+		return fmt.Errorf("nickname request failed: %w", err)
+	}
 
-	if client.fullRoomRejection {
+	room.Join(c)
+
+	// addClient has already dropped the reservation when the room is full
+	if c.fullRoomRejection {
 		conn.Close()
-		return nil, fmt.Errorf("room is full")
+		return fmt.Errorf("room is full")
 	}
 
-	if err := client.sendWelcomeMessage(); err != nil {
-		room.Leave(client)
+	// The client is a room member now, so Leave removes it exactly once
+	if err := c.sendWelcomeMessage(); err != nil {
+		room.Leave(c)
 		conn.Close()
-		return nil, fmt.Errorf("welcome message failed: %w", err)
+		return fmt.Errorf("welcome message failed: %w", err)
 	}
 
-	client.sendHistory()
+	// A client that hangs up mid-replay is cleaned up here rather than by Handle
+	if err := c.sendHistory(); err != nil {
+		room.Leave(c)
+		conn.Close()
+		return fmt.Errorf("history replay failed: %w", err)
+	}
 
-	return client, nil
+	return nil
 }
 
 func (c *Client) requestNickname() error {
 	var welcomeTitle string
-	if c.room.PlainText {
+	if c.usePlainText() {
 		welcomeTitle = ui.FormatTitlePlain("Welcome to Chat Tails")
 	} else {
 		welcomeTitle = ui.FormatTitle("Welcome to Chat Tails")
 	}
-	if err := c.write(welcomeTitle + "\r\n\r\n"); err != nil {
+	if err := c.writeLine(welcomeTitle + c.newline()); err != nil {
 		return fmt.Errorf("failed to write welcome message: %w", err)
 	}
 
+	// Rejected nicknames so far; capped to stop clients probing for taken names
+	attempts := 0
+
+	if c.room.AllowNickHandshake {
+		if nickname, ok := c.readNickHandshake(); ok {
+			reject, err := c.takeNickname(normalizeNickname(nickname))
+			if err != nil {
+				return err
+			}
+			if reject == "" {
+				return nil
+			}
+			attempts++
+			c.writeLine(reject)
+		}
+	}
+
 	for {
-		if err := c.write("Please enter your nickname: "); err != nil {
+		if c.room.nicknameAttemptsExceeded(attempts) {
+			c.writeLine("Too many invalid nickname attempts. Goodbye!")
+			return ErrTooManyNicknameAttempts
+		}
+
+		if err := c.send("Please enter your nickname: "); err != nil {
 			return fmt.Errorf("failed to write nickname prompt: %w", err)
 		}
 
-		nickname, err := c.reader.ReadString('\n')
+		nickname, err := c.readLine()
 		if err != nil {
 			return fmt.Errorf("failed to read nickname: %w", err)
 		}
 
-		nickname = strings.TrimSpace(nickname)
-
-		if err := validateNickname(nickname); err != nil {
-			if writeErr := c.write(err.Error() + "\r\n"); writeErr != nil {
-				return fmt.Errorf("failed to write error message: %w", writeErr)
-			}
-			continue
+		reject, err := c.takeNickname(normalizeNickname(nickname))
+		if err != nil {
+			return err
+		}
+		if reject == "" {
+			return nil
 		}
 
-		if !c.room.ReserveNickname(nickname) {
-			errMsg := fmt.Sprintf("Nickname '%s' is already taken. Please choose another nickname.\r\n", nickname)
-			if err := c.write(errMsg); err != nil {
-				return fmt.Errorf("failed to write error message: %w", err)
-			}
-			continue
+		attempts++
+		if err := c.writeLine(reject); err != nil {
+			return fmt.Errorf("failed to write error message: %w", err)
 		}
+	}
+}
 
-		c.Nickname = nickname
-		break
+// takeNickname validates nickname, reserves it in the room and asks for its
+// password if it is a reserved name. It returns why the nickname was refused,
+// or "" once it is the client's.
+func (c *Client) takeNickname(nickname string) (string, error) {
+	if err := validateNickname(nickname, c.room.MinNicknameLen, c.room.MaxNicknameLen); err != nil {
+		return err.Error(), nil
 	}
 
-	return nil
+	if !c.room.ReserveNickname(nickname) {
+		return fmt.Sprintf("Nickname '%s' is already taken. Please choose another nickname.", nickname), nil
+	}
+
+	// Ask for a reserved name's password only once nobody else holds it
+	ok, admin, reject, err := c.claimReserved(nickname)
+	if err != nil || !ok {
+		c.room.ReleaseNickname(nickname)
+		return reject, err
+	}
+
+	c.Nickname = nickname
+	if admin {
+		c.grantAdmin("reserved nickname")
+	}
+	return "", nil
+}
+
+// readNickHandshake checks whether the client sent a "NICK <name>" line right after
+// connecting and returns the requested nickname. Interactive users who send nothing
+// within the handshake window fall through to the normal prompt.
+func (c *Client) readNickHandshake() (string, bool) {
+	conn, ok := c.conn.(interface{ SetReadDeadline(time.Time) error })
+	if !ok {
+		return "", false
+	}
+
+	conn.SetReadDeadline(time.Now().Add(nickHandshakeWait))
+	defer conn.SetReadDeadline(time.Time{}) // Clear deadline
+
+	prefix, err := c.reader.Peek(len(nickHandshakePrefix))
+	if err != nil || !strings.EqualFold(string(prefix), nickHandshakePrefix) {
+		return "", false
+	}
+
+	line, err := c.readLine()
+	if err != nil {
+		return "", false
+	}
+
+	// readLine applies backspaces, so the line may no longer hold the prefix
+	// that was peeked
+	n := len(nickHandshakePrefix)
+	if len(line) < n || !strings.EqualFold(line[:n], nickHandshakePrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(line[n:]), true
 }
 
 func (c *Client) sendWelcomeMessage() error {
-	banner := `
-╔════════════════════════════════════════════════════════════╗
-║                                                            ║
-║      _____ _           _     _____     _ _                 ║
-║     / ____| |         | |   |_   _|   (_) |                ║
-║    | |    | |__   __ _| |_    | | __ _ _| |___             ║
-║    | |    | '_ \ / _' | __|   | |/ _' | | / __|            ║
-║    | |____| | | | (_| | |_    | | (_| | | \__ \            ║
-║     \_____|_| |_|\__,_|\__|   |_|\__,_|_|_|___/            ║
-║                                                            ║
-╚════════════════════════════════════════════════════════════╝
-`
+	if c.useCompact() {
+		return c.sendCompactWelcome()
+	}
+
+	banner := c.room.Banner
+	if banner == "" {
+		banner = ui.DefaultBanner
+	}
+	banner = ui.FitBanner(banner, c.room.BannerWidth)
+
 	var coloredBanner, welcomeMsg string
 
-	if c.room.PlainText {
+	if c.usePlainText() {
 		coloredBanner = banner
 		welcomeMsg = ui.FormatWelcomeMessagePlain(c.room.Name, c.Nickname)
 	} else {
-		coloredBanner = ui.SystemStyle.Render(banner)
+		coloredBanner = c.room.theme().SystemStyle().Render(banner)
 		welcomeMsg = ui.FormatWelcomeMessage(c.room.Name, c.Nickname)
 	}
 
-	if err := c.write(coloredBanner + "\r\n"); err != nil {
+	if err := c.writeBanner(coloredBanner); err != nil {
 		return fmt.Errorf("failed to write banner: %w", err)
 	}
 
-	if err := c.write(welcomeMsg + "\r\n\r\n"); err != nil {
+	if err := c.writeLine(welcomeMsg + c.newline()); err != nil {
 		return fmt.Errorf("failed to write welcome message: %w", err)
 	}
 
+	if c.room.MOTD != "" {
+		if err := c.writeLine(c.formatMOTD() + c.newline()); err != nil {
+			return fmt.Errorf("failed to write message of the day: %w", err)
+		}
+	}
+
+	if topic := c.room.Topic(); topic.Text != "" {
+		c.sendSystemMessage(topic.String())
+	}
+
+	return nil
+}
+
+// writeBanner writes the welcome banner, one line at a time when the room
+// animates it. Plain-text clients always get it at once. The animation stops
+// early if the connection is closed or the room shuts down.
+func (c *Client) writeBanner(banner string) error {
+	delay := c.room.BannerAnimateDelay
+	if delay <= 0 || c.usePlainText() {
+		return c.writeLine(banner)
+	}
+
+	for i, line := range strings.Split(banner, "\n") {
+		if i > 0 {
+			select {
+			case <-time.After(delay):
+			case <-c.room.ctx.Done():
+				return errors.New("room stopped")
+			}
+		}
+		// write fails once the client has disconnected, ending the animation
+		if err := c.writeLine(line); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (c *Client) sendHistory() {
-	history := c.room.GetHistory()
+func (c *Client) sendHistory() error {
+	history := c.room.ReplayHistory()
 	if len(history) == 0 {
-		return
+		return nil
 	}
 
 	var headerMsg, footerMsg string
-	if c.room.PlainText {
-		headerMsg = ui.FormatSystemMessagePlain("--- Recent messages ---")
-		footerMsg = ui.FormatSystemMessagePlain("--- End of history ---")
+	if c.usePlainText() {
+		headerMsg = c.room.theme().FormatSystemMessagePlain("--- Recent messages ---")
+		footerMsg = c.room.theme().FormatSystemMessagePlain("--- End of history ---")
 	} else {
-		headerMsg = ui.FormatSystemMessage("--- Recent messages ---")
-		footerMsg = ui.FormatSystemMessage("--- End of history ---")
+		headerMsg = c.room.theme().FormatSystemMessage("--- Recent messages ---")
+		footerMsg = c.room.theme().FormatSystemMessage("--- End of history ---")
 	}
 
-	c.write(headerMsg + "\r\n")
+	if err := c.writeLine(headerMsg); err != nil {
+		return fmt.Errorf("failed to write history header: %w", err)
+	}
 
+	// Long histories are paged so they don't flood the scrollback; /more shows the rest
+	lines := make([]string, 0, len(history)+1)
 	for _, msg := range history {
-		c.sendMessage(msg)
+		if c.wantsMessage(msg) {
+			lines = append(lines, c.formatFor(msg))
+		}
 	}
+	lines = append(lines, footerMsg, "")
 
-	c.write(footerMsg + "\r\n\r\n")
+	if err := c.page(lines); err != nil {
+		// Nobody is left to ask for /more
+		c.pagerBuffer = nil
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
 }
 
 // Handle handles client interactions in plain-text mode.
 func (c *Client) Handle(ctx context.Context) {
+	// What ended the read loop, reported in the leave notice and logs
+	var readErr error
+
 	defer func() {
-		c.room.Leave(c)
+		c.room.LeaveWithReason(c, readErr)
 		c.close()
 	}()
 
+	// close() clears c.conn concurrently, so keep our own reference for deadlines
+	deadliner, canPoll := c.conn.(interface{ SetReadDeadline(time.Time) error })
+
 	go func() {
 		<-ctx.Done()
 		c.close()
@@ -336,11 +678,12 @@ func (c *Client) Handle(ctx context.Context) {
 	c.showPrompt()
 
 	for {
-		if conn, ok := c.conn.(interface{ SetReadDeadline(time.Time) error }); ok {
-			conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+		// Wake up periodically so a stuck read notices shutdown; 0 reads without a deadline
+		if canPoll && c.room.ReadPollInterval > 0 {
+			deadliner.SetReadDeadline(time.Now().Add(c.room.ReadPollInterval))
 		}
 
-		line, err := c.reader.ReadString('\n')
+		line, err := c.readLine()
 		if err != nil {
 			if ctx.Err() != nil {
 				return
@@ -350,18 +693,19 @@ func (c *Client) Handle(ctx context.Context) {
 				continue
 			}
 
-			if err == io.EOF {
-				return
-			}
-
-			log.Printf("Error reading from client %s: %v", c.Nickname, err)
+			readErr = err
 			return
 		}
 
 		message := strings.TrimSpace(line)
+		c.touch(c.room.Clock.Now())
 
 		c.clearInputLine()
 
+		if reply, ok := c.answerPing(c.room.Clock.Now()); ok {
+			c.sendSystemMessage(reply)
+		}
+
 		if message == "" {
 			c.showPrompt()
 			continue
@@ -374,7 +718,12 @@ func (c *Client) Handle(ctx context.Context) {
 		}
 
 		if !strings.HasPrefix(message, "/quit") {
-			if err := c.checkRateLimit(); err != nil {
+			if err := c.checkRateLimit(); errors.Is(err, ErrFloodKick) {
+				c.sendSystemMessage(floodKickNotice)
+				c.kickForFlooding()
+				readErr = err
+				return
+			} else if err != nil {
 				c.sendSystemMessage(fmt.Sprintf("Error: %v", err))
 				c.showPrompt()
 				continue
@@ -383,6 +732,8 @@ func (c *Client) Handle(ctx context.Context) {
 
 		if strings.HasPrefix(message, "/") {
 			c.handleCommand(message)
+		} else if err := c.checkJoinCooldown(c.room.Clock.Now()); err != nil {
+			c.sendSystemMessage(fmt.Sprintf("Error: %v", err))
 		} else {
 			c.room.Broadcast(Message{
 				From:      c.Nickname,
@@ -396,16 +747,18 @@ func (c *Client) Handle(ctx context.Context) {
 }
 
 func (c *Client) clearInputLine() {
-	if !c.room.PlainText {
-		c.write(cursorUp + clearLine + cursorToStart)
+	if !c.usePlainText() {
+		c.send(cursorUp + clearLine + cursorToStart)
 	}
 }
 
 func (c *Client) showPrompt() {
-	c.write(inputPrompt)
+	c.send(inputPrompt)
 }
 
 func (c *Client) close() {
+	c.closeOutbox()
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -416,14 +769,11 @@ func (c *Client) close() {
 }
 
 func (c *Client) validateMessageLength(message string) error {
-	if len(message) > MaxMessageLength {
-		return fmt.Errorf("message too long (max %d characters)", MaxMessageLength)
-	}
-	return nil
+	return c.room.validateMessageLength(message)
 }
 
 func (c *Client) checkRateLimit() error {
-	now := time.Now()
+	now := c.room.Clock.Now()
 	c.rateLimitMu.Lock()
 	defer c.rateLimitMu.Unlock()
 
@@ -441,6 +791,9 @@ func (c *Client) checkRateLimit() error {
 	c.messageTimestamps = newTimestamps
 
 	if len(c.messageTimestamps) > MessageRateLimit {
+		if c.floodStrike(now) {
+			return ErrFloodKick
+		}
 		waitTime := c.messageTimestamps[0].Add(RateLimitWindow).Sub(now)
 		return fmt.Errorf("rate limit exceeded (max %d messages per %s). Try again in %.1f seconds",
 			MessageRateLimit, RateLimitWindow, waitTime.Seconds())
@@ -455,7 +808,12 @@ func (c *Client) handleCommand(cmd string) error {
 
 	switch command {
 	case "/who":
-		return c.showUserList()
+		page, err := parseWhoPage(parts)
+		if err != nil {
+			c.sendSystemMessage("Usage: /who [page]")
+			return err
+		}
+		return c.showUserList(page)
 
 	case "/me":
 		if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
@@ -467,13 +825,218 @@ func (c *Client) handleCommand(cmd string) error {
 			From:      c.Nickname,
 			Content:   action,
 			Timestamp: time.Now(),
-			IsAction:  true,
+			Kind:      KindAction,
 		})
 
+	case "/roll":
+		spec := ""
+		if len(parts) > 1 {
+			spec = parts[1]
+		}
+		action, err := rollAction(spec)
+		if err != nil {
+			c.sendSystemMessage(fmt.Sprintf("Error: %v", err))
+			return err
+		}
+		c.room.Broadcast(Message{
+			From:      c.Nickname,
+			Content:   action,
+			Timestamp: time.Now(),
+			Kind:      KindAction,
+		})
+
+	case "/flip":
+		c.room.Broadcast(Message{
+			From:      c.Nickname,
+			Content:   flipAction(),
+			Timestamp: time.Now(),
+			Kind:      KindAction,
+		})
+
+	case "/reply":
+		args := ""
+		if len(parts) > 1 {
+			args = parts[1]
+		}
+		reply, err := buildReply(c.room, c.Nickname, args)
+		if err != nil {
+			c.sendSystemMessage(fmt.Sprintf("Error: %v", err))
+			return err
+		}
+		c.room.Broadcast(reply)
+
+	case "/stats":
+		return c.showStats()
+
+	case "/whoami":
+		return c.writeLine(c.whoami(c.room.Clock.Now()))
+
+	case "/motd":
+		return c.showMOTD()
+
+	case "/seen":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		reply, err := c.seen(arg, c.room.Clock.Now())
+		if err != nil {
+			c.sendSystemMessage(reply)
+			return err
+		}
+		return c.writeLine(reply)
+
+	case "/plain":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		msg, err := c.togglePlainText(arg)
+		c.sendSystemMessage(msg)
+		return err
+
+	case "/compact":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		msg, err := c.toggleCompact(arg)
+		c.sendSystemMessage(msg)
+		return err
+
+	case "/msg":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		msg, notes, err := sendPrivate(c.room, c.Nickname, arg)
+		for _, note := range notes {
+			c.sendSystemMessage(note)
+		}
+		if err != nil {
+			c.sendSystemMessage(fmt.Sprintf("Error: %v", err))
+			return err
+		}
+		return c.sendMessage(msg)
+
+	case "/export":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		payload, err := c.export(arg)
+		if err != nil {
+			c.sendSystemMessage(payload)
+			return err
+		}
+		return c.writeLine(payload)
+
+	case "/more":
+		return c.more()
+
+	case "/topic":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		reply, err := c.changeTopic(arg, c.room.Clock.Now())
+		c.sendSystemMessage(reply)
+		return err
+
+	case "/ping":
+		reply, err := c.startPing(c.room.Clock.Now())
+		c.sendSystemMessage(reply)
+		return err
+
+	case "/feedback":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		reply, err := c.feedback(arg, c.room.Clock.Now())
+		c.sendSystemMessage(reply)
+		return err
+
+	case "/search":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		reply, err := c.search(arg, c.room.Clock.Now())
+		c.sendSystemMessage(reply)
+		return err
+
+	case "/mute":
+		c.sendSystemMessage(c.mute())
+
+	case "/unmute":
+		c.sendSystemMessage(c.unmute())
+
+	case "/dnd":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		msg, err := c.toggleDND(arg)
+		c.sendSystemMessage(msg)
+		return err
+
+	case "/admin":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		msg, err := c.elevate(arg)
+		c.sendSystemMessage(msg)
+		return err
+
+	case "/connections":
+		msg, err := c.listConnections()
+		c.sendSystemMessage(msg)
+		return err
+
+	case "/invite":
+		reply, err := c.invite()
+		c.sendSystemMessage(reply)
+		return err
+
+	case "/disconnect":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		msg, err := c.disconnectAddr(arg)
+		c.sendSystemMessage(msg)
+		return err
+
+	case "/announce":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		if msg, err := c.announce(arg); err != nil {
+			c.sendSystemMessage(msg)
+			return err
+		}
+
+	case "/closeroom":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		// On success the caller is drained along with everyone else
+		if msg, err := c.closeRoom(arg); err != nil {
+			c.sendSystemMessage(msg)
+			return err
+		}
+
 	case "/help":
 		return c.showHelp()
 
 	case "/quit":
+		if len(parts) > 1 {
+			c.setQuitReason(parts[1])
+		}
 		c.sendSystemMessage("Goodbye!")
 		c.close()
 		return nil
@@ -486,25 +1049,165 @@ func (c *Client) handleCommand(cmd string) error {
 	return nil
 }
 
-func (c *Client) showUserList() error {
+// userList renders one page of the /who list for this client
+func (c *Client) userList(page int) string {
 	users := c.room.GetUserList()
+	if c.useCompact() {
+		return ui.FormatUserListCompact(users, c.room.MaxUsers, page)
+	}
+	if c.usePlainText() {
+		return ui.FormatUserListPlain(c.room.Name, users, c.room.MaxUsers, page)
+	}
+	return c.room.theme().FormatUserList(c.room.Name, users, c.room.MaxUsers, page)
+}
+
+func (c *Client) showUserList(page int) error {
+	if err := c.writeLine(c.userList(page)); err != nil {
+		return err
+	}
+	if topic := c.room.Topic(); topic.Text != "" {
+		c.sendSystemMessage(topic.String())
+	}
+	return nil
+}
+
+// parseWhoPage reads the optional page number from "/who [page]"
+func parseWhoPage(parts []string) (int, error) {
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		return 1, nil
+	}
+
+	page, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || page < 1 {
+		return 0, fmt.Errorf("invalid /who page: %s", parts[1])
+	}
+	return page, nil
+}
+
+// sanitizeQuitReason strips control characters and truncates a parting message
+func sanitizeQuitReason(reason string) string {
+	reason = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, reason)
+	reason = strings.TrimSpace(reason)
+
+	if runes := []rune(reason); len(runes) > MaxQuitReasonLen {
+		reason = strings.TrimSpace(string(runes[:MaxQuitReasonLen]))
+	}
+	return reason
+}
+
+// setQuitReason records the parting message announced when the client leaves
+func (c *Client) setQuitReason(reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.quitReason = sanitizeQuitReason(reason)
+}
+
+// getQuitReason returns the parting message, if any
+func (c *Client) getQuitReason() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.quitReason
+}
+
+// usePlainText reports whether this client's output should skip ANSI formatting
+func (c *Client) usePlainText() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.plainText
+}
+
+// renderOptions collects the settings this client's messages render with
+func (c *Client) renderOptions() renderOptions {
+	return renderOptions{plain: c.usePlainText(), links: c.room.Hyperlinks, theme: c.room.theme()}
+}
+
+// setPlainText switches this client between plain and ANSI output
+func (c *Client) setPlainText(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.plainText = enabled
+}
+
+// togglePlainText applies a "/plain on|off" argument and returns the reply to show the user
+func (c *Client) togglePlainText(arg string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(arg)) {
+	case "on":
+		c.setPlainText(true)
+		return "Plain-text mode enabled.", nil
+	case "off":
+		c.setPlainText(false)
+		return "Plain-text mode disabled.", nil
+	case "":
+		if c.usePlainText() {
+			return "Plain-text mode is on. Usage: /plain on|off", nil
+		}
+		return "Plain-text mode is off. Usage: /plain on|off", nil
+	default:
+		return "Usage: /plain on|off", fmt.Errorf("invalid /plain argument: %s", arg)
+	}
+}
+
+// wantsMessage reports whether msg should be shown to this client
+func (c *Client) wantsMessage(msg Message) bool {
+	return !msg.IsPresence() || !c.dndEnabled()
+}
+
+// dndEnabled reports whether this client hides join/leave notices
+func (c *Client) dndEnabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.suppressJoins
+}
+
+// toggleDND applies a "/dnd on|off" argument and returns the reply to show the user
+func (c *Client) toggleDND(arg string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(arg)) {
+	case "on":
+		c.mu.Lock()
+		c.suppressJoins = true
+		c.mu.Unlock()
+		return "Do not disturb enabled. Join/leave notices are hidden.", nil
+	case "off":
+		c.mu.Lock()
+		c.suppressJoins = false
+		c.mu.Unlock()
+		return "Do not disturb disabled.", nil
+	case "":
+		if c.dndEnabled() {
+			return "Do not disturb is on. Usage: /dnd on|off", nil
+		}
+		return "Do not disturb is off. Usage: /dnd on|off", nil
+	default:
+		return "Usage: /dnd on|off", fmt.Errorf("invalid /dnd argument: %s", arg)
+	}
+}
+
+func (c *Client) showStats() error {
+	stats := c.room.Stats()
 	var msg string
-	if c.room.PlainText {
-		msg = ui.FormatUserListPlain(c.room.Name, users, c.room.MaxUsers)
+	if c.usePlainText() {
+		msg = ui.FormatStatsPlain(c.room.Name, stats.TotalMessages, stats.MessagesLastMinute, stats.Online, stats.PeakOnline)
 	} else {
-		msg = ui.FormatUserList(c.room.Name, users, c.room.MaxUsers)
+		msg = ui.FormatStats(c.room.Name, stats.TotalMessages, stats.MessagesLastMinute, stats.Online, stats.PeakOnline)
 	}
-	return c.write(msg + "\r\n")
+	return c.writeLine(msg)
 }
 
 func (c *Client) showHelp() error {
 	var helpMsg string
-	if c.room.PlainText {
-		helpMsg = ui.FormatHelpPlain()
+	if c.useCompact() {
+		helpMsg = ui.FormatHelpCompact(c.room.MOTD != "")
+	} else if c.usePlainText() {
+		helpMsg = ui.FormatHelpPlain(c.room.MOTD != "")
 	} else {
-		helpMsg = ui.FormatHelp()
+		helpMsg = ui.FormatHelp(c.room.MOTD != "")
 	}
-	return c.write(helpMsg + "\r\n")
+	return c.writeLine(helpMsg)
 }
 
 func (c *Client) sendSystemMessage(message string) {
@@ -512,67 +1215,156 @@ func (c *Client) sendSystemMessage(message string) {
 		From:      "System",
 		Content:   message,
 		Timestamp: time.Now(),
-		IsSystem:  true,
+		Kind:      KindSystem,
 	}
 
 	c.sendMessage(msg)
 }
 
-func (c *Client) sendMessage(msg Message) {
-	var formatted string
-	timeStr := msg.Timestamp.Format("15:04:05")
-
-	if c.room.PlainText {
-		if msg.IsSystem {
-			formatted = ui.FormatSystemMessagePlain(msg.Content) + "\r\n"
-		} else if msg.IsAction {
-			formatted = ui.FormatActionMessagePlain(msg.From, msg.Content) + "\r\n"
-		} else {
-			formatted = ui.FormatUserMessagePlain(msg.From, msg.Content, timeStr) + "\r\n"
-		}
-	} else {
-		if msg.IsSystem {
-			formatted = ui.FormatSystemMessage(msg.Content) + "\r\n"
-		} else if msg.IsAction {
-			formatted = ui.FormatActionMessage(msg.From, msg.Content) + "\r\n"
-		} else {
-			formatted = ui.FormatUserMessage(msg.From, msg.Content, timeStr) + "\r\n"
-		}
-	}
+func (c *Client) sendMessage(msg Message) error {
+	return c.send(c.formatFor(msg) + c.newline())
+}
 
+// send writes message to a line-mode client and flushes it; all line-mode
+// output goes through here. A failed write disconnects the client, so callers
+// that can't do anything useful with the error may ignore it.
+func (c *Client) send(message string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.conn == nil {
-		return
+		return errConnClosed
 	}
 
-	if _, err := c.writer.WriteString(formatted); err != nil {
-		log.Printf("Error writing message to %s: %v", c.Nickname, err)
-		return
+	c.setWriteDeadline()
+
+	if _, err := c.writer.WriteString(message); err != nil {
+		c.dropAfterWriteError(err)
+		return fmt.Errorf("error writing message: %w", err)
 	}
 
 	if err := c.writer.Flush(); err != nil {
-		log.Printf("Error flushing message to %s: %v", c.Nickname, err)
-		return
+		c.dropAfterWriteError(err)
+		return fmt.Errorf("error flushing message: %w", err)
 	}
+
+	return nil
 }
 
-func (c *Client) write(message string) error {
+// writeTelnet writes a telnet command to a line-mode client. Commands are
+// protocol rather than text, so they go to the connection directly instead of
+// through the encoder; send has already flushed everything before them.
+func (c *Client) writeTelnet(cmd []byte) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.conn == nil {
-		return fmt.Errorf("connection closed")
+		return errConnClosed
 	}
 
-	if _, err := c.writer.WriteString(message); err != nil {
-		return fmt.Errorf("error writing message: %w", err)
-	}
+	c.setWriteDeadline()
 
-	if err := c.writer.Flush(); err != nil {
-		return fmt.Errorf("error flushing message: %w", err)
+	if _, err := c.conn.Write(cmd); err != nil {
+		c.dropAfterWriteError(err)
+		return fmt.Errorf("error writing telnet command: %w", err)
 	}
 
 	return nil
 }
+
+// setWriteDeadline bounds the next write so a stuck socket can't block forever; c.mu must be held
+func (c *Client) setWriteDeadline() {
+	if c.room.WriteTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.room.WriteTimeout))
+	}
+}
+
+// dropAfterWriteError disconnects a client whose connection failed a write.
+// bufio.Writer keeps failing once a write has, so every later write would
+// only fail again; c.mu must be held.
+func (c *Client) dropAfterWriteError(err error) {
+	if isTimeout(err) {
+		log.Printf("Write to %s timed out, disconnecting", c.Nickname)
+	} else {
+		log.Printf("Write to %s failed, disconnecting: %v", c.Nickname, err)
+	}
+	c.dropConnection()
+}
+
+// dropConnection closes the connection and removes the client from the room; c.mu must be held
+func (c *Client) dropConnection() {
+	c.conn.Close()
+	c.conn = nil
+	c.closeOutbox()
+
+	// Leave asynchronously: we may be running on the room goroutine
+	c.room.leaveAsync(c)
+}
+
+// heartbeat queues a telnet no-op so a dead peer shows up as a failed write
+// instead of lingering until the read deadline. It goes through the same writer
+// as the client's other output, so it can't land inside a message and is
+// bounded by the write timeout. Peers that never spoke telnet would print the
+// bytes, so they are skipped, as is a client whose last heartbeat is still
+// waiting to be written. Any failure disconnects the client.
+func (c *Client) heartbeat() {
+	if !c.Telnet || !c.heartbeatPending.CompareAndSwap(false, true) {
+		return
+	}
+	if c.program != nil {
+		go c.writeTUIHeartbeat()
+		return
+	}
+	c.outboxOnce.Do(func() {
+		c.outbox = newOutbox()
+		go c.drainOutbox()
+	})
+	c.outbox.pushHeartbeat()
+}
+
+// writeHeartbeat writes IAC NOP for the line-mode writer goroutine
+func (c *Client) writeHeartbeat() error {
+	defer c.heartbeatPending.Store(false)
+	return c.writeTelnet(telnetNOP)
+}
+
+// writeTUIHeartbeat writes IAC NOP between bubbletea's writes. Closing the
+// connection on failure ends the program, which leaves the room.
+func (c *Client) writeTUIHeartbeat() {
+	defer c.heartbeatPending.Store(false)
+
+	if _, err := c.tuiOut.Write(telnetNOP); err != nil {
+		log.Printf("Heartbeat to %s failed, disconnecting: %v", c.Nickname, err)
+		c.tuiOut.conn.Close()
+	}
+}
+
+// isTimeout reports whether err is a network timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// deadlineWriter applies a write deadline to every write of the TUI output and
+// closes the connection when one times out, which ends the bubbletea program
+type deadlineWriter struct {
+	mu      sync.Mutex // keeps heartbeats from landing inside a frame
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timeout > 0 {
+		w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	}
+
+	n, err := w.conn.Write(p)
+	if isTimeout(err) {
+		log.Printf("TUI write timed out, disconnecting %s", w.conn.RemoteAddr())
+		w.conn.Close()
+	}
+	return n, err
+}
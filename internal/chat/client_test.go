@@ -1,8 +1,19 @@
 package chat
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 func TestClientConstants(t *testing.T) {
@@ -10,15 +21,15 @@ func TestClientConstants(t *testing.T) {
 	if MaxMessageLength <= 0 {
 		t.Errorf("MaxMessageLength should be positive, got %d", MaxMessageLength)
 	}
-	
+
 	if MaxMessageLength > 10000 {
 		t.Errorf("MaxMessageLength seems too large: %d", MaxMessageLength)
 	}
-	
+
 	if MessageRateLimit <= 0 {
 		t.Errorf("MessageRateLimit should be positive, got %d", MessageRateLimit)
 	}
-	
+
 	if RateLimitWindow <= 0 {
 		t.Errorf("RateLimitWindow should be positive, got %v", RateLimitWindow)
 	}
@@ -27,16 +38,16 @@ func TestClientConstants(t *testing.T) {
 func TestMessageValidation(t *testing.T) {
 	// Test message length constants
 	tests := []struct {
-		name        string
-		messageLen  int
-		shouldBeOK  bool
+		name       string
+		messageLen int
+		shouldBeOK bool
 	}{
 		{"short message", 10, true},
 		{"medium message", 500, true},
 		{"max length", MaxMessageLength, true},
 		{"over max", MaxMessageLength + 1, false},
 	}
-	
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			msg := strings.Repeat("a", tt.messageLen)
@@ -48,4 +59,1118 @@ func TestMessageValidation(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestTogglePlainText(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	client := NewTUIClient(nil, room)
+	if !client.usePlainText() {
+		t.Fatal("Expected new client to inherit the room plain-text default")
+	}
+
+	tests := []struct {
+		arg       string
+		wantPlain bool
+		wantErr   bool
+	}{
+		{"off", false, false},
+		{"ON", true, false},
+		{"", true, false},
+		{"maybe", true, true},
+	}
+
+	for _, tt := range tests {
+		_, err := client.togglePlainText(tt.arg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("togglePlainText(%q) error = %v, wantErr %v", tt.arg, err, tt.wantErr)
+		}
+		if client.usePlainText() != tt.wantPlain {
+			t.Errorf("After togglePlainText(%q), plainText = %v, want %v", tt.arg, client.usePlainText(), tt.wantPlain)
+		}
+	}
+}
+
+func TestSanitizeQuitReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason string
+		want   string
+	}{
+		{"plain", "going to lunch", "going to lunch"},
+		{"surrounding space", "  brb  ", "brb"},
+		{"control characters", "bye\x1b[31m\x07", "bye[31m"},
+		{"empty", "   ", ""},
+		{"too long", strings.Repeat("a", MaxQuitReasonLen+10), strings.Repeat("a", MaxQuitReasonLen)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeQuitReason(tt.reason); got != tt.want {
+				t.Errorf("sanitizeQuitReason(%q) = %q, want %q", tt.reason, got, tt.want)
+			}
+		})
+	}
+}
+
+// failingConn fails every write after the first allowed ones succeed
+type failingConn struct {
+	net.Conn
+	mu      sync.Mutex
+	allowed int
+}
+
+func (c *failingConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.allowed <= 0 {
+		return 0, errors.New("write failed")
+	}
+	c.allowed--
+	return c.Conn.Write(p)
+}
+
+// drain discards everything written to the other end of a pipe
+func drain(conn net.Conn) {
+	io.Copy(io.Discard, conn)
+}
+
+// assertRoomEmpty checks that no users or reservations are left behind
+func assertRoomEmpty(t *testing.T, room *Room) {
+	t.Helper()
+	if users := room.GetUserList(); len(users) != 0 {
+		t.Errorf("Expected no users, got %v", users)
+	}
+	room.mu.RLock()
+	defer room.mu.RUnlock()
+	if len(room.clients) != 0 {
+		t.Errorf("Expected empty client map, got %d entries", len(room.clients))
+	}
+}
+
+func TestNewPlainTextClientReservationCleanup(t *testing.T) {
+	t.Run("nickname failure", func(t *testing.T) {
+		room := NewRoom("Test Room", 5, false, 0, true)
+		defer room.Stop()
+
+		server, remote := net.Pipe()
+		go func() {
+			buf := make([]byte, 512)
+			remote.Read(buf) // title
+			remote.Read(buf) // nickname prompt
+			remote.Close()
+		}()
+
+		if _, err := NewPlainTextClient(server, room, ClientOptions{PlainText: true}); err == nil {
+			t.Fatal("Expected nickname failure")
+		}
+		assertRoomEmpty(t, room)
+	})
+
+	t.Run("room full", func(t *testing.T) {
+		room := NewRoom("Test Room", 1, false, 0, true)
+		defer room.Stop()
+
+		existing := NewTUIClient(nil, room)
+		existing.Nickname = "alice"
+		room.ReserveNickname("alice")
+		room.Join(existing)
+
+		server, remote := net.Pipe()
+		defer remote.Close()
+		go drain(remote)
+		go remote.Write([]byte("bob\n"))
+
+		if _, err := NewPlainTextClient(server, room, ClientOptions{PlainText: true}); err == nil {
+			t.Fatal("Expected room full error")
+		}
+
+		room.Leave(existing)
+		time.Sleep(10 * time.Millisecond)
+		assertRoomEmpty(t, room)
+	})
+
+	t.Run("welcome failure", func(t *testing.T) {
+		room := NewRoom("Test Room", 5, false, 0, true)
+		defer room.Stop()
+
+		server, remote := net.Pipe()
+		defer remote.Close()
+		go drain(remote)
+		go remote.Write([]byte("bob\n"))
+
+		// Allow the title and nickname prompt, then fail the welcome banner
+		conn := &failingConn{Conn: server, allowed: 2}
+		if _, err := NewPlainTextClient(conn, room, ClientOptions{PlainText: true}); err == nil {
+			t.Fatal("Expected welcome failure")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		assertRoomEmpty(t, room)
+	})
+}
+
+func TestLeaveDoesNotEvictNewOwner(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+
+	stale := NewTUIClient(nil, room)
+	stale.Nickname = "alice"
+
+	current := NewTUIClient(nil, room)
+	current.Nickname = "alice"
+	room.ReserveNickname("alice")
+	room.Join(current)
+
+	// A client that never joined leaving must not remove the current owner
+	room.Leave(stale)
+	time.Sleep(10 * time.Millisecond)
+
+	if users := room.GetUserList(); len(users) != 1 || users[0] != "alice" {
+		t.Errorf("Expected alice to remain, got %v", users)
+	}
+}
+
+func TestNickHandshake(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		taken    string
+		wantNick string
+	}{
+		{"valid handshake", "NICK bob\n", "", "bob"},
+		{"lowercase prefix", "nick bob\n", "", "bob"},
+		{"invalid falls back to prompt", "NICK !!\ncarol\n", "", "carol"},
+		{"taken falls back to prompt", "NICK bob\ncarol\n", "bob", "carol"},
+		{"no handshake", "dave\n", "", "dave"},
+		{"erased space", "NICK \x7f\ncarol\n", "", "carol"},
+		{"erased prefix", "NICK \b\b\b\b\beve\nfrank\n", "", "frank"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := NewRoom("Test Room", 5, false, 0, true)
+			room.AllowNickHandshake = true
+			defer room.Stop()
+
+			if tt.taken != "" {
+				room.ReserveNickname(tt.taken)
+			}
+
+			server, remote := net.Pipe()
+			defer remote.Close()
+			go drain(remote)
+			go remote.Write([]byte(tt.input))
+
+			client, err := NewPlainTextClient(server, room, ClientOptions{PlainText: true})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer client.close()
+
+			if client.Nickname != tt.wantNick {
+				t.Errorf("Expected nickname %q, got %q", tt.wantNick, client.Nickname)
+			}
+		})
+	}
+}
+
+func TestWriteTimeoutEvictsStuckClient(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	room.WriteTimeout = 50 * time.Millisecond
+	defer room.Stop()
+
+	// Nobody reads from the remote end, so every write blocks until the deadline
+	server, remote := net.Pipe()
+	defer remote.Close()
+
+	client := &Client{
+		Nickname:  "stuck",
+		conn:      server,
+		writer:    bufio.NewWriter(server),
+		room:      room,
+		plainText: true,
+	}
+	room.ReserveNickname("stuck")
+	room.Join(client)
+
+	room.Broadcast(Message{From: "Alice", Content: "Hello", Timestamp: time.Now()})
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if len(room.GetUserList()) == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Expected stuck client to be evicted, users: %v", room.GetUserList())
+}
+
+func TestValidateNicknameBounds(t *testing.T) {
+	tests := []struct {
+		nickname string
+		min, max int
+		wantErr  bool
+	}{
+		{"ab", 2, 20, false},
+		{"a", 2, 20, true},
+		{"abcd", 5, 10, true},
+		{"abcdef", 5, 10, false},
+		{"abcdefghijk", 5, 10, true},
+		{"system", 2, 20, true},
+		{"bad name", 2, 20, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nickname, func(t *testing.T) {
+			err := validateNickname(tt.nickname, tt.min, tt.max)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateNickname(%q, %d, %d) error = %v, wantErr %v", tt.nickname, tt.min, tt.max, err, tt.wantErr)
+			}
+		})
+	}
+
+	err := validateNickname("abc", 5, 10)
+	if err == nil || !strings.Contains(err.Error(), "between 5 and 10") {
+		t.Errorf("Expected error to state configured bounds, got %v", err)
+	}
+}
+
+func TestValidateNicknameRejectsInvisibleCharacters(t *testing.T) {
+	tests := []struct {
+		name     string
+		nickname string
+	}{
+		{"zero-width joiner", "ali\u200dce"},
+		{"zero-width space", "bob\u200b"},
+		{"right-to-left override", "\u202eecila"},
+		{"left-to-right isolate", "carol\u2066"},
+		{"combining mark", "dave\u0301"},
+		{"control character", "eve\x07"},
+		{"byte order mark", "\ufefffrank"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNickname(tt.nickname, 2, 20)
+			if err == nil || !strings.Contains(err.Error(), "invisible") {
+				t.Errorf("validateNickname(%q) error = %v, want invisible-character error", tt.nickname, err)
+			}
+		})
+	}
+}
+
+func TestNormalizeNickname(t *testing.T) {
+	// "e" plus a combining acute accent composes to a single "é" under NFC
+	if got := normalizeNickname("  jose\u0301 "); got != "jos\u00e9" {
+		t.Errorf("normalizeNickname() = %q, want %q", got, "jos\u00e9")
+	}
+	if got := normalizeNickname("alice"); got != "alice" {
+		t.Errorf("normalizeNickname(alice) = %q", got)
+	}
+}
+
+func TestCustomNicknameBoundsPlainText(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	room.MinNicknameLen = 5
+	room.MaxNicknameLen = 8
+	defer room.Stop()
+
+	server, remote := net.Pipe()
+	defer remote.Close()
+	go drain(remote)
+	go remote.Write([]byte("bob\nbobbybobby\nbobby\n"))
+
+	client, err := NewPlainTextClient(server, room, ClientOptions{PlainText: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer client.close()
+
+	if client.Nickname != "bobby" {
+		t.Errorf("Expected nickname within custom bounds, got %q", client.Nickname)
+	}
+}
+
+func TestCustomLimitsTUI(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	room.MinNicknameLen = 5
+	room.MaxNicknameLen = 8
+	room.MaxMessageLength = 10
+	defer room.Stop()
+
+	model := NewChatModel(NewTUIClient(nil, room))
+	if model.textInput.CharLimit != 8 {
+		t.Errorf("Expected nickname CharLimit 8, got %d", model.textInput.CharLimit)
+	}
+
+	model.textInput.SetValue("bob")
+	updated, _ := model.updateNickname(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updated.(ChatModel)
+	if !strings.Contains(model.errMsg, "between 5 and 8") {
+		t.Errorf("Expected bounds error for short nickname, got %q", model.errMsg)
+	}
+
+	model.textInput.SetValue("bobby")
+	updated, _ = model.updateNickname(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updated.(ChatModel)
+	if model.errMsg != "" {
+		t.Errorf("Expected nickname to be accepted, got %q", model.errMsg)
+	}
+
+	if err := model.client.validateMessageLength(strings.Repeat("a", 11)); err == nil {
+		t.Error("Expected message over the custom limit to be rejected")
+	}
+}
+
+func TestNicknameAttemptsCapPlainText(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	room.MaxNicknameAttempts = 3
+	defer room.Stop()
+
+	if !room.ReserveNickname("taken") {
+		t.Fatal("Failed to reserve nickname")
+	}
+
+	server, remote := net.Pipe()
+	defer remote.Close()
+	go drain(remote)
+	go remote.Write([]byte("x\ntaken\n!!\nalice\n"))
+
+	_, err := NewPlainTextClient(server, room, ClientOptions{PlainText: true})
+	if !errors.Is(err, ErrTooManyNicknameAttempts) {
+		t.Fatalf("Expected ErrTooManyNicknameAttempts, got %v", err)
+	}
+}
+
+func TestNicknameAttemptsCapTUI(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	room.MaxNicknameAttempts = 2
+	defer room.Stop()
+
+	model := NewChatModel(NewTUIClient(nil, room))
+
+	model.textInput.SetValue("x")
+	updated, cmd := model.updateNickname(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updated.(ChatModel)
+	if model.quitting || cmd != nil {
+		t.Fatal("Expected first rejected nickname to allow another try")
+	}
+
+	model.textInput.SetValue("y")
+	updated, _ = model.updateNickname(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updated.(ChatModel)
+	if !model.quitting {
+		t.Error("Expected client to be disconnected after reaching the attempt cap")
+	}
+}
+
+func TestDoNotDisturb(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	join := Message{From: "System", Content: "bob has joined the room", Kind: KindJoin}
+	notice := Message{From: "System", Content: "Error: too fast", Kind: KindSystem}
+	chat := Message{From: "bob", Content: "hi"}
+
+	client := NewTUIClient(nil, room)
+	if !client.wantsMessage(join) {
+		t.Error("Expected join notices to be shown by default")
+	}
+
+	if _, err := client.toggleDND("on"); err != nil {
+		t.Fatalf("toggleDND(on) error = %v", err)
+	}
+	if client.wantsMessage(join) {
+		t.Error("Expected join notices to be hidden in do-not-disturb mode")
+	}
+	if !client.wantsMessage(notice) || !client.wantsMessage(chat) {
+		t.Error("Expected other system messages and chat to still be shown")
+	}
+
+	if _, err := client.toggleDND("maybe"); err == nil {
+		t.Error("Expected invalid /dnd argument to be rejected")
+	}
+
+	client.toggleDND("off")
+	if !client.wantsMessage(join) {
+		t.Error("Expected join notices to be shown again after /dnd off")
+	}
+
+	room.QuietJoins = true
+	if NewTUIClient(nil, room).wantsMessage(join) {
+		t.Error("Expected QuietJoins to start clients in do-not-disturb mode")
+	}
+}
+
+func TestHeartbeatEvictsDeadPeer(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	server, remote := net.Pipe()
+	defer remote.Close()
+	go drain(remote)
+
+	// The peer is gone: every write fails
+	conn := &failingConn{Conn: server}
+	client := &Client{
+		Nickname:  "ghost",
+		Telnet:    true,
+		conn:      conn,
+		writer:    bufio.NewWriter(conn),
+		room:      room,
+		plainText: true,
+	}
+	room.ReserveNickname("ghost")
+	room.Join(client)
+
+	room.SetHeartbeatInterval(10 * time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if len(room.GetUserList()) == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Expected dead peer to be evicted, users: %v", room.GetUserList())
+}
+
+func TestHeartbeatSendsTelnetNOP(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	server, remote := net.Pipe()
+	defer remote.Close()
+
+	client := &Client{
+		Nickname: "alice",
+		Telnet:   true,
+		conn:     server,
+		writer:   bufio.NewWriter(server),
+		room:     room,
+	}
+	defer client.closeOutbox()
+
+	// The pipe blocks the first write until it is read, so the later
+	// heartbeats find one still pending and are skipped
+	client.heartbeat()
+	client.heartbeat()
+	client.heartbeat()
+
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(remote, buf); err != nil {
+		t.Fatalf("ReadFull() error = %v", err)
+	}
+	if buf[0] != 255 || buf[1] != 241 {
+		t.Errorf("Expected IAC NOP, got %v", buf)
+	}
+
+	remote.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if n, err := remote.Read(buf); err == nil {
+		t.Errorf("Expected one heartbeat while one was pending, got %v more", buf[:n])
+	}
+}
+
+func TestHeartbeatTUI(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+
+	server, remote := net.Pipe()
+	defer remote.Close()
+
+	// TUI output shares the deadline writer, which serializes the NOP with frames
+	client := NewTUIClient(server, room)
+	client.Telnet = true
+	client.program = tea.NewProgram(nil)
+	client.tuiOut = &deadlineWriter{conn: server, timeout: time.Second}
+	client.heartbeat()
+
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(remote, buf); err != nil {
+		t.Fatalf("ReadFull() error = %v", err)
+	}
+	if buf[0] != 255 || buf[1] != 241 {
+		t.Errorf("Expected IAC NOP, got %v", buf)
+	}
+	waitFor(t, "the heartbeat to finish", func() bool { return !client.heartbeatPending.Load() })
+}
+
+func TestHeartbeatSkipsNonTelnetPeers(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	server, remote := net.Pipe()
+	defer remote.Close()
+
+	// A netcat user would see the IAC NOP bytes as garbage
+	client := &Client{
+		Nickname: "alice",
+		conn:     server,
+		writer:   bufio.NewWriter(server),
+		room:     room,
+	}
+	defer client.closeOutbox()
+	client.heartbeat()
+
+	remote.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	buf := make([]byte, 2)
+	if n, err := remote.Read(buf); err == nil {
+		t.Errorf("Expected nothing sent to a non-telnet peer, got %v", buf[:n])
+	}
+}
+
+func TestTUIScrollbackLimit(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	room.Scrollback = 50
+	defer room.Stop()
+
+	model := NewChatModel(NewTUIClient(nil, room))
+	model.width, model.height = 80, 24
+	model.initViewport()
+
+	for i := 0; i < 200; i++ {
+		updated, _ := model.handleChatMsg(ChatMsg{Message: Message{From: "bob", Content: fmt.Sprintf("message %d", i), Timestamp: time.Now()}})
+		model = updated.(ChatModel)
+	}
+	model.appendSystemMessage("notice")
+
+	if len(model.messages) != 50 {
+		t.Fatalf("Expected scrollback capped at 50, got %d", len(model.messages))
+	}
+	if first := model.messages[0].Content; first != "message 151" {
+		t.Errorf("Expected oldest kept message to be 'message 151', got %q", first)
+	}
+	if last := model.messages[49].Content; last != "notice" {
+		t.Errorf("Expected newest message last, got %q", last)
+	}
+	if !model.viewport.AtBottom() {
+		t.Error("Expected viewport to stay scrolled to the bottom")
+	}
+}
+
+func TestReadLineEndings(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"CRLF", "hello\r\nworld\r\n", []string{"hello", "world"}},
+		{"LF", "hello\nworld\n", []string{"hello", "world"}},
+		{"bare CR", "hello\rworld\r", []string{"hello", "world"}},
+		{"CR NUL", "hello\r\x00world\r\x00", []string{"hello", "world"}},
+		{"mixed", "a\r\nb\nc\rd\r\x00", []string{"a", "b", "c", "d"}},
+		{"blank line after CRLF", "a\r\n\r\nb\n", []string{"a", "", "b"}},
+		{"backspace", "helo\blo\r\n", []string{"hello"}},
+		{"delete", "helo\x7flo\r\n", []string{"hello"}},
+		{"erase past start", "\b\bhi\b\b\bok\n", []string{"ok"}},
+		{"erase multibyte", "caf\u00e9\be\n", []string{"cafe"}},
+		{"erase whole line", "oops\x7f\x7f\x7f\x7f\r\n", []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{reader: bufio.NewReader(strings.NewReader(tt.input))}
+			for _, want := range tt.want {
+				got, err := client.readLine()
+				if err != nil {
+					t.Fatalf("readLine() error = %v", err)
+				}
+				if got != want {
+					t.Errorf("readLine() = %q, want %q", got, want)
+				}
+			}
+			if _, err := client.readLine(); err != io.EOF {
+				t.Errorf("Expected io.EOF after the last line, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBareCRDoesNotHang(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	events, unsubscribe := room.Subscribe()
+	defer unsubscribe()
+
+	server, remote := net.Pipe()
+	defer remote.Close()
+	go drain(remote)
+	go remote.Write([]byte("alice\rhello\r"))
+
+	client, err := NewPlainTextClient(server, room, ClientOptions{PlainText: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	handled := make(chan struct{})
+	go func() {
+		client.Handle(context.Background())
+		close(handled)
+	}()
+	// Let the client leave before the deferred room.Stop runs
+	defer func() {
+		remote.Close()
+		<-handled
+	}()
+
+	if client.Nickname != "alice" {
+		t.Errorf("Expected nickname alice, got %q", client.Nickname)
+	}
+	for {
+		select {
+		case msg := <-events:
+			if msg.Kind != KindUser {
+				continue
+			}
+			if msg.Content != "hello" {
+				t.Errorf("Expected message %q, got %q", "hello", msg.Content)
+			}
+			return
+		case <-time.After(2 * time.Second):
+			t.Fatal("Bare-CR message was never delivered")
+		}
+	}
+}
+
+func TestShortReadPollInterval(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	room.ReadPollInterval = 10 * time.Millisecond
+
+	events, unsubscribe := room.Subscribe()
+	defer unsubscribe()
+
+	server, remote := net.Pipe()
+	defer remote.Close()
+	go drain(remote)
+	go remote.Write([]byte("alice\n"))
+
+	client, err := NewPlainTextClient(server, room, ClientOptions{PlainText: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handled := make(chan struct{})
+	go func() {
+		client.Handle(ctx)
+		close(handled)
+	}()
+
+	// Split the line across several deadlines; the first half must not be lost
+	remote.Write([]byte("hel"))
+	time.Sleep(50 * time.Millisecond)
+	remote.Write([]byte("lo\n"))
+
+	for delivered := false; !delivered; {
+		select {
+		case msg := <-events:
+			if msg.Kind != KindUser {
+				continue
+			}
+			if msg.Content != "hello" {
+				t.Fatalf("Expected %q, got %q", "hello", msg.Content)
+			}
+			delivered = true
+		case <-time.After(2 * time.Second):
+			t.Fatal("Message was not processed with a short poll interval")
+		}
+	}
+
+	cancel()
+	select {
+	case <-handled:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Handle did not return promptly after cancellation")
+	}
+}
+
+func TestWriteErrorRemovesClient(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	server, remote := net.Pipe()
+	defer remote.Close()
+	go drain(remote)
+	conn := &failingConn{Conn: server}
+
+	client := &Client{
+		Nickname:  "broken",
+		conn:      conn,
+		writer:    bufio.NewWriter(conn),
+		room:      room,
+		plainText: true,
+	}
+	room.ReserveNickname("broken")
+	room.Join(client)
+
+	room.Broadcast(Message{From: "Alice", Content: "Hello", Timestamp: time.Now()})
+
+	deadline := time.Now().Add(time.Second)
+	for len(room.GetUserList()) != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if users := room.GetUserList(); len(users) != 0 {
+		t.Fatalf("Expected client with a failing writer to be removed, users: %v", users)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.conn != nil {
+		t.Error("Expected the connection to be closed")
+	}
+}
+
+func TestResizeBeforeJoin(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+
+	model := NewChatModel(NewTUIClient(nil, room))
+
+	updated, _ := model.Update(tea.WindowSizeMsg{Width: 30, Height: 40})
+	model = updated.(ChatModel)
+	if model.textInput.Width != 26 {
+		t.Errorf("Expected nickname input width 26 on a narrow terminal, got %d", model.textInput.Width)
+	}
+
+	updated, _ = model.Update(tea.WindowSizeMsg{Width: 120, Height: 50})
+	model = updated.(ChatModel)
+	if model.textInput.Width != 40 {
+		t.Errorf("Expected nickname input width capped at 40, got %d", model.textInput.Width)
+	}
+
+	updated, _ = model.Update(JoinedMsg{})
+	model = updated.(ChatModel)
+	if model.viewport.Width != 120 || model.viewport.Height != 45 {
+		t.Errorf("Expected a 120x45 viewport after join, got %dx%d", model.viewport.Width, model.viewport.Height)
+	}
+	if model.textInput.Width != 116 {
+		t.Errorf("Expected chat input width 116 after join, got %d", model.textInput.Width)
+	}
+}
+
+func TestTUIWhoMatchesLineMode(t *testing.T) {
+	room := NewRoom("Lobby", 5, false, 0, false)
+	defer room.Stop()
+
+	self := NewTUIClient(nil, room)
+	self.Nickname = "alice"
+	model := NewChatModel(self)
+	model.initViewport()
+	for _, nickname := range []string{"alice", "bob"} {
+		client := NewTUIClient(nil, room)
+		client.Nickname = nickname
+		room.Join(client)
+	}
+
+	updated, _ := model.handleCommand("/who")
+	model = *updated.(*ChatModel)
+	got := model.formatMessage(model.messages[len(model.messages)-1])
+
+	want := "╭─────────────────────────╮\n" +
+		"│  Users in Lobby (2/5):  │\n" +
+		"│ - alice                 │\n" +
+		"│ - bob                   │\n" +
+		"│                         │\n" +
+		"╰─────────────────────────╯"
+	if got != want {
+		t.Errorf("TUI /who rendered\n%s\nwant\n%s", got, want)
+	}
+	if line := self.userList(1); got != line {
+		t.Errorf("TUI /who differs from the line-mode list:\n%s\nvs\n%s", got, line)
+	}
+}
+
+func TestStatusBarFillsWidth(t *testing.T) {
+	room := NewRoom("Lobby", 5, false, 0, false)
+	defer room.Stop()
+
+	client := NewTUIClient(nil, room)
+	client.Nickname = "alice"
+	model := NewChatModel(client)
+	model.width, model.height = 60, 20
+	model.initViewport()
+
+	for _, line := range strings.Split(model.chatView(), "\n") {
+		if strings.Contains(line, "online") {
+			if w := lipgloss.Width(line); w != 60 {
+				t.Errorf("Status bar is %d columns wide, want 60: %q", w, line)
+			}
+			if !strings.Contains(line, "alice | 0 online") {
+				t.Errorf("Status bar = %q, want the nickname and online count", line)
+			}
+			return
+		}
+	}
+	t.Fatal("No status bar in the chat view")
+}
+
+func TestAnimatedBannerSendsEveryLine(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	room.Banner = "line one\nline two\nline three"
+	room.BannerAnimateDelay = time.Millisecond
+	defer room.Stop()
+
+	conn := &lockedBufferConn{}
+	client := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room}
+	if err := client.sendWelcomeMessage(); err != nil {
+		t.Fatalf("sendWelcomeMessage() error = %v", err)
+	}
+
+	out := conn.String()
+	last := -1
+	for _, line := range []string{"line one", "line two", "line three"} {
+		i := strings.Index(out, line)
+		if i < 0 {
+			t.Fatalf("Banner line %q missing from %q", line, out)
+		}
+		if i < last {
+			t.Errorf("Banner line %q out of order in %q", line, out)
+		}
+		last = i
+	}
+	if !strings.Contains(out, "alice") {
+		t.Errorf("Expected the welcome message after the banner, got %q", out)
+	}
+}
+
+func TestAnimatedBannerSkippedInPlainText(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	room.Banner = "line one\nline two"
+	room.BannerAnimateDelay = time.Hour // would hang the test if it animated
+	defer room.Stop()
+
+	conn := &lockedBufferConn{}
+	client := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+	if err := client.sendWelcomeMessage(); err != nil {
+		t.Fatalf("sendWelcomeMessage() error = %v", err)
+	}
+	if out := conn.String(); !strings.Contains(out, "line one\nline two\r\n") {
+		t.Errorf("Expected the whole banner at once, got %q", out)
+	}
+}
+
+func TestAnimatedBannerStopsOnDisconnect(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	room.Banner = "line one\nline two"
+	room.BannerAnimateDelay = 20 * time.Millisecond
+	defer room.Stop()
+
+	conn := &closableConn{}
+	client := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room}
+	time.AfterFunc(5*time.Millisecond, client.close)
+
+	if err := client.sendWelcomeMessage(); err == nil {
+		t.Fatal("Expected the animation to stop once the client disconnected")
+	}
+	if out := conn.String(); strings.Contains(out, "line two") {
+		t.Errorf("Expected the animation to stop after the first line, got %q", out)
+	}
+}
+
+// mockConn is a net.Conn that reads scripted input and records output
+type mockConn struct {
+	in     *strings.Reader
+	mu     sync.Mutex
+	out    strings.Builder
+	closed bool
+}
+
+func newMockConn(input string) *mockConn {
+	return &mockConn{in: strings.NewReader(input)}
+}
+
+func (c *mockConn) Read(p []byte) (int, error) { return c.in.Read(p) }
+
+func (c *mockConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.Write(p)
+}
+
+func (c *mockConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *mockConn) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.String()
+}
+
+func (c *mockConn) LocalAddr() net.Addr { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2323} }
+func (c *mockConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(100, 64, 0, 1), Port: 40000}
+}
+func (c *mockConn) SetDeadline(t time.Time) error      { return nil }
+func (c *mockConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *mockConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// loginMock logs a line-mode client in over a mockConn with the given input
+func loginMock(t *testing.T, room *Room, input string) (*Client, *mockConn) {
+	t.Helper()
+	conn := newMockConn(input)
+	client := newPlainTextClient(conn, room, ClientOptions{PlainText: true})
+	if err := client.login(); err != nil {
+		t.Fatalf("login() error = %v", err)
+	}
+	return client, conn
+}
+
+func TestMockClientLogin(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	client, conn := loginMock(t, room, "x\r\nalice\r\n")
+	if client.Nickname != "alice" {
+		t.Errorf("Nickname = %q, want alice", client.Nickname)
+	}
+	out := conn.String()
+	if !strings.Contains(out, "Nickname must be between") {
+		t.Errorf("Expected the short nickname to be rejected first, got %q", out)
+	}
+	if !strings.Contains(out, "Welcome to Test Room, alice!") {
+		t.Errorf("Expected the welcome message, got %q", out)
+	}
+	if users := room.GetUserList(); len(users) != 1 || users[0] != "alice" {
+		t.Errorf("GetUserList() = %v, want [alice]", users)
+	}
+}
+
+func TestMockClientCommands(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	client, conn := loginMock(t, room, "alice\r\n")
+	loginMock(t, room, "bob\r\n")
+
+	if err := client.handleCommand("/who"); err != nil {
+		t.Fatalf("/who error = %v", err)
+	}
+	if out := conn.String(); !strings.Contains(out, "Users in Test Room (2/5):") || !strings.Contains(out, "- bob") {
+		t.Errorf("Expected /who to list both users, got %q", out)
+	}
+
+	if err := client.handleCommand("/me waves"); err != nil {
+		t.Fatalf("/me error = %v", err)
+	}
+	waitFor(t, "the /me action", func() bool {
+		client.flush()
+		return strings.Contains(conn.String(), "alice waves")
+	})
+
+	if err := client.handleCommand("/me"); err == nil {
+		t.Error("Expected /me without an action to fail")
+	}
+
+	if err := client.handleCommand("/bogus"); err == nil {
+		t.Error("Expected an unknown command to fail")
+	}
+	if out := conn.String(); !strings.Contains(out, "Unknown command: /bogus") {
+		t.Errorf("Expected an unknown command notice, got %q", out)
+	}
+}
+
+func TestMockClientRateLimitAndSend(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	room.Clock = newFakeClock()
+
+	client, conn := loginMock(t, room, "alice\r\n")
+	for i := 0; i < MessageRateLimit; i++ {
+		if err := client.checkRateLimit(); err != nil {
+			t.Fatalf("Message %d rejected: %v", i+1, err)
+		}
+	}
+	if err := client.checkRateLimit(); err == nil {
+		t.Error("Expected the message over the limit to be rejected")
+	}
+
+	client.sendMessage(Message{From: "bob", Content: "hi alice", Timestamp: time.Now()})
+	if out := conn.String(); !strings.Contains(out, "bob: hi alice") {
+		t.Errorf("Expected sendMessage to write the message, got %q", out)
+	}
+}
+
+func TestSendWithoutConnection(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	client := &Client{Nickname: "alice", room: room, plainText: true}
+	if err := client.send("hello\r\n"); !errors.Is(err, errConnClosed) {
+		t.Errorf("send() without a connection = %v, want errConnClosed", err)
+	}
+	if err := client.sendMessage(Message{From: "bob", Content: "hi"}); !errors.Is(err, errConnClosed) {
+		t.Errorf("sendMessage() without a connection = %v, want errConnClosed", err)
+	}
+}
+
+func TestSendWriteErrorDisconnects(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	server, remote := net.Pipe()
+	defer remote.Close()
+	conn := &failingConn{Conn: server}
+	client := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+
+	if err := client.send("hello\r\n"); err == nil || errors.Is(err, errConnClosed) {
+		t.Fatalf("send() = %v, want the write error", err)
+	}
+	client.mu.Lock()
+	dropped := client.conn == nil
+	client.mu.Unlock()
+	if !dropped {
+		t.Error("Expected a failed write to drop the connection")
+	}
+
+	// Later writes fail fast instead of retrying the broken connection
+	if err := client.send("again\r\n"); !errors.Is(err, errConnClosed) {
+		t.Errorf("send() after a failed write = %v, want errConnClosed", err)
+	}
+}
+
+func TestClientBufferSizes(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		want int
+	}{
+		{"default", 0, DefaultBufferSize},
+		{"small", MinBufferSize, MinBufferSize},
+		{"large", 64 << 10, 64 << 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := NewRoom("Test Room", 5, false, 0, true)
+			defer room.Stop()
+			room.ReadBufferSize = tt.size
+			room.WriteBufferSize = tt.size
+
+			conn := &scriptedConn{in: strings.NewReader("bob\n")}
+			client, err := NewPlainTextClient(conn, room, ClientOptions{PlainText: true})
+			if err != nil {
+				t.Fatalf("NewPlainTextClient() error = %v", err)
+			}
+			if client.reader.Size() != tt.want || client.writer.Size() != tt.want {
+				t.Errorf("Buffer sizes = %d/%d, want %d", client.reader.Size(), client.writer.Size(), tt.want)
+			}
+
+			// A message larger than the buffer still arrives whole
+			long := strings.Repeat("x", 3*MinBufferSize)
+			if err := client.sendMessage(Message{From: "alice", Content: long, Timestamp: time.Now()}); err != nil {
+				t.Fatalf("sendMessage() error = %v", err)
+			}
+			client.flush()
+			if out := conn.String(); !strings.Contains(out, "alice: "+long+"\r\n") {
+				t.Errorf("Expected the long message intact in the output, got %q", out)
+			}
+		})
+	}
+}
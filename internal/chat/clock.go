@@ -0,0 +1,16 @@
+package chat
+
+import "time"
+
+// Clock tells the time. Rooms and their clients read it through Room.Clock so
+// rate limiting, history aging and stats can be tested without sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the default Clock, backed by time.Now
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
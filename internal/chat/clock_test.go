@@ -0,0 +1,83 @@
+package chat
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when told to
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+}
+
+func TestRateLimitWindowWithFakeClock(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+	clock := newFakeClock()
+	room.Clock = clock
+
+	client := NewTUIClient(nil, room)
+	for i := 0; i < MessageRateLimit; i++ {
+		if err := client.checkRateLimit(); err != nil {
+			t.Fatalf("Message %d rejected: %v", i+1, err)
+		}
+	}
+	if err := client.checkRateLimit(); err == nil {
+		t.Fatal("Expected the message over the limit to be rejected")
+	}
+
+	// The first burst is still inside the window one nanosecond before it closes
+	clock.Advance(RateLimitWindow - time.Nanosecond)
+	if err := client.checkRateLimit(); err == nil {
+		t.Error("Expected a rejection just inside the window")
+	}
+
+	// At exactly the window boundary the first burst has aged out
+	clock.Advance(time.Nanosecond)
+	if err := client.checkRateLimit(); err != nil {
+		t.Errorf("Expected a message at the window boundary to pass, got %v", err)
+	}
+}
+
+func TestHistoryAgingWithFakeClock(t *testing.T) {
+	room := NewRoom("Test Room", 5, true, 10, false)
+	defer room.Stop()
+	clock := newFakeClock()
+	room.Clock = clock
+
+	room.historyMu.Lock()
+	room.historyMaxAge = time.Hour
+	room.historyMu.Unlock()
+
+	room.addToHistory(Message{Content: "old", Timestamp: clock.Now()})
+	clock.Advance(30 * time.Minute)
+	room.addToHistory(Message{Content: "new", Timestamp: clock.Now()})
+
+	clock.Advance(31 * time.Minute)
+	room.historyMu.Lock()
+	room.compactHistoryLocked()
+	room.historyMu.Unlock()
+
+	history := room.GetHistory()
+	if len(history) != 1 || history[0].Content != "new" {
+		t.Errorf("GetHistory() = %v, want only the message younger than an hour", history)
+	}
+}
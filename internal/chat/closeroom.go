@@ -0,0 +1,96 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// closeRoomNotice is the last thing occupants see when an admin closes the room
+const closeRoomNotice = "This room is being closed for maintenance. Goodbye!"
+
+// drainRequest asks the run loop to disconnect everyone and reports how many
+// clients it dropped
+type drainRequest struct {
+	notice string
+	done   chan int
+}
+
+// Drain disconnects every occupant of the room with notice as their last
+// message, without stopping the room or the server. No leave notices are
+// broadcast. Users still logging in keep their nickname reservations. It
+// returns the number of clients disconnected.
+func (r *Room) Drain(notice string) int {
+	req := drainRequest{notice: notice, done: make(chan int, 1)}
+	select {
+	case r.drain <- req:
+	case <-r.ctx.Done():
+		return 0
+	}
+	select {
+	case n := <-req.done:
+		return n
+	case <-r.ctx.Done():
+		return 0
+	}
+}
+
+// drainClients empties the room for Drain. It runs on the room goroutine.
+func (r *Room) drainClients(notice string) int {
+	r.mu.Lock()
+	var clients []*Client
+	now := r.Clock.Now()
+	for key, client := range r.clients {
+		// A nil entry is a login in progress; dropping its reservation would
+		// let a second login take the same nickname and overwrite it on Join
+		if client == nil {
+			continue
+		}
+		clients = append(clients, client)
+		r.recordSeenLocked(client.Nickname, now)
+		delete(r.clients, key)
+	}
+	r.sessions = make(map[string]*Client)
+	r.mu.Unlock()
+
+	msg := Message{
+		From:      "System",
+		Content:   notice,
+		Timestamp: time.Now(),
+		Kind:      KindSystem,
+	}
+	for _, client := range clients {
+		client.setQuitReason("room closed")
+		go func() {
+			client.Send(msg)
+			client.flush()
+			client.close()
+		}()
+		r.runHook(r.OnLeaveCmd, "leave", client.Nickname, client.remoteAddr)
+	}
+	return len(clients)
+}
+
+// closeRoom handles "/closeroom <name>" for an admin. The server has a single
+// room, so closing it drains everyone, the caller included, and leaves the
+// room open for new connections.
+func (c *Client) closeRoom(name string) (string, error) {
+	if !c.isAdmin() {
+		return "Permission denied.", ErrPermissionDenied
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "Usage: /closeroom <name>", errors.New("invalid /closeroom command usage")
+	}
+	if !strings.EqualFold(name, c.room.Name) {
+		return fmt.Sprintf("No room named %s.", name), fmt.Errorf("unknown room %s", name)
+	}
+
+	log.Printf("%s closed room %s", c.Nickname, c.room.Name)
+	n := c.room.Drain(closeRoomNotice)
+	c.room.recordAudit(AuditEvent{Actor: c.Nickname, Action: AuditCloseRoom, Target: c.room.Name})
+	return fmt.Sprintf("Closed %s, disconnecting %d %s.", c.room.Name, n, pluralUsers(n)), nil
+}
@@ -0,0 +1,112 @@
+package chat
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// closableConn is a lockedBufferConn that records being closed
+type closableConn struct {
+	lockedBufferConn
+	closed atomic.Bool
+}
+
+func (c *closableConn) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+func TestCloseRoomDrainsOccupants(t *testing.T) {
+	room := NewRoom("Lobby", 5, true, 10, true)
+	room.AdminToken = "secret"
+	defer room.Stop()
+
+	clients := make(map[string]*Client)
+	conns := make(map[string]*closableConn)
+	for _, nickname := range []string{"alice", "bob", "admin"} {
+		conn := &closableConn{}
+		client := &Client{Nickname: nickname, conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+		room.Join(client)
+		clients[nickname] = client
+		conns[nickname] = conn
+	}
+	if _, err := clients["alice"].closeRoom("Lobby"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected non-admin /closeroom to be denied, got %v", err)
+	}
+
+	admin := clients["admin"]
+	admin.elevate("secret")
+	if _, err := admin.closeRoom(""); err == nil {
+		t.Error("Expected missing room name to be rejected")
+	}
+	if _, err := admin.closeRoom("Attic"); err == nil {
+		t.Error("Expected unknown room name to be rejected")
+	}
+
+	reply, err := admin.closeRoom("lobby")
+	if err != nil {
+		t.Fatalf("closeRoom() error = %v", err)
+	}
+	if want := "Closed Lobby, disconnecting 3 users."; reply != want {
+		t.Errorf("closeRoom() = %q, want %q", reply, want)
+	}
+
+	assertRoomEmpty(t, room)
+
+	for nickname, conn := range conns {
+		waitFor(t, nickname+" to be disconnected", conn.closed.Load)
+		out := conn.String()
+		if !strings.Contains(out, closeRoomNotice) {
+			t.Errorf("%s did not get the close notice: %q", nickname, out)
+		}
+		if strings.Contains(out, "has left the room") {
+			t.Errorf("%s saw leave notices for the drained room: %q", nickname, out)
+		}
+		if reason := clients[nickname].getQuitReason(); reason != "room closed" {
+			t.Errorf("%s quit reason = %q, want %q", nickname, reason, "room closed")
+		}
+	}
+
+	// The room stays open for new arrivals
+	conn := &closableConn{}
+	late := &Client{Nickname: "carol", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+	room.Join(late)
+	if users := room.GetUserList(); len(users) != 1 || users[0] != "carol" {
+		t.Errorf("Expected carol to join after the drain, got %v", users)
+	}
+}
+
+func TestDrainKeepsPendingReservations(t *testing.T) {
+	room := NewRoom("Lobby", 5, false, 0, true)
+	defer room.Stop()
+
+	conn := &closableConn{}
+	alice := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+	room.Join(alice)
+
+	// bob is still logging in: his nickname is reserved but he hasn't joined
+	if !room.ReserveNickname("bob") {
+		t.Fatal("Expected to reserve a nickname")
+	}
+
+	if n := room.Drain(closeRoomNotice); n != 1 {
+		t.Errorf("Drain() = %d, want 1", n)
+	}
+	if room.ReserveNickname("bob") {
+		t.Fatal("Expected the drain to leave bob's reservation in place")
+	}
+	if !room.ReserveNickname("alice") {
+		t.Error("Expected the drained user's nickname to be free")
+	}
+	room.ReleaseNickname("alice")
+
+	bobConn := &closableConn{}
+	bob := &Client{Nickname: "bob", conn: bobConn, writer: bufio.NewWriter(bobConn), room: room, plainText: true}
+	room.Join(bob)
+	if users := room.GetUserList(); len(users) != 1 || users[0] != "bob" {
+		t.Errorf("Expected bob to finish joining after the drain, got %v", users)
+	}
+}
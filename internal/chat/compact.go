@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bscott/ts-chat/internal/ui"
+)
+
+// useCompact reports whether this client wants dense output for a small screen
+func (c *Client) useCompact() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.compact
+}
+
+// toggleCompact applies a "/compact on|off" argument and returns the reply to show the user
+func (c *Client) toggleCompact(arg string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(arg)) {
+	case "on":
+		c.mu.Lock()
+		c.compact = true
+		c.mu.Unlock()
+		return "Compact mode enabled.", nil
+	case "off":
+		c.mu.Lock()
+		c.compact = false
+		c.mu.Unlock()
+		return "Compact mode disabled.", nil
+	case "":
+		if c.useCompact() {
+			return "Compact mode is on. Usage: /compact on|off", nil
+		}
+		return "Compact mode is off. Usage: /compact on|off", nil
+	default:
+		return "Usage: /compact on|off", fmt.Errorf("invalid /compact argument: %s", arg)
+	}
+}
+
+// formatFor renders msg for this client, honoring its plain-text and compact settings
+func (c *Client) formatFor(msg Message) string {
+	if msg.Kind == KindAnnouncement && c.useCompact() {
+		return ui.FormatAnnouncementCompact(msg.Content)
+	}
+	return formatMessageFor(msg, c.renderOptions(), c.Nickname)
+}
+
+// sendCompactWelcome is sendWelcomeMessage for compact mode: no banner, and
+// the welcome and message of the day on as few lines as possible
+func (c *Client) sendCompactWelcome() error {
+	if err := c.writeLine(ui.FormatWelcomeMessageCompact(c.room.Name, c.Nickname)); err != nil {
+		return fmt.Errorf("failed to write welcome message: %w", err)
+	}
+
+	if c.room.MOTD != "" {
+		if err := c.writeLine(c.formatMOTD()); err != nil {
+			return fmt.Errorf("failed to write message of the day: %w", err)
+		}
+	}
+
+	if topic := c.room.Topic(); topic.Text != "" {
+		c.sendSystemMessage(topic.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/ui"
+)
+
+func TestToggleCompact(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+	client := NewTUIClient(nil, room)
+
+	if reply, _ := client.toggleCompact(""); reply != "Compact mode is off. Usage: /compact on|off" {
+		t.Errorf("toggleCompact(\"\") = %q", reply)
+	}
+	if _, err := client.toggleCompact("on"); err != nil || !client.useCompact() {
+		t.Errorf("toggleCompact(on) error = %v, compact = %v", err, client.useCompact())
+	}
+	if _, err := client.toggleCompact("off"); err != nil || client.useCompact() {
+		t.Errorf("toggleCompact(off) error = %v, compact = %v", err, client.useCompact())
+	}
+	if _, err := client.toggleCompact("maybe"); err == nil {
+		t.Error("Expected an invalid argument to be rejected")
+	}
+
+	room.Compact = true
+	if !NewTUIClient(nil, room).useCompact() {
+		t.Error("Expected clients to start compact when the room is")
+	}
+}
+
+func TestCompactWelcomeSkipsBanner(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	room.MOTD = "Be nice"
+	room.Compact = true
+	defer room.Stop()
+
+	conn := &lockedBufferConn{}
+	client := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, compact: true}
+	if err := client.sendWelcomeMessage(); err != nil {
+		t.Fatalf("sendWelcomeMessage() error = %v", err)
+	}
+
+	want := "Welcome to Test Room, alice! /help for commands.\r\nMOTD: Be nice\r\n"
+	if got := conn.String(); got != want {
+		t.Errorf("compact welcome = %q, want %q", got, want)
+	}
+}
+
+func TestCompactAnnouncementIsOneLine(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+
+	client := &Client{Nickname: "alice", room: room, compact: true}
+	msg := Message{From: "admin", Content: "Restart at noon", Timestamp: time.Now(), Kind: KindAnnouncement}
+	if got := client.formatFor(msg); got != ui.FormatAnnouncementCompact("Restart at noon") {
+		t.Errorf("formatFor() = %q", got)
+	}
+	if strings.Contains(client.userList(1), "\n") {
+		t.Errorf("Expected a one-line /who, got %q", client.userList(1))
+	}
+}
@@ -0,0 +1,95 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConnectionInfo describes one open connection to the server
+type ConnectionInfo struct {
+	RemoteAddr  string
+	Nickname    string // empty while the user is still choosing one
+	ConnectedAt time.Time
+}
+
+// ConnectionManager is implemented by the server so admin commands can list and
+// drop connections without the chat package depending on it
+type ConnectionManager interface {
+	Connections() []ConnectionInfo
+	Disconnect(remoteAddr string) bool
+}
+
+// NicknameForAddr returns the nickname of the joined client connected from
+// remoteAddr, or "" if none
+func (r *Room) NicknameForAddr(remoteAddr string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, client := range r.clients {
+		if client == nil {
+			continue
+		}
+		client.mu.Lock()
+		conn := client.conn
+		client.mu.Unlock()
+		if conn != nil && conn.RemoteAddr() != nil && conn.RemoteAddr().String() == remoteAddr {
+			return client.Nickname
+		}
+	}
+	return ""
+}
+
+// formatConnections renders the /connections listing, oldest connection first
+func formatConnections(conns []ConnectionInfo, now time.Time) string {
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].ConnectedAt.Before(conns[j].ConnectedAt)
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Connections (%d):", len(conns))
+	for _, conn := range conns {
+		nickname := conn.Nickname
+		if nickname == "" {
+			nickname = "(no nickname)"
+		}
+		fmt.Fprintf(&b, "\n  %s  %s  since %s (%s)",
+			conn.RemoteAddr, nickname, conn.ConnectedAt.Format("15:04:05"), now.Sub(conn.ConnectedAt).Round(time.Second))
+	}
+	return b.String()
+}
+
+// listConnections returns the /connections output for an admin
+func (c *Client) listConnections() (string, error) {
+	if !c.isAdmin() {
+		return "Permission denied.", ErrPermissionDenied
+	}
+	if c.room.Connections == nil {
+		return "Connection management is not available.", errors.New("no connection manager")
+	}
+	return formatConnections(c.room.Connections.Connections(), time.Now()), nil
+}
+
+// disconnectAddr handles "/disconnect <addr>" for an admin
+func (c *Client) disconnectAddr(addr string) (string, error) {
+	if !c.isAdmin() {
+		return "Permission denied.", ErrPermissionDenied
+	}
+	if c.room.Connections == nil {
+		return "Connection management is not available.", errors.New("no connection manager")
+	}
+
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return "Usage: /disconnect <addr>", errors.New("invalid /disconnect command usage")
+	}
+	// Look the nickname up first; it's gone once the connection is
+	target := auditTarget(c.room.NicknameForAddr(addr), addr)
+	if !c.room.Connections.Disconnect(addr) {
+		return fmt.Sprintf("No connection from %s.", addr), fmt.Errorf("unknown connection %s", addr)
+	}
+	c.room.recordAudit(AuditEvent{Actor: c.Nickname, Action: AuditDisconnect, Target: target})
+	return fmt.Sprintf("Disconnected %s.", addr), nil
+}
@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeConnections records disconnect requests
+type fakeConnections struct {
+	conns        []ConnectionInfo
+	disconnected []string
+}
+
+func (f *fakeConnections) Connections() []ConnectionInfo {
+	return f.conns
+}
+
+func (f *fakeConnections) Disconnect(addr string) bool {
+	for _, conn := range f.conns {
+		if conn.RemoteAddr == addr {
+			f.disconnected = append(f.disconnected, addr)
+			return true
+		}
+	}
+	return false
+}
+
+func TestFormatConnections(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	conns := []ConnectionInfo{
+		{RemoteAddr: "100.64.0.2:5000", ConnectedAt: now.Add(-30 * time.Second)},
+		{RemoteAddr: "100.64.0.1:4000", Nickname: "alice", ConnectedAt: now.Add(-5 * time.Minute)},
+	}
+
+	want := "Connections (2):\n" +
+		"  100.64.0.1:4000  alice  since 11:55:00 (5m0s)\n" +
+		"  100.64.0.2:5000  (no nickname)  since 11:59:30 (30s)"
+	if got := formatConnections(conns, now); got != want {
+		t.Errorf("formatConnections() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestConnectionCommands(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	room.AdminToken = "secret"
+	manager := &fakeConnections{conns: []ConnectionInfo{{RemoteAddr: "100.64.0.1:4000", ConnectedAt: time.Now()}}}
+	room.Connections = manager
+	defer room.Stop()
+
+	client := NewTUIClient(nil, room)
+
+	if _, err := client.listConnections(); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected non-admin /connections to be denied, got %v", err)
+	}
+	if _, err := client.disconnectAddr("100.64.0.1:4000"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected non-admin /disconnect to be denied, got %v", err)
+	}
+
+	client.elevate("secret")
+
+	if _, err := client.listConnections(); err != nil {
+		t.Errorf("listConnections() error = %v", err)
+	}
+	if _, err := client.disconnectAddr("100.64.0.9:1"); err == nil {
+		t.Error("Expected unknown address to be reported")
+	}
+	if _, err := client.disconnectAddr("100.64.0.1:4000"); err != nil {
+		t.Errorf("disconnectAddr() error = %v", err)
+	}
+	if len(manager.disconnected) != 1 {
+		t.Errorf("Expected one disconnect, got %v", manager.disconnected)
+	}
+}
@@ -0,0 +1,25 @@
+package chat
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// checkJoinCooldown rejects chat sent within the room's JoinCooldown of the
+// client joining, which slows down bots that connect and post immediately.
+// Commands are not checked.
+func (c *Client) checkJoinCooldown(now time.Time) error {
+	if c.room.JoinCooldown <= 0 {
+		return nil
+	}
+
+	c.room.mu.RLock()
+	joinedAt := c.joinedAt
+	c.room.mu.RUnlock()
+
+	if wait := joinedAt.Add(c.room.JoinCooldown).Sub(now); wait > 0 {
+		return fmt.Errorf("please wait a moment before sending your first message (%ds)", int(math.Ceil(wait.Seconds())))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestJoinCooldown(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+	clock := newFakeClock()
+	room.Clock = clock
+	room.JoinCooldown = 2 * time.Second
+
+	client := NewTUIClient(nil, room)
+	client.Nickname = "bot"
+	room.Join(client)
+
+	err := client.checkJoinCooldown(clock.Now())
+	if err == nil || !strings.Contains(err.Error(), "please wait a moment") {
+		t.Fatalf("Expected the cooldown notice right after join, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "(2s)") {
+		t.Errorf("Expected the remaining wait in the notice, got %q", err)
+	}
+
+	clock.Advance(1500 * time.Millisecond)
+	if err := client.checkJoinCooldown(clock.Now()); err == nil {
+		t.Error("Expected the cooldown to still apply after 1.5s")
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if err := client.checkJoinCooldown(clock.Now()); err != nil {
+		t.Errorf("Expected chat to be accepted once the cooldown passed, got %v", err)
+	}
+}
+
+func TestJoinCooldownTUIExemptsCommands(t *testing.T) {
+	room := NewRoom("Test Room", 5, true, 10, false)
+	defer room.Stop()
+	room.JoinCooldown = time.Hour
+
+	client := NewTUIClient(nil, room)
+	client.Nickname = "bot"
+	room.Join(client)
+	model := NewChatModel(client)
+	model.initViewport()
+	model.state = stateChat
+
+	model.textInput.SetValue("buy cheap stuff")
+	updated, _ := model.updateChat(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updated.(ChatModel)
+	last := model.messages[len(model.messages)-1]
+	if !strings.Contains(last.Content, "please wait a moment") {
+		t.Errorf("Expected a cooldown notice, got %q", last.Content)
+	}
+
+	// Commands go through handleCommand, which returns a *ChatModel
+	model.textInput.SetValue("/help")
+	updated, _ = model.updateChat(tea.KeyMsg{Type: tea.KeyEnter})
+	help := updated.(*ChatModel)
+	if last := help.messages[len(help.messages)-1]; strings.Contains(last.Content, "please wait") {
+		t.Errorf("Expected /help to be exempt, got %q", last.Content)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	for _, msg := range room.GetHistory() {
+		if msg.Content == "buy cheap stuff" {
+			t.Error("Message sent during the cooldown was broadcast")
+		}
+	}
+}
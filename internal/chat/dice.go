@@ -0,0 +1,80 @@
+package chat
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// Bounds for /roll to keep results readable and cheap to compute
+const (
+	MaxDice     = 100  // Maximum number of dice per roll
+	MaxDieSides = 1000 // Maximum sides per die
+)
+
+// parseDice parses NdM notation; an empty spec means 1d6 and a missing N means 1
+func parseDice(spec string) (int, int, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if spec == "" {
+		return 1, 6, nil
+	}
+
+	countStr, sidesStr, ok := strings.Cut(spec, "d")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid dice %q, use NdM like 2d6", spec)
+	}
+
+	count := 1
+	if countStr != "" {
+		n, err := strconv.Atoi(countStr)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid dice count %q", countStr)
+		}
+		count = n
+	}
+
+	sides, err := strconv.Atoi(sidesStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid die sides %q", sidesStr)
+	}
+
+	if count < 1 || count > MaxDice {
+		return 0, 0, fmt.Errorf("dice count must be between 1 and %d", MaxDice)
+	}
+
+	if sides < 2 || sides > MaxDieSides {
+		return 0, 0, fmt.Errorf("die sides must be between 2 and %d", MaxDieSides)
+	}
+
+	return count, sides, nil
+}
+
+// rollAction rolls the dice described by spec and returns the action text to broadcast
+func rollAction(spec string) (string, error) {
+	count, sides, err := parseDice(spec)
+	if err != nil {
+		return "", err
+	}
+
+	total := 0
+	rolls := make([]string, count)
+	for i := range rolls {
+		roll := rand.IntN(sides) + 1
+		total += roll
+		rolls[i] = strconv.Itoa(roll)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("rolls 1d%d = %d", sides, total), nil
+	}
+	return fmt.Sprintf("rolls %dd%d: %s = %d", count, sides, strings.Join(rolls, " + "), total), nil
+}
+
+// flipAction flips a coin and returns the action text to broadcast
+func flipAction() string {
+	if rand.IntN(2) == 0 {
+		return "flips a coin: heads"
+	}
+	return "flips a coin: tails"
+}
@@ -0,0 +1,64 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDice(t *testing.T) {
+	tests := []struct {
+		spec      string
+		wantCount int
+		wantSides int
+		wantErr   bool
+	}{
+		{"", 1, 6, false},
+		{"2d6", 2, 6, false},
+		{"d20", 1, 20, false},
+		{"3D8", 3, 8, false},
+		{"100d1000", 100, 1000, false},
+		{"0d6", 0, 0, true},
+		{"1000d1000", 0, 0, true},
+		{"1d1001", 0, 0, true},
+		{"1d1", 0, 0, true},
+		{"2d", 0, 0, true},
+		{"xd6", 0, 0, true},
+		{"-1d6", 0, 0, true},
+		{"6", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			count, sides, err := parseDice(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDice(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if count != tt.wantCount || sides != tt.wantSides {
+				t.Errorf("parseDice(%q) = %dd%d, want %dd%d", tt.spec, count, sides, tt.wantCount, tt.wantSides)
+			}
+		})
+	}
+}
+
+func TestRollAction(t *testing.T) {
+	action, err := rollAction("3d1000")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(action, "rolls 3d1000: ") || strings.Count(action, " + ") != 2 {
+		t.Errorf("Unexpected roll action: %q", action)
+	}
+
+	if _, err := rollAction("0d6"); err == nil {
+		t.Error("Expected error for 0d6")
+	}
+}
+
+func TestFlipAction(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		action := flipAction()
+		if action != "flips a coin: heads" && action != "flips a coin: tails" {
+			t.Fatalf("Unexpected flip action: %q", action)
+		}
+	}
+}
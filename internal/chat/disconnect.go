@@ -0,0 +1,38 @@
+package chat
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"syscall"
+)
+
+// disconnectReason turns the error that ended a connection into a short reason
+// for the leave notice. Clean disconnects get no reason; details go to the log.
+func disconnectReason(err error) string {
+	switch {
+	case err == nil, errors.Is(err, io.EOF), errors.Is(err, net.ErrClosed), errors.Is(err, ErrFloodKick):
+		return ""
+	case isTimeout(err):
+		return "timed out"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return "connection reset"
+	default:
+		return "connection lost"
+	}
+}
+
+// LeaveWithReason removes the client like Leave, logging what ended the connection
+// and mentioning it in the leave notice unless the user gave a /quit reason
+func (r *Room) LeaveWithReason(client *Client, err error) {
+	if err != nil {
+		log.Printf("Client %s disconnected: %v", client.Nickname, err)
+	}
+
+	if reason := disconnectReason(err); reason != "" && client.getQuitReason() == "" {
+		client.setQuitReason(reason)
+	}
+
+	r.Leave(client)
+}
@@ -0,0 +1,93 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// errReadConn fails every read with err
+type errReadConn struct {
+	net.Conn
+	err error
+}
+
+func (c *errReadConn) Read([]byte) (int, error) {
+	return 0, c.err
+}
+
+func TestDisconnectReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"none", nil, ""},
+		{"eof", io.EOF, ""},
+		{"closed", net.ErrClosed, ""},
+		{"reset", fmt.Errorf("read: %w", syscall.ECONNRESET), "connection reset"},
+		{"other", errors.New("boom"), "connection lost"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := disconnectReason(tt.err); got != tt.want {
+				t.Errorf("disconnectReason(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleDisconnectNotice(t *testing.T) {
+	tests := []struct {
+		name    string
+		readErr error
+		want    string
+	}{
+		{"eof", io.EOF, "alice has left the room"},
+		{"reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, "alice has left the room (connection reset)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := NewRoom("Test Room", 5, false, 0, true)
+			defer room.Stop()
+
+			server, remote := net.Pipe()
+			defer remote.Close()
+			go drain(remote)
+
+			conn := &errReadConn{Conn: server, err: tt.readErr}
+			client := &Client{
+				Nickname:  "alice",
+				conn:      conn,
+				reader:    bufio.NewReader(conn),
+				writer:    bufio.NewWriter(conn),
+				room:      room,
+				plainText: true,
+			}
+			room.ReserveNickname("alice")
+			room.Join(client)
+
+			events, unsubscribe := room.Subscribe()
+			defer unsubscribe()
+
+			client.Handle(context.Background())
+
+			select {
+			case msg := <-events:
+				if msg.Kind != KindLeave || msg.Content != tt.want {
+					t.Errorf("Got %q (%s), want leave notice %q", msg.Content, msg.Kind, tt.want)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("No leave notice was broadcast")
+			}
+		})
+	}
+}
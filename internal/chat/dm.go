@@ -0,0 +1,73 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxPrivateRecipients caps how many users a single /msg can reach
+const MaxPrivateRecipients = 5
+
+// sendPrivate parses "/msg <nick>[,<nick>...] <text>" arguments and delivers the
+// message to each online recipient. It returns the message to echo back to the
+// sender and a note for every recipient that was skipped. Private messages never
+// go through the room's broadcast, so they stay out of history.
+func sendPrivate(room *Room, from, args string) (Message, []string, error) {
+	targets, text, _ := strings.Cut(strings.TrimSpace(args), " ")
+	text = strings.TrimSpace(text)
+	if targets == "" || text == "" {
+		return Message{}, nil, fmt.Errorf("usage: /msg <nick>[,<nick>...] <message>")
+	}
+	if err := room.validateMessageLength(text); err != nil {
+		return Message{}, nil, err
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(targets, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[room.nickKey(name)] {
+			continue
+		}
+		seen[room.nickKey(name)] = true
+		names = append(names, name)
+	}
+	if len(names) > MaxPrivateRecipients {
+		return Message{}, nil, fmt.Errorf("too many recipients (max %d)", MaxPrivateRecipients)
+	}
+
+	var notes []string
+	var recipients []*Client
+	var delivered []string
+	for _, name := range names {
+		if room.sameNick(name, from) {
+			notes = append(notes, "You can't send a private message to yourself.")
+			continue
+		}
+		client := room.GetClient(name)
+		if client == nil {
+			notes = append(notes, fmt.Sprintf("'%s' is not online.", name))
+			continue
+		}
+		recipients = append(recipients, client)
+		delivered = append(delivered, client.Nickname)
+	}
+
+	if len(recipients) == 0 {
+		return Message{}, notes, fmt.Errorf("no recipients online")
+	}
+
+	msg := Message{
+		Kind:      KindPrivate,
+		From:      from,
+		To:        delivered,
+		Content:   text,
+		Timestamp: time.Now(),
+	}
+	for _, client := range recipients {
+		go client.Send(msg)
+	}
+
+	return msg, notes, nil
+}
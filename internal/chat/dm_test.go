@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendPrivate(t *testing.T) {
+	room := NewRoom("Test Room", 10, true, 10, false)
+	defer room.Stop()
+
+	for _, nickname := range []string{"alice", "bob", "carol"} {
+		client := NewTUIClient(nil, room)
+		client.Nickname = nickname
+		room.ReserveNickname(nickname)
+		room.Join(client)
+	}
+	room.ReserveNickname("dave") // still choosing a nickname, not online
+
+	msg, notes, err := sendPrivate(room, "alice", "bob,carol,dave,zed,alice,bob hi there")
+	if err != nil {
+		t.Fatalf("sendPrivate() error = %v", err)
+	}
+
+	if msg.Kind != KindPrivate || msg.Content != "hi there" || !reflect.DeepEqual(msg.To, []string{"bob", "carol"}) {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+
+	wantNotes := []string{
+		"'dave' is not online.",
+		"'zed' is not online.",
+		"You can't send a private message to yourself.",
+	}
+	if !reflect.DeepEqual(notes, wantNotes) {
+		t.Errorf("notes = %q, want %q", notes, wantNotes)
+	}
+
+	// Give the room a moment in case anything was (wrongly) broadcast
+	time.Sleep(20 * time.Millisecond)
+	for _, m := range room.GetHistory() {
+		if m.Kind == KindPrivate {
+			t.Fatalf("Private message leaked into history: %+v", m)
+		}
+	}
+}
+
+func TestSendPrivateErrors(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, false)
+	defer room.Stop()
+
+	tests := []struct {
+		name    string
+		args    string
+		wantErr string
+	}{
+		{"no text", "bob", "usage"},
+		{"nobody online", "bob,carol hello", "no recipients online"},
+		{"too many recipients", "a,b,c,d,e,f hello", "too many recipients"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := sendPrivate(room, "alice", tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("sendPrivate(%q) error = %v, want %q", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatPrivateMessagePlain(t *testing.T) {
+	msg := Message{Kind: KindPrivate, From: "alice", To: []string{"bob", "carol"}, Content: "hi", Timestamp: time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)}
+	want := "[09:30:00] alice (private to bob, carol): hi"
+	if got := formatMessage(msg, renderOptions{plain: true}); got != want {
+		t.Errorf("formatMessage() = %q, want %q", got, want)
+	}
+}
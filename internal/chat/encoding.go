@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+// encodings maps --encoding names to codecs; UTF-8 maps to nil, meaning passthrough
+var encodings = map[string]encoding.Encoding{
+	"utf-8":        nil,
+	"utf8":         nil,
+	"cp437":        charmap.CodePage437,
+	"ibm437":       charmap.CodePage437,
+	"latin1":       charmap.ISO8859_1,
+	"iso-8859-1":   charmap.ISO8859_1,
+	"cp1252":       charmap.Windows1252,
+	"windows-1252": charmap.Windows1252,
+}
+
+// LookupEncoding returns the codec for a terminal encoding name such as
+// "cp437" or "latin1". UTF-8 and the empty name return nil, which leaves
+// traffic untouched.
+func LookupEncoding(name string) (encoding.Encoding, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil, nil
+	}
+	enc, ok := encodings[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %q (use utf-8, cp437, latin1 or cp1252)", name)
+	}
+	return enc, nil
+}
+
+// encodeWriter transcodes UTF-8 written to it into enc before passing it to w.
+// Characters enc can't represent are replaced rather than failing the write.
+func encodeWriter(w io.Writer, enc encoding.Encoding) io.Writer {
+	if enc == nil {
+		return w
+	}
+	return transform.NewWriter(w, encoding.ReplaceUnsupported(enc.NewEncoder()))
+}
+
+// decodeReader transcodes input from enc to UTF-8
+func decodeReader(r io.Reader, enc encoding.Encoding) io.Reader {
+	if enc == nil {
+		return r
+	}
+	return transform.NewReader(r, enc.NewDecoder())
+}
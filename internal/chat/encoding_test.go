@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestEncodingRoundTrip(t *testing.T) {
+	enc, err := LookupEncoding("CP437")
+	if err != nil {
+		t.Fatalf("LookupEncoding() error = %v", err)
+	}
+
+	// The banner's double-line box drawing and an accented letter are all single bytes in CP437
+	const text = "╔═ café ═╗"
+	want := []byte{0xC9, 0xCD, ' ', 'c', 'a', 'f', 0x82, ' ', 0xCD, 0xBB}
+
+	var buf bytes.Buffer
+	if _, err := io.WriteString(encodeWriter(&buf, enc), text); err != nil {
+		t.Fatalf("encode error = %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("Encoded % x, want % x", buf.Bytes(), want)
+	}
+
+	decoded, err := io.ReadAll(decodeReader(bytes.NewReader(buf.Bytes()), enc))
+	if err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	if string(decoded) != text {
+		t.Errorf("Round trip = %q, want %q", decoded, text)
+	}
+}
+
+func TestEncodingUnsupportedRunesReplaced(t *testing.T) {
+	enc, _ := LookupEncoding("latin1")
+
+	var buf bytes.Buffer
+	if _, err := io.WriteString(encodeWriter(&buf, enc), "ok ☃"); err != nil {
+		t.Fatalf("Expected unsupported runes to be replaced, got %v", err)
+	}
+	if got := buf.String(); len(got) != 4 || got[:3] != "ok " {
+		t.Errorf("Encoded %q, want \"ok \" plus one replacement byte", got)
+	}
+}
+
+func TestLookupEncoding(t *testing.T) {
+	for _, name := range []string{"", "utf-8", "UTF8"} {
+		if enc, err := LookupEncoding(name); err != nil || enc != nil {
+			t.Errorf("LookupEncoding(%q) = %v, %v; want passthrough", name, enc, err)
+		}
+	}
+	if _, err := LookupEncoding("ebcdic"); err == nil {
+		t.Error("Expected an error for an unsupported encoding")
+	}
+}
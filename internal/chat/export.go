@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// MaxExportBytes caps the size of an /export payload; older messages are
+// dropped to fit
+const MaxExportBytes = 64 << 10
+
+// exportRecord is the JSON form of a message in /export json
+type exportRecord struct {
+	ID        uint64    `json:"id,omitempty"`
+	Kind      string    `json:"kind"`
+	From      string    `json:"from"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+	ReplyTo   uint64    `json:"reply_to,omitempty"`
+}
+
+// exportLine renders one message for an export, with any ANSI codes removed
+func exportLine(msg Message, format string) (string, error) {
+	if format == "json" {
+		line, err := json.Marshal(exportRecord{
+			ID:        msg.ID,
+			Kind:      msg.Kind.String(),
+			From:      msg.From,
+			Content:   ansi.Strip(msg.Content),
+			Timestamp: msg.Timestamp,
+			ReplyTo:   msg.ReplyTo,
+		})
+		return string(line), err
+	}
+	return ansi.Strip(formatMessage(msg, renderOptions{plain: true})), nil
+}
+
+// exportHistory renders history as one copyable block in the given format
+// ("text" or "json"), keeping the newest messages that fit in MaxExportBytes
+func exportHistory(roomName string, history []Message, format string) (string, error) {
+	if format != "text" && format != "json" {
+		return "", fmt.Errorf("unknown export format %q (use text or json)", format)
+	}
+
+	sep := "\n"
+	if format == "json" {
+		sep = ",\n"
+	}
+
+	var lines []string
+	size := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		line, err := exportLine(history[i], format)
+		if err != nil {
+			return "", fmt.Errorf("failed to export message: %w", err)
+		}
+		if size+len(line)+len(sep) > MaxExportBytes {
+			break
+		}
+		size += len(line) + len(sep)
+		lines = append(lines, line)
+	}
+
+	// Collected newest first; flip to read in order
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+
+	body := strings.Join(lines, sep)
+	if format == "json" {
+		body = "[\n" + body + "\n]"
+		if len(lines) == 0 {
+			body = "[]"
+		}
+	}
+
+	var b strings.Builder
+	if dropped := len(history) - len(lines); dropped > 0 {
+		fmt.Fprintf(&b, "Warning: export truncated to the newest %d of %d messages.\n", len(lines), len(history))
+	}
+	fmt.Fprintf(&b, "--- Begin export of %s (%d messages, %s) ---\n", roomName, len(lines), format)
+	if body != "" {
+		b.WriteString(body + "\n")
+	}
+	b.WriteString("--- End export ---")
+	return b.String(), nil
+}
+
+// export handles "/export [text|json]", returning the payload for the requester alone
+func (c *Client) export(args string) (string, error) {
+	if !c.room.enableHistory {
+		return "Export is unavailable because history is disabled.", errors.New("history disabled")
+	}
+
+	format := strings.ToLower(strings.TrimSpace(args))
+	if format == "" {
+		format = "text"
+	}
+
+	payload, err := exportHistory(c.room.Name, c.room.GetHistory(), format)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), err
+	}
+	return payload, nil
+}
@@ -0,0 +1,82 @@
+package chat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportHistoryText(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	history := []Message{
+		{ID: 1, From: "alice", Content: "\x1b[31mred\x1b[0m alert", Timestamp: ts, Kind: KindUser},
+		{From: "System", Content: "bob has joined the room", Timestamp: ts, Kind: KindJoin},
+		{ID: 2, From: "bob", Content: "waves", Timestamp: ts, Kind: KindAction},
+	}
+
+	got, err := exportHistory("Test Room", history, "text")
+	if err != nil {
+		t.Fatalf("exportHistory() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"--- Begin export of Test Room (3 messages, text) ---",
+		"[09:30:00 #1] alice: red alert",
+		"-> bob has joined the room",
+		"* bob waves",
+		"--- End export ---",
+	}, "\n")
+	if got != want {
+		t.Errorf("exportHistory(text) =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportHistoryJSON(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	history := []Message{
+		{ID: 1, From: "alice", Content: "hi", Timestamp: ts, Kind: KindUser},
+		{ID: 2, From: "bob", Content: "hello", Timestamp: ts, Kind: KindUser, ReplyTo: 1},
+	}
+
+	got, err := exportHistory("Test Room", history, "json")
+	if err != nil {
+		t.Fatalf("exportHistory() error = %v", err)
+	}
+
+	lines := strings.Split(got, "\n")
+	body := strings.Join(lines[1:len(lines)-1], "\n")
+	var records []exportRecord
+	if err := json.Unmarshal([]byte(body), &records); err != nil {
+		t.Fatalf("export body is not a JSON array: %v\n%s", err, body)
+	}
+	if len(records) != 2 || records[1].ReplyTo != 1 || records[0].Kind != "user" || !records[0].Timestamp.Equal(ts) {
+		t.Errorf("Unexpected records: %+v", records)
+	}
+
+	if empty, _ := exportHistory("Test Room", nil, "json"); !strings.Contains(empty, "\n[]\n") {
+		t.Errorf("Expected an empty JSON array, got %q", empty)
+	}
+}
+
+func TestExportHistoryTruncates(t *testing.T) {
+	var history []Message
+	for i := 0; i < 200; i++ {
+		history = append(history, Message{From: "alice", Content: strings.Repeat("x", 1000), Timestamp: time.Now()})
+	}
+
+	got, err := exportHistory("Test Room", history, "text")
+	if err != nil {
+		t.Fatalf("exportHistory() error = %v", err)
+	}
+	if !strings.HasPrefix(got, "Warning: export truncated to the newest ") {
+		t.Errorf("Expected a truncation warning, got %q", got[:80])
+	}
+	if len(got) > MaxExportBytes+200 {
+		t.Errorf("Export is %d bytes, want at most about %d", len(got), MaxExportBytes)
+	}
+
+	if _, err := exportHistory("Test Room", history, "xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,71 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxFeedbackLength caps /feedback text in characters
+const MaxFeedbackLength = 500
+
+// FeedbackInterval is the minimum time between /feedback submissions from one client
+const FeedbackInterval = time.Minute
+
+// ErrFeedbackRateLimited is returned when a client sends feedback too often
+var ErrFeedbackRateLimited = errors.New("feedback rate limit exceeded")
+
+// Feedback is a note for the server operator sent with /feedback
+type Feedback struct {
+	From       string
+	RemoteAddr string
+	Content    string
+	Timestamp  time.Time
+}
+
+// FeedbackSink records /feedback submissions. It is implemented by the server
+// so feedback can go to a file or elsewhere without the room knowing.
+type FeedbackSink interface {
+	RecordFeedback(Feedback) error
+}
+
+// recordFeedback hands f to the room's sink, or the server log if none is set
+func (r *Room) recordFeedback(f Feedback) error {
+	if r.Feedback != nil {
+		return r.Feedback.RecordFeedback(f)
+	}
+	log.Printf("Feedback from %s (%s): %s", f.From, f.RemoteAddr, f.Content)
+	return nil
+}
+
+// feedback handles "/feedback <text>". The text goes to the operator only and
+// is never broadcast. Submissions are limited to one per FeedbackInterval.
+func (c *Client) feedback(args string, now time.Time) (string, error) {
+	text := strings.TrimSpace(args)
+	if text == "" {
+		return "Usage: /feedback <text>", errors.New("invalid /feedback usage")
+	}
+	if utf8.RuneCountInString(text) > MaxFeedbackLength {
+		return fmt.Sprintf("Feedback is too long (max %d characters).", MaxFeedbackLength), errors.New("feedback too long")
+	}
+	if !c.lastFeedback.IsZero() && now.Sub(c.lastFeedback) < FeedbackInterval {
+		return "Please wait a minute before sending more feedback.", ErrFeedbackRateLimited
+	}
+
+	err := c.room.recordFeedback(Feedback{
+		From:       c.Nickname,
+		RemoteAddr: c.remoteAddr,
+		Content:    text,
+		Timestamp:  now,
+	})
+	if err != nil {
+		log.Printf("Failed to record feedback from %s: %v", c.Nickname, err)
+		return "Sorry, your feedback could not be recorded.", err
+	}
+
+	c.lastFeedback = now
+	return "Thanks, your feedback was recorded.", nil
+}
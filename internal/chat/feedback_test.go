@@ -0,0 +1,125 @@
+package chat
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type captureFeedback struct {
+	got []Feedback
+}
+
+func (c *captureFeedback) RecordFeedback(f Feedback) error {
+	c.got = append(c.got, f)
+	return nil
+}
+
+func TestFeedbackNotBroadcast(t *testing.T) {
+	room := NewRoom("Test Room", 10, true, 10, false)
+	defer room.Stop()
+	sink := &captureFeedback{}
+	room.Feedback = sink
+
+	msgs, unsubscribe := room.Subscribe()
+	defer unsubscribe()
+
+	client := NewTUIClient(nil, room)
+	client.Nickname = "alice"
+	client.remoteAddr = "100.64.0.1:1234"
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	reply, err := client.feedback("  the TUI flickers  ", now)
+	if err != nil {
+		t.Fatalf("feedback() error = %v", err)
+	}
+	if reply != "Thanks, your feedback was recorded." {
+		t.Errorf("reply = %q", reply)
+	}
+
+	if len(sink.got) != 1 {
+		t.Fatalf("Sink got %d submissions, want 1", len(sink.got))
+	}
+	want := Feedback{From: "alice", RemoteAddr: "100.64.0.1:1234", Content: "the TUI flickers", Timestamp: now}
+	if sink.got[0] != want {
+		t.Errorf("Recorded %+v, want %+v", sink.got[0], want)
+	}
+
+	select {
+	case msg := <-msgs:
+		t.Errorf("Feedback was broadcast: %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if history := room.GetHistory(); len(history) != 0 {
+		t.Errorf("Feedback was stored in history: %v", history)
+	}
+}
+
+func TestFeedbackLimits(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, false)
+	defer room.Stop()
+	sink := &captureFeedback{}
+	room.Feedback = sink
+
+	client := NewTUIClient(nil, room)
+	client.Nickname = "alice"
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, err := client.feedback("", now); err == nil {
+		t.Error("Expected empty feedback to be rejected")
+	}
+	if _, err := client.feedback(strings.Repeat("x", MaxFeedbackLength+1), now); err == nil {
+		t.Error("Expected overlong feedback to be rejected")
+	}
+	if _, err := client.feedback("first", now); err != nil {
+		t.Fatalf("feedback() error = %v", err)
+	}
+	if _, err := client.feedback("second", now.Add(FeedbackInterval/2)); !errors.Is(err, ErrFeedbackRateLimited) {
+		t.Errorf("Expected ErrFeedbackRateLimited, got %v", err)
+	}
+	if _, err := client.feedback("third", now.Add(FeedbackInterval)); err != nil {
+		t.Errorf("Expected feedback after the interval to succeed, got %v", err)
+	}
+	if len(sink.got) != 2 {
+		t.Errorf("Sink got %d submissions, want 2", len(sink.got))
+	}
+}
+
+func TestFeedbackCommandUsesRoomClock(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, true)
+	defer room.Stop()
+	clock := newFakeClock()
+	room.Clock = clock
+	sink := &captureFeedback{}
+	room.Feedback = sink
+
+	start := clock.Now()
+
+	conn := &lockedBufferConn{}
+	alice := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+	if err := alice.handleCommand("/feedback first"); err != nil {
+		t.Fatalf("/feedback error = %v", err)
+	}
+	// The rate limit runs on the room clock too
+	clock.Advance(FeedbackInterval)
+	if err := alice.handleCommand("/feedback second"); err != nil {
+		t.Fatalf("/feedback after the interval error = %v", err)
+	}
+
+	bob := NewTUIClient(nil, room)
+	bob.Nickname = "bob"
+	model := NewChatModel(bob)
+	model.initViewport()
+	model.handleCommand("/feedback from the TUI")
+
+	if len(sink.got) != 3 {
+		t.Fatalf("Recorded %d feedback submissions, want 3", len(sink.got))
+	}
+	for i, want := range []time.Time{start, clock.Now(), clock.Now()} {
+		if got := sink.got[i].Timestamp; !got.Equal(want) {
+			t.Errorf("Feedback %q stamped %v, want the room clock's %v", sink.got[i].Content, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package chat
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// DefaultFloodKickWindow is how far back rate-limit violations count towards FloodKickAfter
+const DefaultFloodKickWindow = time.Minute
+
+// ErrFloodKick is returned by checkRateLimit once a client has hit the rate
+// limit FloodKickAfter times within FloodKickWindow
+var ErrFloodKick = errors.New("disconnected for flooding")
+
+// floodKickNotice is the last thing a client kicked for flooding sees
+const floodKickNotice = "You have been disconnected for flooding."
+
+// FloodBanner is implemented by the server to refuse new connections from the
+// address of a client kicked for flooding
+type FloodBanner interface {
+	BanFlooder(remoteAddr string)
+}
+
+// busyNotice is broadcast once each time the room-wide rate limit starts dropping messages
+const busyNotice = "The room is busy right now; some messages are being dropped."
+
+// floodGuard applies the room-wide rate limit. It is owned by the run loop.
+type floodGuard struct {
+	tokens float64
+	last   time.Time
+	busy   bool // a busy notice has gone out and nothing has been admitted since
+}
+
+// allow refills the token bucket at rate per second, holding at most one
+// second's worth (and at least one message), and takes a token if one is left
+func (g *floodGuard) allow(rate float64, now time.Time) bool {
+	burst := rate
+	if burst < 1 {
+		burst = 1
+	}
+
+	if g.last.IsZero() {
+		g.tokens = burst
+	} else {
+		g.tokens += now.Sub(g.last).Seconds() * rate
+		if g.tokens > burst {
+			g.tokens = burst
+		}
+	}
+	g.last = now
+
+	if g.tokens < 1 {
+		return false
+	}
+	g.tokens--
+	return true
+}
+
+// admitBroadcast applies RateLimit to msg. System messages always go out;
+// chat that exceeds the limit is dropped, its sender is told, and the room
+// gets a single busy notice until traffic is admitted again.
+func (r *Room) admitBroadcast(g *floodGuard, msg Message, now time.Time) bool {
+	if r.RateLimit <= 0 || msg.IsSystem() {
+		return true
+	}
+
+	if g.allow(r.RateLimit, now) {
+		g.busy = false
+		return true
+	}
+
+	if client := r.GetClient(msg.From); client != nil {
+		r.sendTo(client, Message{
+			From:      "System",
+			Content:   "The room is busy; your message was not delivered. Try again in a moment.",
+			Timestamp: now,
+			Kind:      KindSystem,
+		})
+	}
+	if !g.busy {
+		g.busy = true
+		r.broadcastMessage(Message{
+			From:      "System",
+			Content:   busyNotice,
+			Timestamp: now,
+			Kind:      KindSystem,
+		})
+	}
+	return false
+}
+
+// floodStrike records a rate-limit violation at now and reports whether the
+// client has now reached FloodKickAfter within FloodKickWindow. The caller
+// holds rateLimitMu.
+func (c *Client) floodStrike(now time.Time) bool {
+	if c.room.FloodKickAfter <= 0 {
+		return false
+	}
+
+	cutoff := now.Add(-c.room.FloodKickWindow)
+	kept := c.floodStrikes[:0]
+	for _, ts := range c.floodStrikes {
+		if ts.After(cutoff) {
+			kept = append(kept, ts)
+		}
+	}
+	c.floodStrikes = append(kept, now)
+
+	return len(c.floodStrikes) >= c.room.FloodKickAfter
+}
+
+// kickForFlooding tells the client why it is being dropped and has the server
+// ban its address, if bans are enabled. Nothing is announced to the room
+// beyond the usual leave notice.
+func (c *Client) kickForFlooding() {
+	log.Printf("Disconnecting %s (%s) for flooding", c.Nickname, c.remoteAddr)
+	c.room.recordAudit(AuditEvent{Actor: "server", Action: AuditFloodKick, Target: auditTarget(c.Nickname, c.remoteAddr), Reason: "flooding"})
+	if c.room.FloodBans != nil && c.remoteAddr != "" {
+		c.room.FloodBans.BanFlooder(c.remoteAddr)
+	}
+}
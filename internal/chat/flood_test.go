@@ -0,0 +1,192 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFloodGuardRefills(t *testing.T) {
+	var g floodGuard
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if !g.allow(2, now) {
+			t.Fatalf("Expected message %d of the burst to pass", i+1)
+		}
+	}
+	if g.allow(2, now) {
+		t.Fatal("Expected the bucket to be empty after the burst")
+	}
+	if !g.allow(2, now.Add(500*time.Millisecond)) {
+		t.Error("Expected a token after half a second at 2/s")
+	}
+	if g.allow(2, now.Add(500*time.Millisecond)) {
+		t.Error("Expected only one token to have refilled")
+	}
+}
+
+func TestRoomRateLimitBurst(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, false)
+	defer room.Stop()
+	room.RateLimit = 2
+
+	events, unsubscribe := room.Subscribe()
+	defer unsubscribe()
+
+	for i := 0; i < 10; i++ {
+		room.Broadcast(Message{From: fmt.Sprintf("user%d", i), Content: "spam", Timestamp: time.Now()})
+	}
+	room.Broadcast(Message{From: "System", Content: "still here", Timestamp: time.Now(), Kind: KindSystem})
+
+	var chat, notices int
+	for {
+		select {
+		case msg := <-events:
+			switch {
+			case msg.Content == "spam":
+				chat++
+			case msg.Content == busyNotice:
+				notices++
+			case msg.Content == "still here":
+				if chat != 2 || notices != 1 {
+					t.Errorf("Expected 2 chat messages and 1 busy notice, got %d and %d", chat, notices)
+				}
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("System message was not delivered (chat=%d notices=%d)", chat, notices)
+		}
+	}
+}
+
+// recordingBans records the addresses passed to BanFlooder
+type recordingBans struct {
+	mu    sync.Mutex
+	addrs []string
+}
+
+func (b *recordingBans) BanFlooder(remoteAddr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.addrs = append(b.addrs, remoteAddr)
+}
+
+func TestRateLimitNoticeDoesNotBlockRoom(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, false)
+	defer room.Stop()
+	room.RateLimit = 1
+
+	// A TUI client whose program isn't reading yet: Send blocks until ctx ends
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := NewTUIClient(nil, room)
+	client.Nickname = "alice"
+	client.program = tea.NewProgram(NewChatModel(client), tea.WithContext(ctx))
+	room.Join(client)
+
+	events, unsubscribe := room.Subscribe()
+	defer unsubscribe()
+
+	room.Broadcast(Message{From: "alice", Content: "one", Timestamp: time.Now()})
+	room.Broadcast(Message{From: "alice", Content: "two", Timestamp: time.Now()})
+	room.Broadcast(Message{From: "System", Content: "still here", Timestamp: time.Now(), Kind: KindSystem})
+
+	for {
+		select {
+		case msg := <-events:
+			if msg.Content == "still here" {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Room stopped delivering after telling a blocked client it was rate limited")
+		}
+	}
+}
+
+func TestFloodStrikesWindow(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+	room.FloodKickAfter = 3
+	room.FloodKickWindow = time.Minute
+	now := time.Now()
+
+	client := NewTUIClient(nil, room)
+	if client.floodStrike(now) || client.floodStrike(now.Add(time.Second)) {
+		t.Fatal("Expected no kick before the threshold")
+	}
+	// The first strike has aged out, so this is only the second in the window
+	if client.floodStrike(now.Add(time.Minute)) {
+		t.Error("Expected strikes outside the window not to count")
+	}
+	if !client.floodStrike(now.Add(time.Minute + 500*time.Millisecond)) {
+		t.Error("Expected a kick at the third strike within the window")
+	}
+}
+
+func TestFloodKickDisconnects(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	room.FloodKickAfter = 2
+	bans := &recordingBans{}
+	room.FloodBans = bans
+
+	server, remote := net.Pipe()
+	defer remote.Close()
+	client := &Client{
+		Nickname:   "flooder",
+		conn:       server,
+		remoteAddr: "100.64.0.9:40000",
+		reader:     bufio.NewReader(server),
+		writer:     bufio.NewWriter(server),
+		room:       room,
+		plainText:  true,
+	}
+	room.Join(client)
+
+	output := make(chan string, 1)
+	go func() {
+		out, _ := io.ReadAll(remote)
+		output <- string(out)
+	}()
+	go func() {
+		// MessageRateLimit messages pass; the next two are violations
+		for i := 0; i < MessageRateLimit+2; i++ {
+			if _, err := remote.Write([]byte("spam\n")); err != nil {
+				return
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		client.Handle(context.Background())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the flooder to be disconnected")
+	}
+
+	if out := <-output; !strings.Contains(out, floodKickNotice) {
+		t.Errorf("Expected the kick notice, got %q", out)
+	}
+	bans.mu.Lock()
+	if len(bans.addrs) != 1 || bans.addrs[0] != "100.64.0.9:40000" {
+		t.Errorf("Expected the flooder's address to be banned, got %v", bans.addrs)
+	}
+	bans.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+	assertRoomEmpty(t, room)
+}
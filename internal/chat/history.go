@@ -0,0 +1,91 @@
+package chat
+
+import "time"
+
+// historyBuffer is a fixed-size ring buffer of messages with O(1) append
+type historyBuffer struct {
+	items []Message
+	start int   // index of the oldest message
+	count int   // number of stored messages
+	bytes int64 // estimated memory held by stored messages, see messageSize
+}
+
+// newHistoryBuffer creates a ring buffer holding at most size messages
+func newHistoryBuffer(size int) *historyBuffer {
+	if size < 0 {
+		size = 0
+	}
+	return &historyBuffer{items: make([]Message, size)}
+}
+
+// add appends a message, overwriting the oldest one when the buffer is full
+func (h *historyBuffer) add(msg Message) {
+	if len(h.items) == 0 {
+		return
+	}
+
+	h.bytes += messageSize(msg)
+	if h.count < len(h.items) {
+		h.items[(h.start+h.count)%len(h.items)] = msg
+		h.count++
+		return
+	}
+
+	h.bytes -= messageSize(h.items[h.start])
+	h.items[h.start] = msg
+	h.start = (h.start + 1) % len(h.items)
+}
+
+// dropBefore evicts messages with a timestamp older than cutoff
+func (h *historyBuffer) dropBefore(cutoff time.Time) {
+	for h.count > 0 && h.items[h.start].Timestamp.Before(cutoff) {
+		h.dropOldest()
+	}
+}
+
+// dropOldest evicts the oldest message and returns its estimated size, or 0
+// if the buffer is empty
+func (h *historyBuffer) dropOldest() int64 {
+	if h.count == 0 {
+		return 0
+	}
+	size := messageSize(h.items[h.start])
+	h.items[h.start] = Message{}
+	h.start = (h.start + 1) % len(h.items)
+	h.count--
+	h.bytes -= size
+	return size
+}
+
+// last returns up to n of the newest messages, ordered oldest to newest
+func (h *historyBuffer) last(n int) []Message {
+	if n > h.count {
+		n = h.count
+	}
+	if n < 0 {
+		n = 0
+	}
+
+	out := make([]Message, n)
+	offset := h.count - n
+	for i := 0; i < n; i++ {
+		out[i] = h.items[(h.start+offset+i)%len(h.items)]
+	}
+	return out
+}
+
+// find returns the stored message with the given ID
+func (h *historyBuffer) find(id uint64) (Message, bool) {
+	for i := 0; i < h.count; i++ {
+		msg := h.items[(h.start+i)%len(h.items)]
+		if msg.ID == id {
+			return msg, true
+		}
+	}
+	return Message{}, false
+}
+
+// len returns the number of stored messages
+func (h *historyBuffer) len() int {
+	return h.count
+}
@@ -0,0 +1,326 @@
+package chat
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHistoryBufferWraparound(t *testing.T) {
+	h := newHistoryBuffer(3)
+
+	for i := 1; i <= 7; i++ {
+		h.add(Message{Content: fmt.Sprintf("msg%d", i)})
+	}
+
+	got := h.last(h.len())
+	want := []string{"msg5", "msg6", "msg7"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d messages, got %d", len(want), len(got))
+	}
+	for i, msg := range got {
+		if msg.Content != want[i] {
+			t.Errorf("Message %d: expected %s, got %s", i, want[i], msg.Content)
+		}
+	}
+}
+
+func TestHistoryBufferLast(t *testing.T) {
+	tests := []struct {
+		name  string
+		size  int
+		added int
+		n     int
+		want  []string
+	}{
+		{"empty", 5, 0, 3, []string{}},
+		{"partial fill", 5, 2, 5, []string{"msg1", "msg2"}},
+		{"fewer than stored", 5, 4, 2, []string{"msg3", "msg4"}},
+		{"after wrap", 3, 5, 2, []string{"msg4", "msg5"}},
+		{"negative n", 3, 2, -1, []string{}},
+		{"zero capacity", 0, 3, 3, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHistoryBuffer(tt.size)
+			for i := 1; i <= tt.added; i++ {
+				h.add(Message{Content: fmt.Sprintf("msg%d", i)})
+			}
+
+			got := h.last(tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d messages, got %d", len(tt.want), len(got))
+			}
+			for i, msg := range got {
+				if msg.Content != tt.want[i] {
+					t.Errorf("Message %d: expected %s, got %s", i, tt.want[i], msg.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestRoomGetHistoryN(t *testing.T) {
+	room := NewRoom("Test Room", 5, true, 4, false)
+	defer room.Stop()
+
+	for i := 1; i <= 6; i++ {
+		room.addToHistory(Message{Content: fmt.Sprintf("msg%d", i)})
+	}
+
+	if got := room.GetHistory(); len(got) != 4 || got[0].Content != "msg3" || got[3].Content != "msg6" {
+		t.Errorf("Unexpected history: %+v", got)
+	}
+
+	if got := room.GetHistoryN(2); len(got) != 2 || got[0].Content != "msg5" || got[1].Content != "msg6" {
+		t.Errorf("Unexpected GetHistoryN(2): %+v", got)
+	}
+}
+
+// BenchmarkHistoryRingBuffer measures appends to the ring buffer on a full history
+func BenchmarkHistoryRingBuffer(b *testing.B) {
+	h := newHistoryBuffer(50)
+	msg := Message{From: "Alice", Content: "Hello"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h.add(msg)
+	}
+}
+
+// BenchmarkHistorySliceReslice measures the previous append-and-reslice approach for comparison
+func BenchmarkHistorySliceReslice(b *testing.B) {
+	const size = 50
+	history := make([]Message, 0, size)
+	msg := Message{From: "Alice", Content: "Hello"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		history = append(history, msg)
+		if len(history) > size {
+			history = history[len(history)-size:]
+		}
+	}
+}
+
+func TestHistoryBufferDropBefore(t *testing.T) {
+	h := newHistoryBuffer(3)
+	now := time.Now()
+
+	// Wrap the buffer so eviction has to cross the end of the slice
+	for i := 4; i >= 0; i-- {
+		h.add(Message{Content: fmt.Sprintf("msg%d", i), Timestamp: now.Add(-time.Duration(i) * time.Minute)})
+	}
+
+	h.dropBefore(now.Add(-90 * time.Second))
+
+	got := h.last(h.len())
+	if len(got) != 2 || got[0].Content != "msg1" || got[1].Content != "msg0" {
+		t.Errorf("Unexpected history after eviction: %+v", got)
+	}
+}
+
+func TestRoomHistoryMaxAge(t *testing.T) {
+	room := NewRoom("Test Room", 5, true, 10, false)
+	defer room.Stop()
+
+	room.SetHistoryMaxAge(50 * time.Millisecond)
+	room.broadcastMessage(Message{From: "Alice", Content: "Hello", Timestamp: time.Now()})
+
+	if got := len(room.GetHistory()); got != 1 {
+		t.Fatalf("Expected 1 history entry, got %d", got)
+	}
+
+	// The background compaction should evict the message once it expires
+	time.Sleep(150 * time.Millisecond)
+
+	if got := len(room.GetHistory()); got != 0 {
+		t.Errorf("Expected expired history to be evicted, got %d entries", got)
+	}
+}
+
+func TestReplayHistoryCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored int
+		replay int
+		want   []string
+	}{
+		{"all by default", 3, ReplayAllHistory, []string{"msg1", "msg2", "msg3"}},
+		{"last n", 5, 2, []string{"msg4", "msg5"}},
+		{"more than stored", 2, 10, []string{"msg1", "msg2"}},
+		{"disabled", 3, 0, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := NewRoom("Test Room", 5, true, 50, false)
+			defer room.Stop()
+			room.HistoryReplayCount = tt.replay
+
+			for i := 1; i <= tt.stored; i++ {
+				room.addToHistory(Message{Content: fmt.Sprintf("msg%d", i)})
+			}
+
+			got := room.ReplayHistory()
+			if len(got) != len(tt.want) {
+				t.Fatalf("ReplayHistory() returned %d messages, want %d", len(got), len(tt.want))
+			}
+			for i, msg := range got {
+				if msg.Content != tt.want[i] {
+					t.Errorf("Message %d = %q, want %q", i, msg.Content, tt.want[i])
+				}
+			}
+			if stored := len(room.GetHistory()); stored != tt.stored {
+				t.Errorf("Expected all %d messages to stay stored, got %d", tt.stored, stored)
+			}
+		})
+	}
+}
+
+func TestHistoryExcludesSystemMessages(t *testing.T) {
+	room := NewRoom("Test Room", 5, true, 50, false)
+	defer room.Stop()
+	room.HistorySystem = false
+
+	msgs, unsubscribe := room.Subscribe()
+	defer unsubscribe()
+
+	sent := []Message{
+		{From: "System", Content: "alice has joined the room", Kind: KindJoin},
+		{From: "alice", Content: "hello", Kind: KindUser},
+		{From: "System", Content: "alice set the topic: x", Kind: KindSystem},
+		{From: "System", Content: "alice has left the room", Kind: KindLeave},
+	}
+	for _, msg := range sent {
+		room.Broadcast(msg)
+	}
+
+	for _, want := range sent {
+		select {
+		case msg := <-msgs:
+			if msg.Content != want.Content {
+				t.Errorf("Broadcast %q, want %q", msg.Content, want.Content)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for %q to be broadcast", want.Content)
+		}
+	}
+
+	history := room.GetHistory()
+	if len(history) != 1 || history[0].Content != "hello" {
+		t.Errorf("GetHistory() = %v, want only the chat message", history)
+	}
+}
+
+func TestHistoryKindsUserOnly(t *testing.T) {
+	room := NewRoom("Test Room", 5, true, 50, false)
+	defer room.Stop()
+
+	kinds, err := ParseMessageKinds("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	room.HistoryKinds = kinds
+
+	room.addToHistory(Message{From: "alice", Content: "hello", Kind: KindUser})
+	room.addToHistory(Message{From: "alice", Content: "waves", Kind: KindAction})
+	room.addToHistory(Message{From: "System", Content: "bob has joined the room", Kind: KindJoin})
+	room.addToHistory(Message{From: "bob", Content: "hi", Kind: KindUser})
+
+	history := room.GetHistory()
+	if len(history) != 2 || history[0].Content != "hello" || history[1].Content != "hi" {
+		t.Errorf("GetHistory() = %v, want only the two chat messages", history)
+	}
+}
+
+// hangupConn behaves like a client that disconnects once the history replay
+// header has been written, counting the writes attempted after that
+type hangupConn struct {
+	net.Conn
+	mu       sync.Mutex
+	hungUp   bool
+	attempts int
+}
+
+func (c *hangupConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hungUp {
+		c.attempts++
+		return 0, errors.New("broken pipe")
+	}
+	c.hungUp = strings.Contains(string(p), "Recent messages")
+	if c.Conn == nil {
+		return len(p), nil
+	}
+	return c.Conn.Write(p)
+}
+
+func (c *hangupConn) Close() error {
+	if c.Conn == nil {
+		return nil
+	}
+	return c.Conn.Close()
+}
+
+func (c *hangupConn) failedWrites() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.attempts
+}
+
+// fillHistory broadcasts n chat messages and waits for them to reach the history
+func fillHistory(t *testing.T, room *Room, n int) {
+	t.Helper()
+	for i := 1; i <= n; i++ {
+		room.Broadcast(Message{From: "alice", Content: fmt.Sprintf("msg%d", i), Timestamp: time.Now(), Kind: KindUser})
+	}
+	waitFor(t, "history to fill", func() bool { return len(room.GetHistory()) == n })
+}
+
+func TestSendHistoryStopsAfterHangup(t *testing.T) {
+	room := NewRoom("Test Room", 5, true, 50, true)
+	defer room.Stop()
+	fillHistory(t, room, 10)
+
+	conn := &hangupConn{}
+	client := &Client{conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+
+	if err := client.sendHistory(); err == nil {
+		t.Fatal("Expected sendHistory to report the failed write")
+	}
+	if got := conn.failedWrites(); got != 1 {
+		t.Errorf("Expected replay to stop after the first failed write, got %d attempts", got)
+	}
+	if client.pagerBuffer != nil {
+		t.Errorf("Expected no history left for /more, got %d lines", len(client.pagerBuffer))
+	}
+}
+
+func TestHangupDuringHistoryReplayLeavesRoom(t *testing.T) {
+	room := NewRoom("Test Room", 5, true, 50, true)
+	defer room.Stop()
+	fillHistory(t, room, 10)
+
+	server, remote := net.Pipe()
+	defer remote.Close()
+	go drain(remote)
+	go remote.Write([]byte("bob\n"))
+
+	conn := &hangupConn{Conn: server}
+	if _, err := NewPlainTextClient(conn, room, ClientOptions{PlainText: true}); err == nil {
+		t.Fatal("Expected history replay failure")
+	}
+
+	waitFor(t, "bob to leave", func() bool { return room.GetClient("bob") == nil })
+	if got := conn.failedWrites(); got != 1 {
+		t.Errorf("Expected one failed write, got %d", got)
+	}
+}
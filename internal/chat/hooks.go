@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// HookTimeout bounds how long a join/leave hook may run before it is killed
+const HookTimeout = 10 * time.Second
+
+// addrOf returns the peer address of a connection, or "" if unknown
+func addrOf(conn net.Conn) string {
+	if conn == nil || conn.RemoteAddr() == nil {
+		return ""
+	}
+	return conn.RemoteAddr().String()
+}
+
+// runHook starts the operator's command for a join or leave event without
+// blocking the room. The command line is split on whitespace and run directly,
+// never through a shell; the nickname and address are passed as the last two
+// arguments and as CHAT_* environment variables.
+func (r *Room) runHook(command, event, nickname, addr string) {
+	if strings.TrimSpace(command) == "" {
+		return
+	}
+	go r.execHook(command, event, nickname, addr)
+}
+
+// execHook runs a hook command to completion, logging failures and timeouts
+func (r *Room) execHook(command, event, nickname, addr string) {
+	ctx, cancel := context.WithTimeout(context.Background(), HookTimeout)
+	defer cancel()
+
+	fields := strings.Fields(command)
+	args := append(fields[1:], nickname, addr)
+	cmd := exec.CommandContext(ctx, fields[0], args...)
+	cmd.Env = append(os.Environ(),
+		"CHAT_EVENT="+event,
+		"CHAT_NICKNAME="+nickname,
+		"CHAT_REMOTE_ADDR="+addr,
+		"CHAT_ROOM="+r.Name,
+	)
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			log.Printf("%s hook for %s timed out after %s", event, nickname, HookTimeout)
+			return
+		}
+		log.Printf("%s hook for %s failed: %v", event, nickname, err)
+	}
+}
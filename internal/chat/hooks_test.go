@@ -0,0 +1,82 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJoinLeaveHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook script needs a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "events")
+	script := filepath.Join(dir, "hook.sh")
+	body := "#!/bin/sh\necho \"$CHAT_EVENT $1 $CHAT_NICKNAME $CHAT_ROOM\" >> " + out + "\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	room := NewRoom("Test Room", 10, false, 0, false)
+	defer room.Stop()
+	room.OnJoinCmd = script
+	room.OnLeaveCmd = script
+
+	// A nickname that would be dangerous in a shell string must arrive verbatim
+	client := NewTUIClient(nil, room)
+	client.Nickname = "bob;rm"
+	room.Join(client)
+	waitForLines(t, out, 1)
+	room.Leave(client)
+	lines := waitForLines(t, out, 2)
+
+	want := []string{"join bob;rm bob;rm Test Room", "leave bob;rm bob;rm Test Room"}
+	for i, line := range want {
+		if lines[i] != line {
+			t.Errorf("Hook line %d = %q, want %q", i, lines[i], line)
+		}
+	}
+}
+
+func TestHookFailureDoesNotBlockRoom(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, false)
+	defer room.Stop()
+	room.OnJoinCmd = filepath.Join(t.TempDir(), "missing")
+
+	client := NewTUIClient(nil, room)
+	client.Nickname = "alice"
+	done := make(chan struct{})
+	go func() {
+		room.Join(client)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Join blocked on a failing hook")
+	}
+}
+
+// waitForLines polls path until it holds at least n lines
+func waitForLines(t *testing.T, path string, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if len(lines) >= n {
+				return lines
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %d hook lines in %s", n, path)
+	return nil
+}
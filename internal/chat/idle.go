@@ -0,0 +1,131 @@
+package chat
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// DefaultIdleWarning is how long before an idle disconnect the client is warned
+const DefaultIdleWarning = 30 * time.Second
+
+// idleCheckInterval is how often the room looks for idle clients
+const idleCheckInterval = time.Second
+
+// SetIdleTimeout disconnects clients that send nothing for timeout, warning
+// them warning beforehand (0 skips the warning). A background check runs until
+// the room stops. Zero timeout disables it.
+func (r *Room) SetIdleTimeout(timeout, warning time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	if warning >= timeout {
+		warning = timeout / 2
+	}
+
+	r.mu.Lock()
+	r.idleTimeout = timeout
+	r.idleWarning = warning
+	r.mu.Unlock()
+
+	go r.idleLoop(idleCheckInterval)
+}
+
+// idleLoop checks for idle clients on each tick until the room stops
+func (r *Room) idleLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			r.checkIdle(r.Clock.Now())
+		}
+	}
+}
+
+// checkIdle warns clients nearing the idle timeout at now and disconnects
+// those past it
+func (r *Room) checkIdle(now time.Time) {
+	r.mu.RLock()
+	timeout, warning := r.idleTimeout, r.idleWarning
+	var clients []*Client
+	for _, client := range r.clients {
+		if client != nil {
+			clients = append(clients, client)
+		}
+	}
+	r.mu.RUnlock()
+
+	if timeout <= 0 {
+		return
+	}
+
+	for _, client := range clients {
+		switch client.idleStage(now, timeout, warning) {
+		case idleWarn:
+			client.Send(Message{
+				From:      "System",
+				Content:   fmt.Sprintf("You'll be disconnected in %s due to inactivity. Send anything to stay connected.", warning),
+				Timestamp: now,
+				Kind:      KindSystem,
+			})
+		case idleKick:
+			log.Printf("Disconnecting %s after %s of inactivity", client.Nickname, timeout)
+			client.setQuitReason("idle")
+			go func() {
+				client.Send(Message{
+					From:      "System",
+					Content:   fmt.Sprintf("You have been disconnected after %s of inactivity.", timeout),
+					Timestamp: now,
+					Kind:      KindSystem,
+				})
+				client.flush()
+				// The client's handler notices the closed connection and leaves the room
+				client.close()
+			}()
+		}
+	}
+}
+
+// idleAction is what checkIdle should do about a client
+type idleAction int
+
+const (
+	idleNone idleAction = iota
+	idleWarn
+	idleKick
+)
+
+// idleStage decides whether the client is due a warning or a disconnect at
+// now. A warning is given once per idle stretch and doesn't count as activity.
+func (c *Client) idleStage(now time.Time, timeout, warning time.Duration) idleAction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lastActive.IsZero() || c.idleKicked {
+		return idleNone
+	}
+
+	idle := now.Sub(c.lastActive)
+	switch {
+	case idle >= timeout:
+		c.idleKicked = true
+		return idleKick
+	case warning > 0 && idle >= timeout-warning && !c.idleWarned:
+		c.idleWarned = true
+		return idleWarn
+	}
+	return idleNone
+}
+
+// touch records user activity at now, restarting the idle clock and
+// cancelling any pending warning
+func (c *Client) touch(now time.Time) {
+	c.mu.Lock()
+	c.lastActive = now
+	c.idleWarned = false
+	c.mu.Unlock()
+}
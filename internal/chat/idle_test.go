@@ -0,0 +1,101 @@
+package chat
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdleWarnThenDisconnect(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	clock := newFakeClock()
+	room.Clock = clock
+	room.mu.Lock()
+	room.idleTimeout = 5 * time.Minute
+	room.idleWarning = 30 * time.Second
+	room.mu.Unlock()
+
+	conn := &closableConn{}
+	client := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+	room.Join(client)
+
+	// 4m29s idle: too early to warn
+	clock.Advance(4*time.Minute + 29*time.Second)
+	room.checkIdle(clock.Now())
+	client.flush()
+	if strings.Contains(conn.String(), "due to inactivity") {
+		t.Fatalf("Warned too early: %q", conn.String())
+	}
+
+	// 4m30s idle: warned once
+	clock.Advance(time.Second)
+	room.checkIdle(clock.Now())
+	clock.Advance(10 * time.Second)
+	room.checkIdle(clock.Now())
+	client.flush()
+	if got := strings.Count(conn.String(), "You'll be disconnected in 30s due to inactivity"); got != 1 {
+		t.Fatalf("Expected one idle warning, got %d: %q", got, conn.String())
+	}
+
+	// The warning doesn't reset the idle clock: 5m idle disconnects
+	clock.Advance(20 * time.Second)
+	room.checkIdle(clock.Now())
+	waitFor(t, "the idle client to be disconnected", conn.closed.Load)
+	if !strings.Contains(conn.String(), "You have been disconnected after 5m0s of inactivity.") {
+		t.Errorf("Expected an idle disconnect notice, got %q", conn.String())
+	}
+	if reason := client.getQuitReason(); reason != "idle" {
+		t.Errorf("quit reason = %q, want %q", reason, "idle")
+	}
+}
+
+func TestIdleActivityCancelsWarning(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	clock := newFakeClock()
+	room.Clock = clock
+	room.mu.Lock()
+	room.idleTimeout = 5 * time.Minute
+	room.idleWarning = 30 * time.Second
+	room.mu.Unlock()
+
+	conn := &closableConn{}
+	client := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+	room.Join(client)
+
+	clock.Advance(4*time.Minute + 45*time.Second)
+	room.checkIdle(clock.Now())
+
+	// Sending anything restarts the clock
+	client.touch(clock.Now())
+	clock.Advance(4 * time.Minute)
+	room.checkIdle(clock.Now())
+	if conn.closed.Load() {
+		t.Fatal("Expected activity to cancel the idle disconnect")
+	}
+
+	// A fresh idle stretch gets a fresh warning
+	clock.Advance(40 * time.Second)
+	room.checkIdle(clock.Now())
+	client.flush()
+	if got := strings.Count(conn.String(), "due to inactivity"); got != 2 {
+		t.Errorf("Expected a warning for each idle stretch, got %d: %q", got, conn.String())
+	}
+	if conn.closed.Load() {
+		t.Error("Expected the client to still be connected")
+	}
+}
+
+func TestSetIdleTimeoutClampsWarning(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	room.SetIdleTimeout(time.Minute, 2*time.Minute)
+	room.mu.RLock()
+	defer room.mu.RUnlock()
+	if room.idleWarning != 30*time.Second {
+		t.Errorf("idleWarning = %s, want 30s", room.idleWarning)
+	}
+}
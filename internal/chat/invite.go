@@ -0,0 +1,19 @@
+package chat
+
+// InviteProvider is implemented by the server so /invite can show how to
+// connect without the chat package depending on it
+type InviteProvider interface {
+	ConnectCommand() string
+}
+
+// invite handles the /invite admin command, returning a ready-to-copy
+// connection command for sharing with someone who should join
+func (c *Client) invite() (string, error) {
+	if !c.isAdmin() {
+		return "Permission denied.", ErrPermissionDenied
+	}
+	if c.room.Invites == nil {
+		return "Invites are not available on this server.", nil
+	}
+	return "Share this to invite someone: " + c.room.Invites.ConnectCommand(), nil
+}
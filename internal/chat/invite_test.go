@@ -0,0 +1,31 @@
+package chat
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeInvites string
+
+func (f fakeInvites) ConnectCommand() string { return string(f) }
+
+func TestInvite(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+
+	client := NewTUIClient(nil, room)
+	if _, err := client.invite(); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("invite() by non-admin error = %v, want ErrPermissionDenied", err)
+	}
+
+	client.admin = true
+	if reply, _ := client.invite(); reply != "Invites are not available on this server." {
+		t.Errorf("invite() without provider = %q", reply)
+	}
+
+	room.Invites = fakeInvites("telnet chat.tail1234.ts.net 2323")
+	reply, err := client.invite()
+	if err != nil || reply != "Share this to invite someone: telnet chat.tail1234.ts.net 2323" {
+		t.Errorf("invite() = %q, %v", reply, err)
+	}
+}
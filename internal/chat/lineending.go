@@ -0,0 +1,38 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Line terminators for line-mode output
+const (
+	CRLF = "\r\n"
+	LF   = "\n"
+)
+
+// LookupLineEnding returns the terminator for a line ending name, "crlf" or
+// "lf". The empty name is CRLF, which is what telnet expects.
+func LookupLineEnding(name string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "crlf":
+		return CRLF, nil
+	case "lf":
+		return LF, nil
+	default:
+		return "", fmt.Errorf("unsupported line ending %q (use crlf or lf)", name)
+	}
+}
+
+// newline is the terminator this client's output lines end with
+func (c *Client) newline() string {
+	if c.room != nil && c.room.LineEnding != "" {
+		return c.room.LineEnding
+	}
+	return CRLF
+}
+
+// writeLine writes message followed by the client's line terminator
+func (c *Client) writeLine(message string) error {
+	return c.send(message + c.newline())
+}
@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLookupLineEnding(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", CRLF},
+		{"crlf", CRLF},
+		{"LF", LF},
+		{" lf ", LF},
+	}
+	for _, tt := range tests {
+		if got, err := LookupLineEnding(tt.name); err != nil || got != tt.want {
+			t.Errorf("LookupLineEnding(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := LookupLineEnding("cr"); err == nil {
+		t.Error("Expected an error for an unsupported line ending")
+	}
+}
+
+// scriptedConn feeds canned input to a client and records what it writes.
+// Writes come from the outbox goroutine too, so out is guarded by mu.
+type scriptedConn struct {
+	net.Conn
+	in  *strings.Reader
+	mu  sync.Mutex
+	out bytes.Buffer
+}
+
+func (c *scriptedConn) Read(p []byte) (int, error) { return c.in.Read(p) }
+
+func (c *scriptedConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.Write(p)
+}
+
+func (c *scriptedConn) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.String()
+}
+
+func (c *scriptedConn) Close() error                     { return nil }
+func (c *scriptedConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *scriptedConn) SetWriteDeadline(time.Time) error { return nil }
+func (c *scriptedConn) RemoteAddr() net.Addr             { return &net.TCPAddr{} }
+
+func TestLineEndingLF(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	room.LineEnding = LF
+
+	// A rejected nickname exercises the prompts before the welcome
+	conn := &scriptedConn{in: strings.NewReader("!!\nbob\n")}
+	client, err := NewPlainTextClient(conn, room, ClientOptions{PlainText: true})
+	if err != nil {
+		t.Fatalf("NewPlainTextClient() error = %v", err)
+	}
+	client.sendSystemMessage("hello")
+	client.showStats()
+	client.flush()
+
+	out := conn.String()
+	if strings.Contains(out, "\r") {
+		t.Errorf("Expected no carriage returns with LF line endings, got %q", out)
+	}
+	for _, want := range []string{
+		"Welcome to Chat Tails ===\n\n",
+		"underscores, and hyphens.\nPlease enter your nickname: ",
+		"[System] hello\n",
+		"- Peak online: 1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got %q", want, out)
+		}
+	}
+}
+
+func TestLineEndingDefaultsToCRLF(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	conn := &bufferConn{}
+	client := &Client{conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+	client.sendSystemMessage("hello")
+	client.writeLine("bye")
+
+	if got := conn.out.String(); !strings.HasSuffix(got, "hello\r\nbye\r\n") {
+		t.Errorf("Expected CRLF line endings, got %q", got)
+	}
+}
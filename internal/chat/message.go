@@ -0,0 +1,207 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/ui"
+)
+
+// MessageKind says what a message is and how it is rendered
+type MessageKind int
+
+const (
+	KindUser         MessageKind = iota // regular chat message
+	KindSystem                          // server notice or command output
+	KindAction                          // /me, /roll and friends
+	KindJoin                            // "X has joined the room"
+	KindLeave                           // "X has left the room"
+	KindPresence                        // coalesced join/leave summary
+	KindAnnouncement                    // admin notice sent with /announce
+	KindPrivate                         // direct message sent with /msg, never broadcast
+	KindOutput                          // preformatted command output shown to one TUI client
+	KindPinned                          // operator note from --pinned-file, replayed ahead of history
+)
+
+var messageKindNames = map[MessageKind]string{
+	KindUser:         "user",
+	KindSystem:       "system",
+	KindAction:       "action",
+	KindJoin:         "join",
+	KindLeave:        "leave",
+	KindPresence:     "presence",
+	KindAnnouncement: "announcement",
+	KindPrivate:      "private",
+	KindOutput:       "output",
+	KindPinned:       "pinned",
+}
+
+// ParseMessageKinds parses a comma-separated list of kind names such as
+// "user,action" into a set. Unknown names are an error.
+func ParseMessageKinds(list string) (map[MessageKind]bool, error) {
+	kinds := make(map[MessageKind]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		kind, ok := kindByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown message kind %q", name)
+		}
+		kinds[kind] = true
+	}
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("no message kinds in %q", list)
+	}
+	return kinds, nil
+}
+
+func kindByName(name string) (MessageKind, bool) {
+	for kind, kindName := range messageKindNames {
+		if kindName == name {
+			return kind, true
+		}
+	}
+	return 0, false
+}
+
+func (k MessageKind) String() string {
+	if name, ok := messageKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Message represents a chat message
+type Message struct {
+	ID         uint64 // assigned by the room when broadcast
+	Kind       MessageKind
+	From       string
+	Content    string
+	Timestamp  time.Time
+	To         []string // recipients of a private message
+	ReplyTo    uint64   // ID of the message this one replies to (0 if none)
+	ReplyQuote string   // content of the message being replied to
+	Priority   Priority // delivery priority; announcements and notices are always high
+	Origin     Origin   // who produced it; bridged and bot messages are badged
+
+	remote bool // arrived over the bus from another process, so it is not published again
+}
+
+// IsSystem reports whether the message comes from the server rather than a user
+func (m Message) IsSystem() bool {
+	switch m.Kind {
+	case KindSystem, KindJoin, KindLeave, KindPresence, KindAnnouncement, KindOutput, KindPinned:
+		return true
+	}
+	return false
+}
+
+// IsAction reports whether the message is an action such as /me
+func (m Message) IsAction() bool {
+	return m.Kind == KindAction
+}
+
+// IsPresence reports whether the message is a join/leave notice
+func (m Message) IsPresence() bool {
+	switch m.Kind {
+	case KindJoin, KindLeave, KindPresence:
+		return true
+	}
+	return false
+}
+
+// renderOptions are the per-client and per-room settings that change how a
+// message renders
+type renderOptions struct {
+	plain bool // no ANSI formatting
+	links bool // wrap URLs in OSC 8 hyperlinks; ignored for plain text
+	theme ui.Theme
+}
+
+// formatMessage renders a message for display, without a trailing newline
+func formatMessage(msg Message, opts renderOptions) string {
+	plain, theme := opts.plain, opts.theme
+	timeStr := messageLabel(msg)
+
+	// Bridged and bot senders are badged so they can't pass for local users
+	if badge := msg.Origin.badge(); badge != "" && !msg.IsSystem() {
+		msg.From = badge + " " + msg.From
+	}
+
+	// Plain-text clients always see the raw URL
+	if !plain && opts.links && !msg.IsSystem() {
+		msg.Content = ui.LinkifyURLs(msg.Content)
+	}
+
+	switch msg.Kind {
+	case KindOutput:
+		return msg.Content
+
+	case KindJoin:
+		if plain {
+			return theme.FormatJoinMessagePlain(msg.Content)
+		}
+		return theme.FormatJoinMessage(msg.Content)
+
+	case KindLeave:
+		if plain {
+			return theme.FormatLeaveMessagePlain(msg.Content)
+		}
+		return theme.FormatLeaveMessage(msg.Content)
+
+	case KindSystem, KindPresence:
+		if plain {
+			return theme.FormatSystemMessagePlain(msg.Content)
+		}
+		return theme.FormatSystemMessage(msg.Content)
+
+	case KindPinned:
+		if plain {
+			return theme.FormatPinnedMessagePlain(msg.Content)
+		}
+		return theme.FormatPinnedMessage(msg.Content)
+
+	case KindAnnouncement:
+		if plain {
+			return ui.FormatAnnouncementPlain(msg.Content)
+		}
+		return ui.FormatAnnouncement(msg.Content)
+
+	case KindPrivate:
+		if plain {
+			return theme.FormatPrivateMessagePlain(msg.From, msg.To, msg.Content, timeStr)
+		}
+		return theme.FormatPrivateMessage(msg.From, msg.To, msg.Content, timeStr)
+
+	case KindAction:
+		if plain {
+			return theme.FormatActionMessagePlain(msg.From, msg.Content)
+		}
+		return theme.FormatActionMessage(msg.From, msg.Content)
+	}
+
+	if msg.ReplyTo != 0 {
+		if plain {
+			return ui.FormatReplyMessagePlain(msg.From, msg.ReplyQuote, msg.Content, timeStr)
+		}
+		return theme.FormatReplyMessage(msg.From, msg.ReplyQuote, msg.Content, timeStr)
+	}
+
+	if plain {
+		return ui.FormatUserMessagePlain(msg.From, msg.Content, timeStr)
+	}
+	return theme.FormatUserMessage(msg.From, msg.Content, timeStr)
+}
+
+// formatMessageFor renders a message for the client nicknamed self, marking
+// their own chat lines when the accessibility theme is on
+func formatMessageFor(msg Message, opts renderOptions, self string) string {
+	formatted := formatMessage(msg, opts)
+	if msg.From == self && !msg.IsSystem() {
+		return opts.theme.MarkSelf(formatted)
+	}
+	return formatted
+}
@@ -0,0 +1,176 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/ui"
+)
+
+func TestFormatMessageKinds(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC)
+	label := messageLabel(Message{ID: 7, Timestamp: ts})
+
+	tests := []struct {
+		name      string
+		msg       Message
+		wantPlain string
+		wantANSI  string
+	}{
+		{
+			name:      "user",
+			msg:       Message{ID: 7, Kind: KindUser, From: "alice", Content: "hi", Timestamp: ts},
+			wantPlain: ui.FormatUserMessagePlain("alice", "hi", label),
+			wantANSI:  ui.Theme{}.FormatUserMessage("alice", "hi", label),
+		},
+		{
+			name:      "reply",
+			msg:       Message{ID: 7, Kind: KindUser, From: "alice", Content: "yes", Timestamp: ts, ReplyTo: 3, ReplyQuote: "ready?"},
+			wantPlain: ui.FormatReplyMessagePlain("alice", "ready?", "yes", label),
+			wantANSI:  ui.Theme{}.FormatReplyMessage("alice", "ready?", "yes", label),
+		},
+		{
+			name:      "system",
+			msg:       Message{Kind: KindSystem, From: "System", Content: "notice"},
+			wantPlain: "[System] notice",
+			wantANSI:  ui.Theme{}.FormatSystemMessage("notice"),
+		},
+		{
+			name:      "action",
+			msg:       Message{Kind: KindAction, From: "alice", Content: "waves"},
+			wantPlain: "* alice waves",
+			wantANSI:  ui.Theme{}.FormatActionMessage("alice", "waves"),
+		},
+		{
+			name:      "join",
+			msg:       Message{Kind: KindJoin, From: "System", Content: "bob has joined the room"},
+			wantPlain: "-> bob has joined the room",
+			wantANSI:  ui.Theme{}.FormatJoinMessage("bob has joined the room"),
+		},
+		{
+			name:      "leave",
+			msg:       Message{Kind: KindLeave, From: "System", Content: "bob has left the room"},
+			wantPlain: "<- bob has left the room",
+			wantANSI:  ui.Theme{}.FormatLeaveMessage("bob has left the room"),
+		},
+		{
+			name:      "presence",
+			msg:       Message{Kind: KindPresence, From: "System", Content: "3 users joined"},
+			wantPlain: "[System] 3 users joined",
+			wantANSI:  ui.Theme{}.FormatSystemMessage("3 users joined"),
+		},
+		{
+			name:      "announcement",
+			msg:       Message{Kind: KindAnnouncement, From: "alice", Content: "maintenance at noon"},
+			wantPlain: "*** ANNOUNCEMENT: maintenance at noon ***",
+			wantANSI:  ui.FormatAnnouncement("maintenance at noon"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatMessage(tt.msg, renderOptions{plain: true}); got != tt.wantPlain {
+				t.Errorf("formatMessage(plain) = %q, want %q", got, tt.wantPlain)
+			}
+			if got := formatMessage(tt.msg, renderOptions{}); got != tt.wantANSI {
+				t.Errorf("formatMessage(ansi) = %q, want %q", got, tt.wantANSI)
+			}
+		})
+	}
+}
+
+func TestFormatMessageHyperlinks(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC)
+	msg := Message{ID: 7, Kind: KindUser, From: "alice", Content: "docs at https://example.com/docs", Timestamp: ts}
+	label := messageLabel(msg)
+	var theme ui.Theme
+
+	want := theme.FormatUserMessage("alice", "docs at \x1b]8;;https://example.com/docs\x1b\\https://example.com/docs\x1b]8;;\x1b\\", label)
+	if got := formatMessage(msg, renderOptions{links: true}); got != want {
+		t.Errorf("ANSI formatMessage() = %q, want %q", got, want)
+	}
+	if got, want := formatMessage(msg, renderOptions{plain: true, links: true}), ui.FormatUserMessagePlain("alice", msg.Content, label); got != want {
+		t.Errorf("Plain formatMessage() = %q, want the raw URL %q", got, want)
+	}
+
+	notice := Message{Kind: KindSystem, From: "System", Content: "see https://example.com", Timestamp: ts}
+	if got := formatMessage(notice, renderOptions{links: true}); got != theme.FormatSystemMessage(notice.Content) {
+		t.Errorf("Expected system messages to be left alone, got %q", got)
+	}
+}
+
+func TestHyperlinksPerRoom(t *testing.T) {
+	linked := NewRoom("Linked", 5, false, 0, false)
+	defer linked.Stop()
+	linked.Hyperlinks = true
+	plain := NewRoom("Plain", 5, false, 0, false)
+	defer plain.Stop()
+
+	msg := Message{Kind: KindUser, From: "alice", Content: "https://example.com", Timestamp: time.Now()}
+	if got := NewTUIClient(nil, linked).formatFor(msg); !strings.Contains(got, "\x1b]8;;") {
+		t.Errorf("Expected a hyperlink in the room with Hyperlinks set, got %q", got)
+	}
+	if got := NewTUIClient(nil, plain).formatFor(msg); strings.Contains(got, "\x1b]8;;") {
+		t.Errorf("Expected no hyperlink in the other room, got %q", got)
+	}
+}
+
+func TestAccessiblePerRoom(t *testing.T) {
+	accessible := NewRoom("Accessible", 5, false, 0, false)
+	defer accessible.Stop()
+	accessible.Accessible = true
+	standard := NewRoom("Standard", 5, false, 0, false)
+	defer standard.Stop()
+
+	msg := Message{Kind: KindSystem, From: "System", Content: "notice", Timestamp: time.Now()}
+	if got := NewTUIClient(nil, accessible).formatFor(msg); !strings.Contains(got, ui.SystemTag) {
+		t.Errorf("Expected the %q tag in the accessible room, got %q", ui.SystemTag, got)
+	}
+	if got := NewTUIClient(nil, standard).formatFor(msg); strings.Contains(got, ui.SystemTag) {
+		t.Errorf("Expected no %q tag in the other room, got %q", ui.SystemTag, got)
+	}
+}
+
+func TestMessageKindHelpers(t *testing.T) {
+	tests := []struct {
+		kind         MessageKind
+		wantSystem   bool
+		wantAction   bool
+		wantPresence bool
+	}{
+		{KindUser, false, false, false},
+		{KindSystem, true, false, false},
+		{KindAction, false, true, false},
+		{KindJoin, true, false, true},
+		{KindLeave, true, false, true},
+		{KindPresence, true, false, true},
+		{KindAnnouncement, true, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind.String(), func(t *testing.T) {
+			msg := Message{Kind: tt.kind}
+			if msg.IsSystem() != tt.wantSystem || msg.IsAction() != tt.wantAction || msg.IsPresence() != tt.wantPresence {
+				t.Errorf("helpers = (%v, %v, %v), want (%v, %v, %v)",
+					msg.IsSystem(), msg.IsAction(), msg.IsPresence(), tt.wantSystem, tt.wantAction, tt.wantPresence)
+			}
+		})
+	}
+}
+
+func TestParseMessageKinds(t *testing.T) {
+	kinds, err := ParseMessageKinds(" User, action ")
+	if err != nil {
+		t.Fatalf("ParseMessageKinds() error = %v", err)
+	}
+	if len(kinds) != 2 || !kinds[KindUser] || !kinds[KindAction] {
+		t.Errorf("ParseMessageKinds() = %v, want user and action", kinds)
+	}
+
+	for _, list := range []string{"user,chatter", "", " , "} {
+		if _, err := ParseMessageKinds(list); err == nil {
+			t.Errorf("ParseMessageKinds(%q) succeeded, want an error", list)
+		}
+	}
+}
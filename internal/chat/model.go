@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -32,25 +33,36 @@ type ChatModel struct {
 	ready     bool
 	errMsg    string
 	quitting  bool
+
+	nickAttempts int    // rejected nicknames so far
+	pendingNick  string // reserved nickname whose password is being entered
+	maxMessages  int    // scrollback limit; the oldest messages are dropped past it (0 keeps all)
 }
 
 // NewChatModel creates a model in the nickname-entry state.
 func NewChatModel(client *Client) ChatModel {
 	ti := textinput.New()
 	ti.Placeholder = "Enter nickname..."
-	ti.CharLimit = MaxNicknameLen
-	ti.Width = 40
+	ti.CharLimit = client.room.MaxNicknameLen
+	ti.Width = nicknameInputWidth(80)
 	ti.Focus()
 
 	return ChatModel{
-		state:     stateNickname,
-		textInput: ti,
-		client:    client,
-		width:     80,
-		height:    24,
+		state:       stateNickname,
+		textInput:   ti,
+		client:      client,
+		width:       80,
+		maxMessages: client.room.Scrollback,
+		height:      24,
 	}
 }
 
+// nicknameInputWidth fits the nickname prompt, indented two columns, into a
+// terminal of the given width without growing past 40 columns
+func nicknameInputWidth(width int) int {
+	return max(min(40, width-4), 10)
+}
+
 func (m ChatModel) Init() tea.Cmd {
 	return textinput.Blink
 }
@@ -60,7 +72,10 @@ func (m ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		if m.state == stateChat {
+		switch m.state {
+		case stateNickname:
+			m.textInput.Width = nicknameInputWidth(m.width)
+		case stateChat:
 			m.resizeViewport()
 		}
 		return m, nil
@@ -98,9 +113,9 @@ func (m ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m ChatModel) View() string {
 	if m.quitting {
 		if m.errMsg != "" {
-			return ui.FormatSystemMessage(m.errMsg) + "\n"
+			return m.client.room.theme().FormatSystemMessage(m.errMsg) + "\n"
 		}
-		return ui.FormatSystemMessage("Goodbye!") + "\n"
+		return m.client.room.theme().FormatSystemMessage("Goodbye!") + "\n"
 	}
 
 	switch m.state {
@@ -115,28 +130,50 @@ func (m ChatModel) View() string {
 
 // --- Nickname state ---
 
+// rejectNickname counts a rejected nickname and disconnects once the room's cap is hit
+func (m ChatModel) rejectNickname() (tea.Model, tea.Cmd) {
+	m.nickAttempts++
+	if m.client.room.nicknameAttemptsExceeded(m.nickAttempts) {
+		m.errMsg = "Too many invalid nickname attempts."
+		m.quitting = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
 func (m ChatModel) updateNickname(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.Type {
 	case tea.KeyEnter:
-		nickname := strings.TrimSpace(m.textInput.Value())
+		if m.pendingNick != "" {
+			return m.checkReservedPassword()
+		}
 
-		if err := validateNickname(nickname); err != nil {
+		nickname := normalizeNickname(m.textInput.Value())
+
+		if err := validateNickname(nickname, m.client.room.MinNicknameLen, m.client.room.MaxNicknameLen); err != nil {
 			m.errMsg = err.Error()
 			m.textInput.Reset()
-			return m, nil
+			return m.rejectNickname()
 		}
 
-		if !m.client.room.ReserveNickname(nickname) {
-			m.errMsg = fmt.Sprintf("Nickname '%s' is already taken.", nickname)
+		// The password comes first: holding the name while the user types it
+		// would leak the reservation if they disconnect
+		reserved := m.client.room.ReservedNicks
+		if nick, ok := reserved.lookup(nickname); ok {
 			m.textInput.Reset()
+			if !reserved.claimable(nick) {
+				m.errMsg = fmt.Sprintf("Nickname '%s' is reserved.", nickname)
+				return m.rejectNickname()
+			}
+			m.pendingNick = nickname
+			m.errMsg = ""
+			m.textInput.Placeholder = "Password for " + nickname + "..."
+			m.textInput.EchoMode = textinput.EchoPassword
+			m.textInput.CharLimit = 0
 			return m, nil
 		}
 
-		m.client.Nickname = nickname
-		m.errMsg = ""
-
-		// Join room asynchronously via Cmd
-		return m, m.joinRoomCmd()
+		return m.takeNickname(nickname, false)
 
 	case tea.KeyEsc:
 		m.quitting = true
@@ -148,6 +185,42 @@ func (m ChatModel) updateNickname(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// checkReservedPassword handles the password entered for a reserved nickname
+func (m ChatModel) checkReservedPassword() (tea.Model, tea.Cmd) {
+	nickname, password := m.pendingNick, strings.TrimSpace(m.textInput.Value())
+	m.pendingNick = ""
+	m.textInput.Reset()
+	m.textInput.Placeholder = "Enter nickname..."
+	m.textInput.EchoMode = textinput.EchoNormal
+	m.textInput.CharLimit = m.client.room.MaxNicknameLen
+
+	reserved := m.client.room.ReservedNicks
+	nick, _ := reserved.lookup(nickname)
+	if !reserved.check(nick, password) {
+		m.errMsg = fmt.Sprintf("Incorrect password for '%s'.", nickname)
+		return m.rejectNickname()
+	}
+	return m.takeNickname(nickname, nick.Admin)
+}
+
+// takeNickname reserves an accepted nickname and joins the room with it
+func (m ChatModel) takeNickname(nickname string, admin bool) (tea.Model, tea.Cmd) {
+	if !m.client.room.ReserveNickname(nickname) {
+		m.errMsg = fmt.Sprintf("Nickname '%s' is already taken.", nickname)
+		m.textInput.Reset()
+		return m.rejectNickname()
+	}
+
+	m.client.Nickname = nickname
+	m.errMsg = ""
+	if admin {
+		m.client.grantAdmin("reserved nickname")
+	}
+
+	// Join room asynchronously via Cmd
+	return m, m.joinRoomCmd()
+}
+
 func (m ChatModel) joinRoomCmd() tea.Cmd {
 	client := m.client
 	return func() tea.Msg {
@@ -167,20 +240,21 @@ func (m ChatModel) nicknameView() string {
 	subtitleStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("#383838"))
 
-	banner := `  _____ _           _     _____     _ _
- / ____| |         | |   |_   _|   (_) |
-| |    | |__   __ _| |_    | | __ _ _| |___
-| |    | '_ \ / _' | __|   | |/ _' | | / __|
-| |____| | | | (_| | |_    | | (_| | | \__ \
- \_____|_| |_|\__,_|\__|   |_|\__,_|_|_|___/`
+	banner := m.client.room.Banner
+	if banner == "" {
+		banner = ui.LogoBanner
+	}
+	banner = ui.FitBanner(banner, m.width)
 
 	var b strings.Builder
 
 	b.WriteString("\n")
-	b.WriteString(bannerStyle.Render(banner))
-	b.WriteString("\n")
-	b.WriteString(subtitleStyle.Render("  Terminal chat over TCP & Tailscale"))
-	b.WriteString("\n\n")
+	if !m.client.useCompact() {
+		b.WriteString(bannerStyle.Render(banner))
+		b.WriteString("\n")
+		b.WriteString(subtitleStyle.Render("  Terminal chat over TCP & Tailscale"))
+		b.WriteString("\n\n")
+	}
 
 	b.WriteString("  " + m.textInput.View())
 	b.WriteString("\n\n")
@@ -202,19 +276,39 @@ func (m ChatModel) nicknameView() string {
 
 func (m ChatModel) handleJoined() (tea.Model, tea.Cmd) {
 	m.state = stateChat
-	m.initViewport()
 	m.errMsg = ""
 
-	// Reconfigure text input for chat mode
+	// Reconfigure text input for chat mode, sized from the latest known
+	// terminal size even if it changed during nickname entry
 	m.textInput.Placeholder = "Type a message..."
-	m.textInput.CharLimit = MaxMessageLength
-	m.textInput.Width = m.width - 4
+	m.textInput.CharLimit = m.client.room.MaxMessageLength
 	m.textInput.Reset()
+	m.initViewport()
 
 	// Load message history
-	history := m.client.room.GetHistory()
+	history := m.client.room.ReplayHistory()
 	for _, msg := range history {
-		m.messages = append(m.messages, msg)
+		if m.client.wantsMessage(msg) {
+			m.messages = append(m.messages, msg)
+		}
+	}
+
+	if motd := m.client.room.MOTD; motd != "" {
+		m.messages = append(m.messages, Message{
+			From:      "System",
+			Content:   "Message of the Day:\n" + motd,
+			Timestamp: time.Now(),
+			Kind:      KindSystem,
+		})
+	}
+
+	if topic := m.client.room.Topic(); topic.Text != "" {
+		m.messages = append(m.messages, Message{
+			From:      "System",
+			Content:   topic.String(),
+			Timestamp: time.Now(),
+			Kind:      KindSystem,
+		})
 	}
 	m.updateViewportContent()
 
@@ -226,18 +320,28 @@ func (m ChatModel) updateChat(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case tea.KeyEnter:
 		message := strings.TrimSpace(m.textInput.Value())
 		m.textInput.Reset()
+		m.client.touch(m.client.room.Clock.Now())
+
+		if reply, ok := m.client.answerPing(m.client.room.Clock.Now()); ok {
+			m.appendSystemMessage(reply)
+		}
 
 		if message == "" {
 			return m, nil
 		}
 
-		if len(message) > MaxMessageLength {
-			m.appendSystemMessage(fmt.Sprintf("Message too long (max %d characters)", MaxMessageLength))
+		if err := m.client.validateMessageLength(message); err != nil {
+			m.appendSystemMessage(fmt.Sprintf("Error: %v", err))
 			return m, nil
 		}
 
 		// Check rate limit
-		if err := m.client.checkRateLimit(); err != nil {
+		if err := m.client.checkRateLimit(); errors.Is(err, ErrFloodKick) {
+			m.errMsg = floodKickNotice
+			m.client.kickForFlooding()
+			m.quitting = true
+			return m, tea.Quit
+		} else if err != nil {
 			m.appendSystemMessage(fmt.Sprintf("Error: %v", err))
 			return m, nil
 		}
@@ -247,6 +351,11 @@ func (m ChatModel) updateChat(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m.handleCommand(message)
 		}
 
+		if err := m.client.checkJoinCooldown(m.client.room.Clock.Now()); err != nil {
+			m.appendSystemMessage(fmt.Sprintf("Error: %v", err))
+			return m, nil
+		}
+
 		// Broadcast regular message
 		m.client.room.Broadcast(Message{
 			From:      m.client.Nickname,
@@ -279,12 +388,16 @@ func (m *ChatModel) handleCommand(cmd string) (tea.Model, tea.Cmd) {
 
 	switch command {
 	case "/who":
-		users := m.client.room.GetUserList()
-		userList := fmt.Sprintf("Users in %s (%d/%d):", m.client.room.Name, len(users), m.client.room.MaxUsers)
-		for _, user := range users {
-			userList += "\n  - " + user
+		page, err := parseWhoPage(parts)
+		if err != nil {
+			m.appendSystemMessage("Usage: /who [page]")
+			return m, nil
+		}
+
+		m.appendOutput(m.client.userList(page))
+		if topic := m.client.room.Topic(); topic.Text != "" {
+			m.appendSystemMessage(topic.String())
 		}
-		m.appendSystemMessage(userList)
 
 	case "/me":
 		if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
@@ -295,18 +408,231 @@ func (m *ChatModel) handleCommand(cmd string) (tea.Model, tea.Cmd) {
 			From:      m.client.Nickname,
 			Content:   parts[1],
 			Timestamp: time.Now(),
-			IsAction:  true,
+			Kind:      KindAction,
+		})
+
+	case "/roll":
+		spec := ""
+		if len(parts) > 1 {
+			spec = parts[1]
+		}
+		action, err := rollAction(spec)
+		if err != nil {
+			m.appendSystemMessage(fmt.Sprintf("Error: %v", err))
+			return m, nil
+		}
+		m.client.room.Broadcast(Message{
+			From:      m.client.Nickname,
+			Content:   action,
+			Timestamp: time.Now(),
+			Kind:      KindAction,
 		})
 
+	case "/flip":
+		m.client.room.Broadcast(Message{
+			From:      m.client.Nickname,
+			Content:   flipAction(),
+			Timestamp: time.Now(),
+			Kind:      KindAction,
+		})
+
+	case "/reply":
+		args := ""
+		if len(parts) > 1 {
+			args = parts[1]
+		}
+		reply, err := buildReply(m.client.room, m.client.Nickname, args)
+		if err != nil {
+			m.appendSystemMessage(fmt.Sprintf("Error: %v", err))
+			return m, nil
+		}
+		m.client.room.Broadcast(reply)
+
+	case "/stats":
+		stats := m.client.room.Stats()
+		m.appendSystemMessage(fmt.Sprintf("Stats for %s:\n  Messages since start: %d\n  Messages last minute: %d\n  Online now: %d\n  Peak online: %d",
+			m.client.room.Name, stats.TotalMessages, stats.MessagesLastMinute, stats.Online, stats.PeakOnline))
+
+	case "/whoami":
+		m.appendOutput(m.client.whoami(m.client.room.Clock.Now()))
+
+	case "/motd":
+		if motd := m.client.room.MOTD; motd != "" {
+			m.appendSystemMessage("Message of the Day:\n" + motd)
+		} else {
+			m.appendSystemMessage(noMOTD)
+		}
+
+	case "/seen":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		if reply, err := m.client.seen(arg, m.client.room.Clock.Now()); err != nil {
+			m.appendSystemMessage(reply)
+		} else {
+			m.appendOutput(reply)
+		}
+
+	case "/plain":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		reply, _ := m.client.togglePlainText(arg)
+		m.appendSystemMessage(reply)
+
+	case "/compact":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		reply, _ := m.client.toggleCompact(arg)
+		m.appendSystemMessage(reply)
+
+	case "/msg":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		msg, notes, err := sendPrivate(m.client.room, m.client.Nickname, arg)
+		for _, note := range notes {
+			m.appendSystemMessage(note)
+		}
+		if err != nil {
+			m.appendSystemMessage(fmt.Sprintf("Error: %v", err))
+			return m, nil
+		}
+		m.messages = append(m.messages, msg)
+		m.trimMessages()
+		m.updateViewportContent()
+		m.viewport.GotoBottom()
+
+	case "/export":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		payload, _ := m.client.export(arg)
+		m.appendSystemMessage(payload)
+
+	case "/topic":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		reply, _ := m.client.changeTopic(arg, m.client.room.Clock.Now())
+		m.appendSystemMessage(reply)
+
+	case "/ping":
+		reply, _ := m.client.startPing(m.client.room.Clock.Now())
+		m.appendSystemMessage(reply)
+
+	case "/feedback":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		reply, _ := m.client.feedback(arg, m.client.room.Clock.Now())
+		m.appendSystemMessage(reply)
+
+	case "/more":
+		// The viewport already scrolls, so there is never anything buffered
+		m.appendSystemMessage("Nothing more to show.")
+
+	case "/search":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		reply, _ := m.client.search(arg, m.client.room.Clock.Now())
+		m.appendSystemMessage(reply)
+
+	case "/mute":
+		m.appendSystemMessage(m.client.mute())
+
+	case "/unmute":
+		m.appendSystemMessage(m.client.unmute())
+
+	case "/dnd":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		reply, _ := m.client.toggleDND(arg)
+		m.appendSystemMessage(reply)
+
+	case "/admin":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		reply, _ := m.client.elevate(arg)
+		m.appendSystemMessage(reply)
+
+	case "/connections":
+		reply, _ := m.client.listConnections()
+		m.appendSystemMessage(reply)
+
+	case "/invite":
+		reply, _ := m.client.invite()
+		m.appendSystemMessage(reply)
+
+	case "/disconnect":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		reply, _ := m.client.disconnectAddr(arg)
+		m.appendSystemMessage(reply)
+
+	case "/announce":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		if reply, err := m.client.announce(arg); err != nil {
+			m.appendSystemMessage(reply)
+		}
+
+	case "/closeroom":
+		arg := ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		if reply, err := m.client.closeRoom(arg); err != nil {
+			m.appendSystemMessage(reply)
+		}
+
 	case "/help":
 		help := "Commands:\n" +
-			"  /who    - Show online users\n" +
+			"  /who    - Show online users (optional page)\n" +
 			"  /me     - Perform an action\n" +
+			"  /reply  - Reply to a message by ID\n" +
+			"  /msg    - Private message (nick[,nick...] text)\n" +
+			"  /roll   - Roll dice (NdM, default 1d6)\n" +
+			"  /flip   - Flip a coin\n" +
+			"  /stats  - Show room activity\n" +
+			"  /whoami - Show your nickname, color and identity\n" +
+			"  /plain  - Toggle plain-text output (on|off)\n" +
+			"  /compact - Dense output for small screens (on|off)\n" +
+			"  /dnd    - Hide join/leave notices (on|off)\n" +
+			"  /mute   - Pause incoming chat (/unmute resumes)\n" +
+			"  /admin  - Become an admin with the admin token\n" +
+			"  /announce - Broadcast a notice (admins only)\n" +
+			"  /connections, /disconnect <addr> - Manage connections (admins only)\n" +
+			"  /closeroom <name> - Disconnect everyone from the room (admins only)\n" +
 			"  /help   - Show this help\n" +
-			"  /quit   - Leave the chat"
+			"  /quit   - Leave the chat (optional reason)"
+		if m.client.room.MOTD != "" {
+			help += "\n  /motd   - Show the message of the day again"
+		}
 		m.appendSystemMessage(help)
 
 	case "/quit":
+		if len(parts) > 1 {
+			m.client.setQuitReason(parts[1])
+		}
 		m.quitting = true
 		return m, tea.Quit
 
@@ -319,6 +645,7 @@ func (m *ChatModel) handleCommand(cmd string) (tea.Model, tea.Cmd) {
 
 func (m ChatModel) handleChatMsg(msg ChatMsg) (tea.Model, tea.Cmd) {
 	m.messages = append(m.messages, msg.Message)
+	m.trimMessages()
 	wasAtBottom := m.viewport.AtBottom()
 	m.updateViewportContent()
 	if wasAtBottom {
@@ -329,28 +656,23 @@ func (m ChatModel) handleChatMsg(msg ChatMsg) (tea.Model, tea.Cmd) {
 
 // --- Viewport helpers ---
 
-func (m *ChatModel) initViewport() {
+// viewportHeight is the message area height left after the status bar and input
+func (m *ChatModel) viewportHeight() int {
 	headerHeight := 1 // status bar
 	inputHeight := 3  // input area with border
-	vpHeight := m.height - headerHeight - inputHeight - 1
-	if vpHeight < 3 {
-		vpHeight = 3
-	}
+	return max(m.height-headerHeight-inputHeight-1, 3)
+}
 
-	m.viewport = viewport.New(m.width, vpHeight)
+func (m *ChatModel) initViewport() {
+	m.viewport = viewport.New(m.width, m.viewportHeight())
 	m.viewport.Style = lipgloss.NewStyle()
+	m.textInput.Width = m.width - 4
 	m.ready = true
 }
 
 func (m *ChatModel) resizeViewport() {
-	headerHeight := 1
-	inputHeight := 3
-	vpHeight := m.height - headerHeight - inputHeight - 1
-	if vpHeight < 3 {
-		vpHeight = 3
-	}
 	m.viewport.Width = m.width
-	m.viewport.Height = vpHeight
+	m.viewport.Height = m.viewportHeight()
 	m.textInput.Width = m.width - 4
 }
 
@@ -363,29 +685,57 @@ func (m *ChatModel) updateViewportContent() {
 }
 
 func (m *ChatModel) formatMessage(msg Message) string {
-	timeStr := msg.Timestamp.Format("15:04:05")
+	formatted := m.client.formatFor(msg)
 
-	if msg.IsSystem {
-		return ui.FormatSystemMessage(msg.Content)
-	}
-	if msg.IsAction {
-		return ui.FormatActionMessage(msg.From, msg.Content)
+	// Indent replies so threads stand out in the viewport
+	if msg.Kind == KindUser && msg.ReplyTo != 0 {
+		if m.client.usePlainText() {
+			return "  " + formatted
+		}
+		return "  ↳ " + formatted
 	}
-	return ui.FormatUserMessage(msg.From, msg.Content, timeStr)
+	return formatted
 }
 
 func (m *ChatModel) appendSystemMessage(content string) {
-	msg := Message{
+	m.appendMessage(Message{
 		From:      "System",
 		Content:   content,
 		Timestamp: time.Now(),
-		IsSystem:  true,
-	}
+		Kind:      KindSystem,
+	})
+}
+
+// appendOutput shows already formatted command output, such as the /who box, as is
+func (m *ChatModel) appendOutput(content string) {
+	m.appendMessage(Message{
+		From:      "System",
+		Content:   content,
+		Timestamp: time.Now(),
+		Kind:      KindOutput,
+	})
+}
+
+func (m *ChatModel) appendMessage(msg Message) {
 	m.messages = append(m.messages, msg)
+	m.trimMessages()
 	m.updateViewportContent()
 	m.viewport.GotoBottom()
 }
 
+// trimMessages drops the oldest messages beyond the scrollback limit so long
+// sessions don't grow memory and the viewport content without bound
+func (m *ChatModel) trimMessages() {
+	if m.maxMessages <= 0 || len(m.messages) <= m.maxMessages {
+		return
+	}
+
+	// Copy into a fresh slice so the dropped messages can be garbage collected
+	kept := make([]Message, m.maxMessages)
+	copy(kept, m.messages[len(m.messages)-m.maxMessages:])
+	m.messages = kept
+}
+
 // --- Chat view ---
 
 func (m ChatModel) chatView() string {
@@ -404,9 +754,17 @@ func (m ChatModel) chatView() string {
 		Background(lipgloss.Color("#4A2DB0")).
 		Padding(0, 1)
 
-	users := m.client.room.GetUserList()
+	// Color our own nickname as it appears in chat; each segment keeps the
+	// bar's background so the padding and gap still line up
+	nickStyle := statusInfoStyle.
+		Foreground(lipgloss.Color(m.client.room.theme().UserColor(m.client.Nickname))).
+		Bold(true).
+		PaddingRight(0)
+	onlineStyle := statusInfoStyle.PaddingLeft(0)
+
 	statusLeft := statusStyle.Render(m.client.room.Name)
-	statusRight := statusInfoStyle.Render(fmt.Sprintf("%s | %d online", m.client.Nickname, len(users)))
+	statusRight := nickStyle.Render(m.client.Nickname) +
+		onlineStyle.Render(fmt.Sprintf(" | %d online", m.client.room.OnlineCount()))
 
 	statusGap := m.width - lipgloss.Width(statusLeft) - lipgloss.Width(statusRight)
 	if statusGap < 0 {
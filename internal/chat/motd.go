@@ -0,0 +1,27 @@
+package chat
+
+import "github.com/bscott/ts-chat/internal/ui"
+
+// noMOTD answers /motd when the server has no message of the day
+const noMOTD = "No message of the day set."
+
+// formatMOTD renders the room's message of the day as the welcome shows it
+func (c *Client) formatMOTD() string {
+	switch {
+	case c.useCompact():
+		return ui.FormatMOTDCompact(c.room.MOTD)
+	case c.usePlainText():
+		return ui.FormatMOTDPlain(c.room.MOTD)
+	default:
+		return ui.FormatMOTD(c.room.MOTD)
+	}
+}
+
+// showMOTD answers /motd by repeating the message of the day to the caller only
+func (c *Client) showMOTD() error {
+	if c.room.MOTD == "" {
+		c.sendSystemMessage(noMOTD)
+		return nil
+	}
+	return c.writeLine(c.formatMOTD())
+}
@@ -0,0 +1,48 @@
+package chat
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestMOTDCommand(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	room.MOTD = "Be nice"
+
+	conn := &bufferConn{}
+	client := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+
+	if err := client.handleCommand("/motd"); err != nil {
+		t.Fatalf("/motd error = %v", err)
+	}
+	if got, want := conn.out.String(), "--- Message of the Day ---\nBe nice\n---\r\n"; got != want {
+		t.Errorf("/motd wrote %q, want %q", got, want)
+	}
+
+	conn.out.Reset()
+	client.showHelp()
+	if !strings.Contains(conn.out.String(), "/motd") {
+		t.Error("Expected /help to list /motd when a message of the day is set")
+	}
+}
+
+func TestMOTDCommandWithoutMOTD(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	conn := &bufferConn{}
+	client := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+
+	client.handleCommand("/motd")
+	if out := conn.out.String(); !strings.Contains(out, noMOTD) {
+		t.Errorf("/motd wrote %q, want %q", out, noMOTD)
+	}
+
+	conn.out.Reset()
+	client.showHelp()
+	if strings.Contains(conn.out.String(), "/motd") {
+		t.Error("Expected /help to leave out /motd without a message of the day")
+	}
+}
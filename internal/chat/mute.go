@@ -0,0 +1,58 @@
+package chat
+
+import "fmt"
+
+// mutedPasses reports whether msg still reaches a muted client: server notices,
+// admin announcements and private messages are never held back
+func mutedPasses(msg Message) bool {
+	switch msg.Kind {
+	case KindSystem, KindAnnouncement, KindPrivate:
+		return true
+	}
+	return false
+}
+
+// suppressIfMuted counts and drops msg when the client is muted
+func (c *Client) suppressIfMuted(msg Message) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.muted || mutedPasses(msg) {
+		return false
+	}
+	c.missed++
+	return true
+}
+
+// mute pauses chat output to this client until unmute
+func (c *Client) mute() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.muted {
+		return "You are already muted. Use /unmute to resume."
+	}
+	c.muted = true
+	c.missed = 0
+	return "Muted. Incoming chat is paused until you /unmute."
+}
+
+// unmute resumes output and summarizes what was missed
+func (c *Client) unmute() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.muted {
+		return "You are not muted."
+	}
+	c.muted = false
+
+	switch c.missed {
+	case 0:
+		return "Unmuted. You didn't miss any messages."
+	case 1:
+		return "Unmuted. You missed 1 message."
+	default:
+		return fmt.Sprintf("Unmuted. You missed %d messages.", c.missed)
+	}
+}
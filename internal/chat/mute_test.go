@@ -0,0 +1,62 @@
+package chat
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMuteMissedSummary(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	server, remote := net.Pipe()
+	defer remote.Close()
+
+	client := &Client{
+		Nickname:  "alice",
+		conn:      server,
+		writer:    bufio.NewWriter(server),
+		room:      room,
+		plainText: true,
+	}
+
+	received := make(chan string, 10)
+	go func() {
+		scanner := bufio.NewScanner(remote)
+		for scanner.Scan() {
+			received <- scanner.Text()
+		}
+	}()
+
+	client.mute()
+	client.Send(Message{Kind: KindUser, From: "bob", Content: "one", Timestamp: time.Now()})
+	client.Send(Message{Kind: KindAction, From: "bob", Content: "waves", Timestamp: time.Now()})
+	client.Send(Message{Kind: KindJoin, From: "System", Content: "carol has joined the room"})
+	client.Send(Message{Kind: KindSystem, From: "System", Content: "Error: still delivered"})
+
+	select {
+	case line := <-received:
+		if !strings.Contains(line, "still delivered") {
+			t.Errorf("Expected only the system notice while muted, got %q", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("System notice was not delivered while muted")
+	}
+
+	if got, want := client.unmute(), "Unmuted. You missed 3 messages."; got != want {
+		t.Errorf("unmute() = %q, want %q", got, want)
+	}
+
+	client.Send(Message{Kind: KindUser, From: "bob", Content: "back", Timestamp: time.Now()})
+	select {
+	case line := <-received:
+		if !strings.Contains(line, "bob: back") {
+			t.Errorf("Expected chat after unmute, got %q", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Chat was not delivered after unmute")
+	}
+}
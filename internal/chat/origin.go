@@ -0,0 +1,57 @@
+package chat
+
+import "strings"
+
+// Origin says where a message came from, so content injected by a bridge or
+// bot can't pass for a user connected to this server
+type Origin int
+
+const (
+	OriginLocal  Origin = iota // a user connected to a chat-tails server
+	OriginBridge               // relayed from another network such as IRC
+	OriginBot                  // produced by an automated account
+)
+
+var originNames = map[Origin]string{
+	OriginLocal:  "local",
+	OriginBridge: "bridge",
+	OriginBot:    "bot",
+}
+
+func originByName(name string) (Origin, bool) {
+	for origin, originName := range originNames {
+		if originName == name {
+			return origin, true
+		}
+	}
+	return 0, false
+}
+
+func (o Origin) String() string {
+	if name, ok := originNames[o]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// badge is shown before the sender of a non-local message, e.g. "[bridge]".
+// Local messages have none.
+func (o Origin) badge() string {
+	if o == OriginLocal {
+		return ""
+	}
+	return "[" + o.String() + "]"
+}
+
+// reservedOrigin reports the origin whose nickname prefix, such as "bridge-"
+// or "bot_", nickname starts with. Those prefixes are left to bridges and bots.
+func reservedOrigin(nickname string) (Origin, bool) {
+	lower := strings.ToLower(nickname)
+	for _, origin := range []Origin{OriginBridge, OriginBot} {
+		name := origin.String()
+		if strings.HasPrefix(lower, name+"-") || strings.HasPrefix(lower, name+"_") {
+			return origin, true
+		}
+	}
+	return 0, false
+}
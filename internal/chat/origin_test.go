@@ -0,0 +1,85 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/bus"
+	"github.com/bscott/ts-chat/internal/ui"
+)
+
+func TestFormatMessageOriginBadge(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC)
+	label := messageLabel(Message{ID: 7, Timestamp: ts})
+	var theme ui.Theme
+
+	bridged := Message{ID: 7, Kind: KindUser, From: "alice", Content: "hi", Timestamp: ts, Origin: OriginBridge}
+	if got, want := formatMessage(bridged, renderOptions{plain: true}), ui.FormatUserMessagePlain("[bridge] alice", "hi", label); got != want {
+		t.Errorf("Plain bridged message = %q, want %q", got, want)
+	}
+	if got, want := formatMessage(bridged, renderOptions{}), theme.FormatUserMessage("[bridge] alice", "hi", label); got != want {
+		t.Errorf("ANSI bridged message = %q, want %q", got, want)
+	}
+
+	bot := Message{Kind: KindAction, From: "deploybot", Content: "shipped v2", Timestamp: ts, Origin: OriginBot}
+	if got, want := formatMessage(bot, renderOptions{plain: true}), theme.FormatActionMessagePlain("[bot] deploybot", "shipped v2"); got != want {
+		t.Errorf("Plain bot action = %q, want %q", got, want)
+	}
+
+	local := Message{ID: 7, Kind: KindUser, From: "alice", Content: "hi", Timestamp: ts}
+	if got, want := formatMessage(local, renderOptions{plain: true}), ui.FormatUserMessagePlain("alice", "hi", label); got != want {
+		t.Errorf("Plain local message = %q, want %q", got, want)
+	}
+}
+
+func TestReservedOriginNicknames(t *testing.T) {
+	for _, nickname := range []string{"bridge-alice", "Bridge_alice", "bot-deploy", "BOT_x"} {
+		err := validateNickname(nickname, 2, 20)
+		if err == nil || !strings.Contains(err.Error(), "reserved") {
+			t.Errorf("validateNickname(%q) error = %v, want a reserved-prefix error", nickname, err)
+		}
+	}
+	for _, nickname := range []string{"bridget", "bottle", "robot-1", "bridge"} {
+		if err := validateNickname(nickname, 2, 20); err != nil {
+			t.Errorf("validateNickname(%q) error = %v, want nil", nickname, err)
+		}
+	}
+}
+
+func TestRelayKeepsOrigin(t *testing.T) {
+	b := bus.NewMemory()
+	defer b.Close()
+
+	east := NewRoom("Lobby", 5, false, 0, true)
+	defer east.Stop()
+	west := NewRoom("Lobby", 5, false, 0, true)
+	defer west.Stop()
+	for origin, room := range map[string]*Room{"east": east, "west": west} {
+		if err := room.AttachBus(b, origin, time.Hour); err != nil {
+			t.Fatalf("AttachBus(%s) error = %v", origin, err)
+		}
+	}
+
+	westEvents, unsubscribe := west.Subscribe()
+	defer unsubscribe()
+
+	east.Broadcast(Message{From: "alice", Content: "from irc", Timestamp: time.Now(), Origin: OriginBridge})
+	east.Broadcast(Message{From: "bob", Content: "native", Timestamp: time.Now()})
+
+	for _, want := range []Origin{OriginBridge, OriginLocal} {
+		select {
+		case msg := <-westEvents:
+			if msg.Origin != want {
+				t.Errorf("Relayed %q has origin %s, want %s", msg.Content, msg.Origin, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for a %s message on west", want)
+		}
+	}
+
+	// A source this build doesn't know is never trusted as local
+	if got := relayedOrigin("matrix"); got != OriginBridge {
+		t.Errorf("relayedOrigin(unknown) = %s, want bridge", got)
+	}
+}
@@ -0,0 +1,256 @@
+package chat
+
+import (
+	"sync"
+	"time"
+)
+
+// Priority orders delivery to a line-mode client; higher priorities are
+// written before any queued lower-priority messages
+type Priority int
+
+const (
+	PriorityNormal Priority = iota // chat, actions and presence notices
+	PriorityHigh                   // moderation notices, announcements and errors
+)
+
+// priority is the delivery priority of m. Announcements and server notices
+// are always high so moderation stays responsive while the room is flooded.
+func (m Message) priority() Priority {
+	if m.Kind == KindAnnouncement || m.Kind == KindSystem {
+		return PriorityHigh
+	}
+	return m.Priority
+}
+
+// outbox queues messages for a line-mode client's single writer goroutine, so
+// a backlog of chat can't hold up a high-priority notice
+type outbox struct {
+	mu     sync.Mutex
+	high   []outboxItem
+	normal []outboxItem
+	closed bool
+	wake   chan struct{} // signalled when a message is pushed or the outbox closes
+}
+
+// outboxItem is a queued message, a flush marker when done is set, or a
+// keepalive when heartbeat is set
+type outboxItem struct {
+	msg       Message
+	done      chan struct{} // closed once everything queued before the marker is written
+	heartbeat bool
+}
+
+func newOutbox() *outbox {
+	return &outbox{wake: make(chan struct{}, 1)}
+}
+
+// push queues msg by priority; it is a no-op once the outbox is closed
+func (o *outbox) push(msg Message) {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return
+	}
+	if msg.priority() >= PriorityHigh {
+		o.high = append(o.high, outboxItem{msg: msg})
+	} else {
+		o.normal = append(o.normal, outboxItem{msg: msg})
+	}
+	o.mu.Unlock()
+	o.signal()
+}
+
+// pushFlush queues a marker behind everything already pushed and returns a
+// channel closed once the writer reaches it or the outbox closes. The high
+// lane always drains first, so the end of the normal lane is behind both.
+func (o *outbox) pushFlush() <-chan struct{} {
+	done := make(chan struct{})
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		close(done)
+		return done
+	}
+	o.normal = append(o.normal, outboxItem{done: done})
+	o.mu.Unlock()
+	o.signal()
+	return done
+}
+
+// pushHeartbeat queues a keepalive behind the messages already pushed; it is
+// a no-op once the outbox is closed
+func (o *outbox) pushHeartbeat() {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return
+	}
+	o.normal = append(o.normal, outboxItem{heartbeat: true})
+	o.mu.Unlock()
+	o.signal()
+}
+
+// pop blocks until an item is queued, returning high-priority messages
+// first. It returns false once the outbox is closed.
+func (o *outbox) pop() (outboxItem, bool) {
+	for {
+		o.mu.Lock()
+		if o.closed {
+			o.mu.Unlock()
+			return outboxItem{}, false
+		}
+		if len(o.high) > 0 {
+			item := o.high[0]
+			o.high = o.high[1:]
+			o.mu.Unlock()
+			return item, true
+		}
+		if len(o.normal) > 0 {
+			item := o.normal[0]
+			o.normal = o.normal[1:]
+			o.mu.Unlock()
+			return item, true
+		}
+		o.mu.Unlock()
+		<-o.wake
+	}
+}
+
+// close discards queued messages, releases pending flushes and stops pop
+func (o *outbox) close() {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return
+	}
+	o.closed = true
+	for _, item := range o.normal {
+		if item.done != nil {
+			close(item.done)
+		}
+	}
+	o.high, o.normal = nil, nil
+	o.mu.Unlock()
+	o.signal()
+}
+
+func (o *outbox) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue hands msg to the client's writer goroutine, starting it on first use
+func (c *Client) enqueue(msg Message) {
+	c.outboxOnce.Do(func() {
+		c.outbox = newOutbox()
+		go c.drainOutbox()
+	})
+	c.outbox.push(msg)
+}
+
+// drainOutbox writes queued messages until the client disconnects
+func (c *Client) drainOutbox() {
+	for {
+		item, ok := c.outbox.pop()
+		if !ok {
+			return
+		}
+		if item.done != nil {
+			close(item.done)
+			continue
+		}
+		if item.heartbeat {
+			c.writeHeartbeat()
+			continue
+		}
+		// Keep draining after a failed write so flush never waits on a writer
+		// that has gone; send has already dropped the connection
+		c.sendMessage(item.msg)
+	}
+}
+
+// flush waits until messages already passed to Send have been written. TUI
+// clients need no wait: program.Send returns once the model has the message.
+func (c *Client) flush() {
+	if c.program != nil {
+		return
+	}
+	c.outboxOnce.Do(func() {
+		c.outbox = newOutbox()
+		go c.drainOutbox()
+	})
+	<-c.outbox.pushFlush()
+}
+
+// closeOutbox stops the writer goroutine, if one was started
+func (c *Client) closeOutbox() {
+	c.outboxOnce.Do(func() { c.outbox = newOutbox() })
+	c.outbox.close()
+}
+
+// flushBatch is what the run loop hands Flush: the clients to flush and the
+// broadcast deliveries to wait for first
+type flushBatch struct {
+	clients []*Client
+	sending *sync.WaitGroup
+}
+
+// Flush waits until every line-mode client has written the messages broadcast
+// before the call, or until timeout passes. It reports whether every client
+// finished in time. A server calls it on shutdown so the goodbye it just
+// broadcast isn't lost when connections close.
+func (r *Room) Flush(timeout time.Duration) bool {
+	// Going through the run loop orders the flush after every earlier broadcast
+	reply := make(chan flushBatch, 1)
+	select {
+	case r.flushAll <- reply:
+	case <-r.ctx.Done():
+		return false
+	}
+	var batch flushBatch
+	select {
+	case batch = <-reply:
+	case <-r.ctx.Done():
+		return false
+	}
+
+	done := make(chan struct{})
+	go func() {
+		// Broadcasts are handed to clients asynchronously; queue them before flushing
+		batch.sending.Wait()
+		var wg sync.WaitGroup
+		for _, client := range batch.clients {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				client.flush()
+			}()
+		}
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// lineClients returns the joined clients that write through an outbox
+func (r *Room) lineClients() []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var clients []*Client
+	for _, client := range r.clients {
+		if client != nil && client.program == nil {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
@@ -0,0 +1,78 @@
+package chat
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutboxHighPriorityFirst(t *testing.T) {
+	o := newOutbox()
+	o.push(Message{From: "spammer", Content: "1"})
+	o.push(Message{From: "spammer", Content: "2"})
+	o.push(Message{From: "System", Content: "announcement", Kind: KindAnnouncement})
+	o.push(Message{From: "spammer", Content: "3"})
+	o.push(Message{From: "mod", Content: "urgent", Priority: PriorityHigh})
+
+	want := []string{"announcement", "urgent", "1", "2", "3"}
+	for _, w := range want {
+		item, ok := o.pop()
+		if !ok || item.msg.Content != w {
+			t.Fatalf("pop() = %q, %v; want %q", item.msg.Content, ok, w)
+		}
+	}
+
+	o.close()
+	if _, ok := o.pop(); ok {
+		t.Error("pop() succeeded on a closed outbox")
+	}
+}
+
+func TestSendDeliversQueuedMessages(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, true)
+	defer room.Stop()
+
+	server, clientEnd := net.Pipe()
+	defer clientEnd.Close()
+	client := &Client{conn: server, writer: bufio.NewWriter(server), room: room, plainText: true}
+	defer client.close()
+
+	client.Send(Message{From: "bob", Content: "hello", Kind: KindUser})
+	client.Send(Message{From: "System", Content: "heads up", Kind: KindSystem})
+
+	clientEnd.SetReadDeadline(time.Now().Add(2 * time.Second))
+	r := bufio.NewReader(clientEnd)
+	var got []string
+	for len(got) < 2 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("ReadString() error = %v (got %q)", err, got)
+		}
+		got = append(got, line)
+	}
+	joined := strings.Join(got, "")
+	if !strings.Contains(joined, "hello") || !strings.Contains(joined, "heads up") {
+		t.Errorf("Delivered %q, want both messages", joined)
+	}
+}
+
+func TestFlushWithoutConnection(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	client := &Client{Nickname: "alice", room: room, plainText: true}
+	client.Send(Message{From: "bob", Content: "hi"})
+
+	done := make(chan struct{})
+	go func() {
+		client.flush()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("flush blocked on a client without a connection")
+	}
+}
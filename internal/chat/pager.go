@@ -0,0 +1,50 @@
+package chat
+
+import "fmt"
+
+// DefaultPageSize is how many lines a page holds when the client's terminal
+// height is unknown
+const DefaultPageSize = 20
+
+// pageSize returns the number of lines to show per page, leaving room for the
+// "more" hint and the prompt
+func (c *Client) pageSize() int {
+	if c.termHeight > 2 {
+		return c.termHeight - 2
+	}
+	return DefaultPageSize
+}
+
+// page shows the first page of lines and keeps the rest for /more
+func (c *Client) page(lines []string) error {
+	c.pagerBuffer = lines
+	return c.more()
+}
+
+// more shows the next page of buffered output, with a hint when more remains
+func (c *Client) more() error {
+	if len(c.pagerBuffer) == 0 {
+		c.pagerBuffer = nil
+		c.sendSystemMessage("Nothing more to show.")
+		return nil
+	}
+
+	n := min(c.pageSize(), len(c.pagerBuffer))
+	chunk := c.pagerBuffer[:n]
+	c.pagerBuffer = c.pagerBuffer[n:]
+
+	out := ""
+	for _, line := range chunk {
+		out += line + c.newline()
+	}
+	if err := c.send(out); err != nil {
+		return err
+	}
+
+	if remaining := len(c.pagerBuffer); remaining > 0 {
+		c.sendSystemMessage(fmt.Sprintf("-- %d more lines, type /more to continue --", remaining))
+	} else {
+		c.pagerBuffer = nil
+	}
+	return nil
+}
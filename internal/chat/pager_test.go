@@ -0,0 +1,75 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// bufferConn is a net.Conn stand-in that records writes
+type bufferConn struct {
+	net.Conn
+	out bytes.Buffer
+}
+
+func (c *bufferConn) Write(p []byte) (int, error) {
+	return c.out.Write(p)
+}
+
+func TestPagerPagesThroughBuffer(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	conn := &bufferConn{}
+	client := &Client{conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+
+	var lines []string
+	for i := 1; i <= 45; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+
+	takeOutput := func() string {
+		out := conn.out.String()
+		conn.out.Reset()
+		return out
+	}
+
+	client.page(lines)
+	first := takeOutput()
+	if !strings.Contains(first, "line 20\r\n") || strings.Contains(first, "line 21\r\n") {
+		t.Fatalf("First page should end at line 20, got %q", first)
+	}
+	if !strings.Contains(first, "25 more lines") {
+		t.Errorf("Expected a /more hint, got %q", first)
+	}
+
+	client.more()
+	second := takeOutput()
+	if !strings.HasPrefix(second, "line 21\r\n") || !strings.Contains(second, "5 more lines") {
+		t.Errorf("Unexpected second page %q", second)
+	}
+
+	client.more()
+	last := takeOutput()
+	if !strings.Contains(last, "line 45\r\n") || strings.Contains(last, "more lines") {
+		t.Errorf("Unexpected last page %q", last)
+	}
+
+	client.more()
+	if got := takeOutput(); !strings.Contains(got, "Nothing more to show.") {
+		t.Errorf("Expected the empty-buffer notice, got %q", got)
+	}
+}
+
+func TestPagerUsesTerminalHeight(t *testing.T) {
+	client := &Client{termHeight: 10}
+	if got := client.pageSize(); got != 8 {
+		t.Errorf("pageSize() = %d, want 8", got)
+	}
+	if got := (&Client{}).pageSize(); got != DefaultPageSize {
+		t.Errorf("pageSize() without a height = %d, want %d", got, DefaultPageSize)
+	}
+}
@@ -0,0 +1,42 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPingPending is returned when /ping is used while a ping is still unanswered
+var ErrPingPending = errors.New("ping already outstanding")
+
+// startPing records the send time for /ping. The next line the client sends
+// answers it, so the result includes typing time and is only approximate.
+// Only one ping may be outstanding at a time.
+func (c *Client) startPing(now time.Time) (string, error) {
+	if !c.pingSent.IsZero() {
+		return "A ping is already outstanding; press Enter to answer it.", ErrPingPending
+	}
+	c.pingSent = now
+	return "Ping! Press Enter to measure the round trip.", nil
+}
+
+// answerPing completes an outstanding ping and reports the latency. It returns
+// false when no ping is pending.
+func (c *Client) answerPing(now time.Time) (string, bool) {
+	if c.pingSent.IsZero() {
+		return "", false
+	}
+	latency := pingLatency(c.pingSent, now)
+	c.pingSent = time.Time{}
+	return fmt.Sprintf("Pong: round trip ~%s (includes the time to press Enter)", latency), true
+}
+
+// pingLatency is the round trip between sending a ping and receiving the
+// answer, rounded to the millisecond and never negative
+func pingLatency(sent, received time.Time) time.Duration {
+	d := received.Sub(sent)
+	if d < 0 {
+		return 0
+	}
+	return d.Round(time.Millisecond)
+}
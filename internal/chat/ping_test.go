@@ -0,0 +1,75 @@
+package chat
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPingLatency(t *testing.T) {
+	sent := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		received time.Time
+		want     time.Duration
+	}{
+		{sent.Add(42 * time.Millisecond), 42 * time.Millisecond},
+		{sent.Add(1500 * time.Microsecond), 2 * time.Millisecond},
+		{sent, 0},
+		{sent.Add(-time.Second), 0},
+	}
+	for _, tt := range tests {
+		if got := pingLatency(sent, tt.received); got != tt.want {
+			t.Errorf("pingLatency(+%v) = %v, want %v", tt.received.Sub(sent), got, tt.want)
+		}
+	}
+}
+
+func TestPingOneOutstanding(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, false)
+	t.Cleanup(func() { room.Stop() })
+	client := NewTUIClient(nil, room)
+
+	if _, ok := client.answerPing(time.Now()); ok {
+		t.Fatal("answerPing succeeded with no ping pending")
+	}
+
+	sent := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := client.startPing(sent); err != nil {
+		t.Fatalf("startPing: %v", err)
+	}
+	if _, err := client.startPing(sent.Add(time.Second)); !errors.Is(err, ErrPingPending) {
+		t.Fatalf("second startPing err = %v, want ErrPingPending", err)
+	}
+
+	reply, ok := client.answerPing(sent.Add(250 * time.Millisecond))
+	if !ok || !strings.Contains(reply, "250ms") {
+		t.Fatalf("answerPing = %q, %v; want a 250ms round trip", reply, ok)
+	}
+	if _, ok := client.answerPing(sent.Add(time.Second)); ok {
+		t.Error("answerPing succeeded twice for one ping")
+	}
+	if _, err := client.startPing(sent.Add(time.Second)); err != nil {
+		t.Errorf("startPing after the answer: %v", err)
+	}
+}
+
+func TestPingCommandUsesRoomClock(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	clock := newFakeClock()
+	room.Clock = clock
+
+	conn := &bufferConn{}
+	client := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+	if err := client.handleCommand("/ping"); err != nil {
+		t.Fatalf("/ping error = %v", err)
+	}
+
+	clock.Advance(250 * time.Millisecond)
+	reply, ok := client.answerPing(clock.Now())
+	if !ok || !strings.Contains(reply, "250ms") {
+		t.Errorf("answerPing = %q, %v; want a 250ms round trip on the room clock", reply, ok)
+	}
+}
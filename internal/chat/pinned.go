@@ -0,0 +1,35 @@
+package chat
+
+import "strings"
+
+// SetPinned replaces the room's pinned messages. Each non-blank line becomes a
+// system message shown ahead of the history replay to every joining user. Pinned
+// messages live outside the history buffer, so they are never evicted and don't
+// count against its size.
+func (r *Room) SetPinned(lines []string) {
+	now := r.Clock.Now()
+	var pinned []Message
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		pinned = append(pinned, Message{
+			From:      "System",
+			Content:   line,
+			Timestamp: now,
+			Kind:      KindPinned,
+		})
+	}
+
+	r.historyMu.Lock()
+	r.pinned = pinned
+	r.historyMu.Unlock()
+}
+
+// Pinned returns the room's pinned messages in order
+func (r *Room) Pinned() []Message {
+	r.historyMu.RLock()
+	defer r.historyMu.RUnlock()
+	return append([]Message(nil), r.pinned...)
+}
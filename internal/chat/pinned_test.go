@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPinnedMessagesLeadReplay(t *testing.T) {
+	room := NewRoom("Test Room", 5, true, 2, true)
+	defer room.Stop()
+
+	room.SetPinned([]string{"Read the FAQ first", "", "  Support hours are 9-5  "})
+
+	// Fill the history well past its size so real messages get evicted
+	for i := 1; i <= 5; i++ {
+		room.addToHistory(Message{ID: uint64(i), From: "alice", Content: fmt.Sprintf("message %d", i), Timestamp: time.Now()})
+	}
+
+	replay := room.ReplayHistory()
+	var got []string
+	for _, msg := range replay {
+		got = append(got, msg.Content)
+	}
+	want := []string{"Read the FAQ first", "Support hours are 9-5", "message 4", "message 5"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("ReplayHistory() = %q, want %q", got, want)
+	}
+	if replay[0].Kind != KindPinned {
+		t.Errorf("pinned message kind = %v, want pinned", replay[0].Kind)
+	}
+	if n := len(room.GetHistory()); n != 2 {
+		t.Errorf("Expected pinned messages outside the history buffer, got %d stored", n)
+	}
+}
+
+func TestPinnedMessagesWithoutHistory(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	room.HistoryReplayCount = 0
+
+	room.SetPinned([]string{"Welcome to support"})
+	if replay := room.ReplayHistory(); len(replay) != 1 || replay[0].Content != "Welcome to support" {
+		t.Errorf("ReplayHistory() = %v, want just the pinned message", replay)
+	}
+}
+
+func TestFormatPinnedMessage(t *testing.T) {
+	msg := Message{From: "System", Content: "Read the FAQ first", Timestamp: time.Now(), Kind: KindPinned}
+	if got := formatMessage(msg, renderOptions{plain: true}); got != "[pinned] Read the FAQ first" {
+		t.Errorf("formatMessage(pinned) = %q", got)
+	}
+}
@@ -0,0 +1,82 @@
+package chat
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrProtocolProbe is returned when a connection opens with something other
+// than telnet input, such as an HTTP request from a port scanner
+var ErrProtocolProbe = errors.New("not a telnet client")
+
+// Protocol sniffing on the first bytes a client sends, before the banner
+const (
+	probeWait    = 250 * time.Millisecond
+	probePeekLen = 64
+)
+
+// httpMethods start the request line of HTTP probes
+var httpMethods = []string{"GET ", "POST ", "HEAD ", "PUT ", "DELETE ", "OPTIONS ", "PATCH ", "CONNECT "}
+
+// Replies sent to probes before the connection is closed
+const (
+	httpProbeReply = "HTTP/1.0 400 Bad Request\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"Connection: close\r\n" +
+		"\r\n" +
+		"This is a Chat Tails telnet server. Connect with a telnet client.\r\n"
+	binaryProbeReply = "This is a Chat Tails telnet server. Connect with a telnet client.\r\n"
+)
+
+// classifyProbe inspects the first bytes a client sent and returns the reply
+// for a protocol probe, or false for telnet input. Telnet negotiation, escape
+// sequences and line editing keys are allowed; other control bytes are taken
+// as binary garbage.
+func classifyProbe(peek []byte) (string, bool) {
+	if len(peek) == 0 || peek[0] == telnetIAC {
+		return "", false
+	}
+
+	for _, method := range httpMethods {
+		if strings.HasPrefix(string(peek), method) {
+			return httpProbeReply, true
+		}
+	}
+
+	for i, b := range peek {
+		switch {
+		case b == '\r', b == '\n', b == '\t', b == '\b', b == 0x1b, b == 0x7f, b >= 0x20:
+		case b == 0 && i > 0 && peek[i-1] == '\r': // telnet CR NUL
+		default:
+			return binaryProbeReply, true
+		}
+	}
+	return "", false
+}
+
+// rejectProbe waits briefly for the client's first bytes and, if they look
+// like HTTP or binary data, sends a short notice and returns ErrProtocolProbe.
+// Interactive users who send nothing fall through to the nickname prompt, and
+// the peeked input stays buffered for it.
+func (c *Client) rejectProbe() error {
+	conn, ok := c.conn.(interface{ SetReadDeadline(time.Time) error })
+	if !ok {
+		return nil
+	}
+
+	conn.SetReadDeadline(time.Now().Add(probeWait))
+	defer conn.SetReadDeadline(time.Time{}) // Clear deadline
+
+	if _, err := c.reader.Peek(1); err != nil {
+		return nil
+	}
+	peek, _ := c.reader.Peek(min(c.reader.Buffered(), probePeekLen))
+
+	reply, probe := classifyProbe(peek)
+	if !probe {
+		return nil
+	}
+	c.send(reply)
+	return ErrProtocolProbe
+}
@@ -0,0 +1,77 @@
+package chat
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestClassifyProbe(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"nickname", "alice\r\n", ""},
+		{"nick handshake", "NICK alice\n", ""},
+		{"telnet negotiation", "\xff\xfd\x03\xff\xfb\x18", ""},
+		{"backspace and arrows", "al\bice\x1b[A\x7f\r\x00", ""},
+		{"http get", "GET / HTTP/1.1\r\nHost: chat\r\n\r\n", httpProbeReply},
+		{"http post", "POST /api HTTP/1.1\r\n", httpProbeReply},
+		{"tls hello", "\x16\x03\x01\x02\x00\x01\x00\x01\xfc\x03\x03", binaryProbeReply},
+		{"random bytes", "\x8f\x02\xd1\x7a\x00\x13\xee\x05", binaryProbeReply},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, probe := classifyProbe([]byte(tt.input))
+			if got != tt.want || probe != (tt.want != "") {
+				t.Errorf("classifyProbe(%q) = %q, %v; want %q", tt.input, got, probe, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPlainTextClientRejectsProbes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"http request", "GET / HTTP/1.1\r\nHost: chat\r\n\r\n", "HTTP/1.0 400 Bad Request"},
+		{"random bytes", "\x16\x03\x01\x00\xa5\x01\x00\x00\xa1\x03\x03", "telnet server"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := NewRoom("Test Room", 5, false, 0, true)
+			defer room.Stop()
+
+			server, remote := net.Pipe()
+			defer remote.Close()
+			go remote.Write([]byte(tt.input))
+
+			out := make(chan string, 1)
+			go func() {
+				reply, _ := io.ReadAll(remote)
+				out <- string(reply)
+			}()
+
+			_, err := NewPlainTextClient(server, room, ClientOptions{PlainText: true})
+			if !errors.Is(err, ErrProtocolProbe) {
+				t.Fatalf("Expected ErrProtocolProbe, got %v", err)
+			}
+
+			reply := <-out
+			if !strings.Contains(reply, tt.want) {
+				t.Errorf("Expected reply containing %q, got %q", tt.want, reply)
+			}
+			if strings.Contains(reply, "Welcome to Chat Tails") || strings.Contains(reply, "nickname") {
+				t.Errorf("Expected no banner or nickname prompt, got %q", reply)
+			}
+			assertRoomEmpty(t, room)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// quietHoursCheckInterval is how often the room looks for quiet hours starting or ending
+const quietHoursCheckInterval = 30 * time.Second
+
+// QuietHours is a daily window during which only admins may post
+type QuietHours struct {
+	Start    time.Duration // offset from midnight when quiet hours begin
+	End      time.Duration // offset from midnight when they end; before Start crosses midnight
+	Location *time.Location
+}
+
+// ParseQuietHours parses a window such as "22:00-07:00" in the named time zone
+// (empty for the server's local zone)
+func ParseQuietHours(spec, timezone string) (*QuietHours, error) {
+	startStr, endStr, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid quiet hours %q (use HH:MM-HH:MM)", spec)
+	}
+
+	start, err := parseClock(startStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours start: %w", err)
+	}
+	end, err := parseClock(endStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours end: %w", err)
+	}
+	if start == end {
+		return nil, fmt.Errorf("quiet hours %q start and end at the same time", spec)
+	}
+
+	loc := time.Local
+	if timezone != "" {
+		loc, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quiet hours time zone: %w", err)
+		}
+	}
+
+	return &QuietHours{Start: start, End: end, Location: loc}, nil
+}
+
+// parseClock converts "HH:MM" into an offset from midnight
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Active reports whether t falls inside the quiet window
+func (q *QuietHours) Active(t time.Time) bool {
+	t = t.In(q.Location)
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+
+	if q.Start < q.End {
+		return offset >= q.Start && offset < q.End
+	}
+	// The window crosses midnight
+	return offset >= q.Start || offset < q.End
+}
+
+// String formats the window as "HH:MM-HH:MM"
+func (q *QuietHours) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return clock(q.Start) + "-" + clock(q.End)
+}
+
+// SetQuietHours enforces q until the room stops, announcing when quiet hours
+// begin and end. Nil disables quiet hours.
+func (r *Room) SetQuietHours(q *QuietHours) {
+	r.quietHours = q
+	if q != nil {
+		go r.quietHoursLoop(q, q.Active(r.Clock.Now()), quietHoursCheckInterval)
+	}
+}
+
+// quietHoursLoop broadcasts a notice whenever quiet hours start or end,
+// starting from whether they were active when set. The ticker only paces the
+// checks; the time comes from the room clock.
+func (r *Room) quietHoursLoop(q *QuietHours, active bool, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			now := r.Clock.Now()
+			if q.Active(now) == active {
+				continue
+			}
+			active = !active
+			r.Broadcast(quietHoursNotice(q, active, now))
+		}
+	}
+}
+
+// quietHoursNotice is the system message sent when quiet hours begin or end
+func quietHoursNotice(q *QuietHours, active bool, now time.Time) Message {
+	content := fmt.Sprintf("Quiet hours (%s) have started; only admins can post until they end.", q)
+	if !active {
+		content = "Quiet hours are over; everyone can post again."
+	}
+	return Message{From: "System", Content: content, Timestamp: now, Kind: KindSystem}
+}
+
+// admitDuringQuietHours rejects chat from non-admin members while quiet hours
+// are active, telling the sender why. System messages and messages from
+// senders who aren't room members (such as the embedding program) pass.
+func (r *Room) admitDuringQuietHours(msg Message, now time.Time) bool {
+	if r.quietHours == nil || msg.IsSystem() || !r.quietHours.Active(now) {
+		return true
+	}
+
+	client := r.GetClient(msg.From)
+	if client == nil || client.isAdmin() {
+		return true
+	}
+
+	r.sendTo(client, Message{
+		From:      "System",
+		Content:   fmt.Sprintf("Shh, it's quiet hours (%s). Only admins can post right now.", r.quietHours),
+		Timestamp: now,
+		Kind:      KindSystem,
+	})
+	return false
+}
@@ -0,0 +1,130 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHoursActive(t *testing.T) {
+	day := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		at   time.Time
+		want bool
+	}{
+		{"same-day inside", "13:00-14:30", day(13, 45), true},
+		{"same-day at start", "13:00-14:30", day(13, 0), true},
+		{"same-day at end", "13:00-14:30", day(14, 30), false},
+		{"same-day outside", "13:00-14:30", day(9, 0), false},
+		{"overnight before midnight", "22:00-07:00", day(23, 30), true},
+		{"overnight after midnight", "22:00-07:00", day(3, 0), true},
+		{"overnight at end", "22:00-07:00", day(7, 0), false},
+		{"overnight daytime", "22:00-07:00", day(12, 0), false},
+		{"overnight just before start", "22:00-07:00", day(21, 59), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuietHours(tt.spec, "UTC")
+			if err != nil {
+				t.Fatalf("ParseQuietHours() error = %v", err)
+			}
+			if got := q.Active(tt.at); got != tt.want {
+				t.Errorf("Active(%s) = %v, want %v", tt.at.Format("15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuietHoursTimezone(t *testing.T) {
+	q, err := ParseQuietHours("22:00-07:00", "Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	// 14:00 UTC is 23:00 in Tokyo
+	if !q.Active(time.Date(2024, 3, 1, 14, 0, 0, 0, time.UTC)) {
+		t.Error("Expected quiet hours to follow the configured time zone")
+	}
+}
+
+func TestParseQuietHoursErrors(t *testing.T) {
+	for _, spec := range []string{"", "22:00", "25:00-07:00", "22:00-7pm", "08:00-08:00"} {
+		if _, err := ParseQuietHours(spec, ""); err == nil {
+			t.Errorf("ParseQuietHours(%q) should fail", spec)
+		}
+	}
+	if _, err := ParseQuietHours("22:00-07:00", "Nowhere/Special"); err == nil {
+		t.Error("Expected an error for an unknown time zone")
+	}
+}
+
+func TestQuietHoursBlocksNonAdmins(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, false)
+	defer room.Stop()
+
+	q, err := ParseQuietHours("22:00-07:00", "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	room.quietHours = q
+	night := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	noon := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	user := NewTUIClient(nil, room)
+	user.Nickname = "bob"
+	admin := NewTUIClient(nil, room)
+	admin.Nickname = "alice"
+	admin.admin = true
+	room.Join(user)
+	room.Join(admin)
+
+	if room.admitDuringQuietHours(Message{From: "bob", Content: "hi"}, night) {
+		t.Error("Expected a non-admin message to be rejected during quiet hours")
+	}
+	if !room.admitDuringQuietHours(Message{From: "alice", Content: "hi"}, night) {
+		t.Error("Expected an admin message to pass during quiet hours")
+	}
+	if !room.admitDuringQuietHours(Message{From: "System", Content: "notice", Kind: KindSystem}, night) {
+		t.Error("Expected system messages to pass during quiet hours")
+	}
+	if !room.admitDuringQuietHours(Message{From: "bob", Content: "hi"}, noon) {
+		t.Error("Expected messages to pass outside quiet hours")
+	}
+}
+
+func TestQuietHoursLoopUsesRoomClock(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, false)
+	defer room.Stop()
+	clock := newFakeClock() // 12:00 UTC
+	room.Clock = clock
+
+	q, err := ParseQuietHours("12:30-13:00", "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, unsubscribe := room.Subscribe()
+	defer unsubscribe()
+	go room.quietHoursLoop(q, q.Active(clock.Now()), time.Millisecond)
+
+	for _, step := range []struct {
+		advance time.Duration
+		want    string
+	}{
+		{45 * time.Minute, "Quiet hours (12:30-13:00) have started; only admins can post until they end."},
+		{30 * time.Minute, "Quiet hours are over; everyone can post again."},
+	} {
+		clock.Advance(step.advance)
+		select {
+		case msg := <-msgs:
+			if msg.Content != step.want || !msg.Timestamp.Equal(clock.Now()) {
+				t.Errorf("Got %q at %v, want %q at the room clock's %v", msg.Content, msg.Timestamp, step.want, clock.Now())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("No notice after advancing the room clock to %v", clock.Now())
+		}
+	}
+}
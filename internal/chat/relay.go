@@ -0,0 +1,260 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/bus"
+)
+
+// DefaultPresenceInterval is how often a room on a bus announces its local users
+const DefaultPresenceInterval = 10 * time.Second
+
+// presenceExpiry is how many missed announcements drop another process's users
+const presenceExpiry = 3
+
+// relayQueue is how many outgoing events may wait for a slow bus before new ones are dropped
+const relayQueue = 256
+
+// Event types on the bus
+const (
+	relayMessage  = "message"
+	relayPresence = "presence"
+)
+
+// relayedKinds are the message kinds shared with other processes. Command
+// output, private messages and room-local notices such as the busy warning
+// stay on the process that produced them.
+var relayedKinds = map[MessageKind]bool{
+	KindUser:         true,
+	KindAction:       true,
+	KindJoin:         true,
+	KindLeave:        true,
+	KindPresence:     true,
+	KindAnnouncement: true,
+}
+
+// relayEvent is the JSON form of a room event on the bus
+type relayEvent struct {
+	Origin     string    `json:"origin"`
+	Type       string    `json:"type"`
+	Kind       string    `json:"kind,omitempty"`
+	From       string    `json:"from,omitempty"`
+	Content    string    `json:"content,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	ReplyTo    uint64    `json:"reply_to,omitempty"`
+	ReplyQuote string    `json:"reply_quote,omitempty"`
+	Source     string    `json:"source,omitempty"` // message Origin, omitted for local
+	Users      []string  `json:"users,omitempty"`
+}
+
+// relay links a room to a bus shared with other processes
+type relay struct {
+	bus         bus.MessageBus
+	origin      string // identifies this process, so its own events are skipped
+	topic       string
+	interval    time.Duration
+	outgoing    chan relayEvent
+	unsubscribe func()
+
+	mu    sync.Mutex
+	peers map[string]peerPresence // origin -> users last announced by that process
+}
+
+// peerPresence is another process's most recent user list
+type peerPresence struct {
+	users []string
+	seen  time.Time
+}
+
+// AttachBus shares the room with other processes on b: local chat, actions,
+// join/leave notices and announcements are published, those from other
+// processes are delivered here, and their users are listed by /who. Each
+// process announces its users every interval. origin must be unique to this
+// process. Call it before any client joins.
+func (r *Room) AttachBus(b bus.MessageBus, origin string, interval time.Duration) error {
+	topic := "chat-tails/room/" + r.Name
+	events, unsubscribe, err := b.Subscribe(topic)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to room %s: %w", r.Name, err)
+	}
+
+	r.relay = &relay{
+		bus:         b,
+		origin:      origin,
+		topic:       topic,
+		interval:    interval,
+		outgoing:    make(chan relayEvent, relayQueue),
+		unsubscribe: unsubscribe,
+		peers:       make(map[string]peerPresence),
+	}
+
+	go r.receiveRelayed(events)
+	go r.sendRelayed()
+	return nil
+}
+
+// relayMessageOut queues a locally broadcast message for other processes
+func (r *Room) relayMessageOut(msg Message) {
+	if r.relay == nil || msg.remote || !relayedKinds[msg.Kind] {
+		return
+	}
+
+	event := relayEvent{
+		Type:       relayMessage,
+		Kind:       msg.Kind.String(),
+		From:       msg.From,
+		Content:    msg.Content,
+		Timestamp:  msg.Timestamp,
+		ReplyTo:    msg.ReplyTo,
+		ReplyQuote: msg.ReplyQuote,
+	}
+	if msg.Origin != OriginLocal {
+		event.Source = msg.Origin.String()
+	}
+	select {
+	case r.relay.outgoing <- event:
+	default:
+		log.Printf("Bus is falling behind; message from %s not shared", msg.From)
+	}
+}
+
+// sendRelayed publishes queued events and the periodic presence announcement
+// until the room stops, then announces that this process has no users left
+func (r *Room) sendRelayed() {
+	defer r.relay.unsubscribe()
+
+	ticker := time.NewTicker(r.relay.interval)
+	defer ticker.Stop()
+
+	r.publishRelayed(relayEvent{Type: relayPresence, Users: r.localUserList()})
+	for {
+		select {
+		case <-r.ctx.Done():
+			r.publishRelayed(relayEvent{Type: relayPresence})
+			return
+		case event := <-r.relay.outgoing:
+			r.publishRelayed(event)
+		case <-ticker.C:
+			r.publishRelayed(relayEvent{Type: relayPresence, Users: r.localUserList()})
+		}
+	}
+}
+
+// publishRelayed stamps event with this process's origin and publishes it
+func (r *Room) publishRelayed(event relayEvent) {
+	event.Origin = r.relay.origin
+	if event.Timestamp.IsZero() {
+		event.Timestamp = r.Clock.Now()
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode bus event: %v", err)
+		return
+	}
+	if err := r.relay.bus.Publish(r.relay.topic, payload); err != nil {
+		log.Printf("Failed to publish to bus: %v", err)
+	}
+}
+
+// receiveRelayed applies events from other processes until the subscription closes
+func (r *Room) receiveRelayed(events <-chan []byte) {
+	for payload := range events {
+		var event relayEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			log.Printf("Ignoring malformed bus event: %v", err)
+			continue
+		}
+		if event.Origin == r.relay.origin {
+			continue
+		}
+
+		switch event.Type {
+		case relayMessage:
+			kind, ok := kindByName(event.Kind)
+			if !ok || !relayedKinds[kind] {
+				continue
+			}
+			msg := Message{
+				Kind:       kind,
+				From:       event.From,
+				Content:    event.Content,
+				Timestamp:  event.Timestamp,
+				ReplyTo:    event.ReplyTo,
+				ReplyQuote: event.ReplyQuote,
+				Origin:     relayedOrigin(event.Source),
+				remote:     true,
+			}
+			select {
+			case r.remote <- msg:
+			case <-r.ctx.Done():
+				return
+			}
+		case relayPresence:
+			r.relay.setPeer(event.Origin, event.Users, r.Clock.Now())
+		}
+	}
+}
+
+// relayedOrigin maps an event's source back to an Origin. A source this
+// process doesn't know is treated as a bridge rather than trusted as local.
+func relayedOrigin(source string) Origin {
+	if source == "" {
+		return OriginLocal
+	}
+	if origin, ok := originByName(source); ok {
+		return origin
+	}
+	return OriginBridge
+}
+
+// setPeer records the users another process announced; an empty list means
+// it has none, so it is forgotten
+func (rl *relay) setPeer(origin string, users []string, now time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if len(users) == 0 {
+		delete(rl.peers, origin)
+		return
+	}
+	rl.peers[origin] = peerPresence{users: users, seen: now}
+}
+
+// peerUsers returns the users of other processes that have announced recently
+func (rl *relay) peerUsers(now time.Time) []string {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	var users []string
+	for origin, peer := range rl.peers {
+		if now.Sub(peer.seen) > presenceExpiry*rl.interval {
+			delete(rl.peers, origin)
+			continue
+		}
+		users = append(users, peer.users...)
+	}
+	return users
+}
+
+// remoteUsers returns the users connected to other processes sharing the room
+func (r *Room) remoteUsers() []string {
+	if r.relay == nil {
+		return nil
+	}
+	return r.relay.peerUsers(r.Clock.Now())
+}
+
+// isRemoteUser reports whether nickname is in use on another process
+func (r *Room) isRemoteUser(nickname string) bool {
+	for _, user := range r.remoteUsers() {
+		if r.sameNick(user, nickname) {
+			return true
+		}
+	}
+	return false
+}
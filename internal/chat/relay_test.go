@@ -0,0 +1,115 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/bus"
+)
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRelayBetweenRooms(t *testing.T) {
+	b := bus.NewMemory()
+	defer b.Close()
+
+	east := NewRoom("Lobby", 5, true, 10, false) // stopped below
+	west := NewRoom("Lobby", 5, true, 10, false)
+	defer west.Stop()
+
+	for origin, room := range map[string]*Room{"east": east, "west": west} {
+		if err := room.AttachBus(b, origin, 20*time.Millisecond); err != nil {
+			t.Fatalf("AttachBus(%s) error = %v", origin, err)
+		}
+	}
+
+	eastEvents, unsubscribeEast := east.Subscribe()
+	defer unsubscribeEast()
+	westEvents, unsubscribeWest := west.Subscribe()
+	defer unsubscribeWest()
+
+	alice := NewTUIClient(nil, east)
+	alice.Nickname = "alice"
+	east.ReserveNickname("alice")
+	east.Join(alice)
+
+	// Presence: west lists alice and won't hand out her nickname
+	waitFor(t, "alice to appear on west", func() bool {
+		users := west.GetUserList()
+		return len(users) == 1 && users[0] == "alice"
+	})
+	if west.ReserveNickname("alice") {
+		t.Error("Expected a nickname in use on another server to be refused")
+	}
+
+	east.Broadcast(Message{From: "alice", Content: "hello west", Timestamp: time.Now()})
+
+	var got []Message
+	timeout := time.After(time.Second)
+	for len(got) < 2 {
+		select {
+		case msg := <-westEvents:
+			got = append(got, msg)
+		case <-timeout:
+			t.Fatalf("Expected the join notice and message on west, got %v", got)
+		}
+	}
+	if got[0].Kind != KindJoin || got[1].Content != "hello west" || got[1].From != "alice" {
+		t.Errorf("Unexpected relayed messages: %v", got)
+	}
+
+	// Nothing echoes back to east beyond its own two messages
+	for i := 0; i < 2; i++ {
+		<-eastEvents
+	}
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case msg := <-eastEvents:
+		t.Errorf("Expected no echo on east, got %v", msg)
+	default:
+	}
+
+	// Stopping east withdraws its users
+	east.Stop()
+	waitFor(t, "alice to leave west's list", func() bool {
+		return len(west.GetUserList()) == 0
+	})
+}
+
+func TestRelaySkipsLocalKinds(t *testing.T) {
+	b := bus.NewMemory()
+	defer b.Close()
+
+	room := NewRoom("Lobby", 5, false, 0, false)
+	defer room.Stop()
+	if err := room.AttachBus(b, "east", time.Hour); err != nil {
+		t.Fatalf("AttachBus() error = %v", err)
+	}
+
+	events, unsubscribe, _ := b.Subscribe("chat-tails/room/Lobby")
+	defer unsubscribe()
+	<-events // initial presence announcement
+
+	room.Broadcast(Message{From: "System", Content: busyNotice, Timestamp: time.Now(), Kind: KindSystem})
+	room.Broadcast(Message{From: "alice", Content: "shared", Timestamp: time.Now()})
+
+	select {
+	case payload := <-events:
+		if want := `"content":"shared"`; !strings.Contains(string(payload), want) {
+			t.Errorf("Expected only the chat message to be published, got %s", payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the chat message to be published")
+	}
+}
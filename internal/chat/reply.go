@@ -0,0 +1,45 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// messageLabel returns the bracketed label shown before a message: its time and,
+// once assigned, the ID that /reply can refer to
+func messageLabel(msg Message) string {
+	label := msg.Timestamp.Format("15:04:05")
+	if msg.ID != 0 {
+		label += fmt.Sprintf(" #%d", msg.ID)
+	}
+	return label
+}
+
+// buildReply parses "/reply <id> <text>" arguments into a message quoting the referenced one
+func buildReply(room *Room, from, args string) (Message, error) {
+	idStr, text, _ := strings.Cut(strings.TrimSpace(args), " ")
+	text = strings.TrimSpace(text)
+	if idStr == "" || text == "" {
+		return Message{}, fmt.Errorf("usage: /reply <id> <message>")
+	}
+
+	id, err := strconv.ParseUint(strings.TrimPrefix(idStr, "#"), 10, 64)
+	if err != nil {
+		return Message{}, fmt.Errorf("invalid message ID %q", idStr)
+	}
+
+	original, ok := room.FindMessage(id)
+	if !ok {
+		return Message{}, fmt.Errorf("message #%d is not in history", id)
+	}
+
+	return Message{
+		From:       from,
+		Content:    text,
+		Timestamp:  time.Now(),
+		ReplyTo:    id,
+		ReplyQuote: original.Content,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildReply(t *testing.T) {
+	room := NewRoom("Test Room", 5, true, 10, false)
+	defer room.Stop()
+
+	room.broadcastMessage(Message{From: "Alice", Content: "Anyone up for lunch?", Timestamp: time.Now()})
+	original := room.GetHistory()[0]
+	if original.ID == 0 {
+		t.Fatal("Expected broadcast message to be assigned an ID")
+	}
+
+	tests := []struct {
+		name    string
+		args    string
+		wantErr bool
+	}{
+		{"valid", "1 count me in", false},
+		{"hash prefix", "#1 count me in", false},
+		{"missing text", "1", true},
+		{"missing everything", "", true},
+		{"bad id", "abc hello", true},
+		{"unknown id", "99 hello", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, err := buildReply(room, "Bob", tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildReply(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if reply.ReplyTo != original.ID || reply.ReplyQuote != original.Content || reply.Content != "count me in" {
+				t.Errorf("Unexpected reply: %+v", reply)
+			}
+		})
+	}
+}
+
+func TestMessageLabel(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+
+	if got := messageLabel(Message{Timestamp: ts}); got != "09:30:00" {
+		t.Errorf("Expected label without ID, got %q", got)
+	}
+
+	if got := messageLabel(Message{ID: 7, Timestamp: ts}); got != "09:30:00 #7" {
+		t.Errorf("Expected label with ID, got %q", got)
+	}
+}
@@ -0,0 +1,156 @@
+package chat
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Prefixes that mark a per-name password hash in a reserved nicknames file
+const (
+	reservedHashPrefix   = "sha256:"
+	reservedBcryptPrefix = "bcrypt:"
+)
+
+// ReservedNick is a nickname set aside for a known person. With neither hash
+// set, the shared password unlocks it.
+type ReservedNick struct {
+	Hash   string // hex SHA-256 of the name's own password; unsalted, so only as strong as the password
+	Bcrypt string // bcrypt hash of the name's own password, checked instead of Hash when set
+	Admin  bool   // claiming the name also grants admin rights
+}
+
+// ReservedNicks holds the nicknames ordinary connections can't take. Names
+// match case-insensitively whatever --nick-case-insensitive says, so "Alice"
+// can't stand in for "alice".
+type ReservedNicks struct {
+	Password string // shared password for names without their own hash (empty: only hashed names can be claimed)
+	names    map[string]ReservedNick
+}
+
+// NewReservedNicks returns an empty set of reserved nicknames
+func NewReservedNicks(password string) *ReservedNicks {
+	return &ReservedNicks{Password: password, names: make(map[string]ReservedNick)}
+}
+
+// Add reserves name
+func (r *ReservedNicks) Add(name string, nick ReservedNick) {
+	r.names[strings.ToLower(name)] = nick
+}
+
+// Len returns how many nicknames are reserved
+func (r *ReservedNicks) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.names)
+}
+
+// AddList reserves each name in a comma-separated list, using the shared password
+func (r *ReservedNicks) AddList(list string) {
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			r.Add(name, ReservedNick{})
+		}
+	}
+}
+
+// AddFile reserves the names in a reserved nicknames file. Each line is a
+// name, optionally followed by "sha256:<hex>" or "bcrypt:<hash>" with the
+// name's own password hash and by "admin"; blank lines and lines starting
+// with # are skipped.
+func (r *ReservedNicks) AddFile(text string) error {
+	for i, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		var nick ReservedNick
+		for _, field := range fields[1:] {
+			switch {
+			case strings.HasPrefix(field, reservedHashPrefix):
+				hash := strings.ToLower(strings.TrimPrefix(field, reservedHashPrefix))
+				if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+					return fmt.Errorf("line %d: invalid password hash for %s", i+1, fields[0])
+				}
+				nick.Hash = hash
+			case strings.HasPrefix(field, reservedBcryptPrefix):
+				hash := strings.TrimPrefix(field, reservedBcryptPrefix)
+				if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+					return fmt.Errorf("line %d: invalid bcrypt hash for %s", i+1, fields[0])
+				}
+				nick.Bcrypt = hash
+			case field == "admin":
+				nick.Admin = true
+			default:
+				return fmt.Errorf("line %d: unexpected %q after %s", i+1, field, fields[0])
+			}
+		}
+		r.Add(fields[0], nick)
+	}
+	return nil
+}
+
+// lookup returns the reservation for nickname, if it is reserved
+func (r *ReservedNicks) lookup(nickname string) (ReservedNick, bool) {
+	if r == nil {
+		return ReservedNick{}, false
+	}
+	nick, ok := r.names[strings.ToLower(nickname)]
+	return nick, ok
+}
+
+// claimable reports whether any password unlocks nick
+func (r *ReservedNicks) claimable(nick ReservedNick) bool {
+	return nick.Bcrypt != "" || nick.Hash != "" || r.Password != ""
+}
+
+// check reports whether password unlocks nick
+func (r *ReservedNicks) check(nick ReservedNick, password string) bool {
+	if nick.Bcrypt != "" {
+		return bcrypt.CompareHashAndPassword([]byte(nick.Bcrypt), []byte(password)) == nil
+	}
+	if nick.Hash != "" {
+		sum := sha256.Sum256([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(nick.Hash)) == 1
+	}
+	return r.Password != "" && subtle.ConstantTimeCompare([]byte(password), []byte(r.Password)) == 1
+}
+
+// claimReserved asks for the password when nickname is reserved. It returns
+// whether the client may take the name, whether taking it makes them an admin,
+// and the rejection to show when they may not.
+func (c *Client) claimReserved(nickname string) (ok, admin bool, reject string, err error) {
+	reserved := c.room.ReservedNicks
+	nick, isReserved := reserved.lookup(nickname)
+	if !isReserved {
+		return true, false, "", nil
+	}
+	if !reserved.claimable(nick) {
+		return false, false, fmt.Sprintf("Nickname '%s' is reserved. Please choose another nickname.", nickname), nil
+	}
+
+	// Hide the password as it is typed, as PasswordAuth does
+	if err := c.writeTelnet(telnetEchoOff); err != nil {
+		return false, false, "", fmt.Errorf("failed to write password prompt: %w", err)
+	}
+	defer c.writeTelnet(telnetEchoOn)
+
+	if err := c.send(fmt.Sprintf("Nickname '%s' is reserved. Password: ", nickname)); err != nil {
+		return false, false, "", fmt.Errorf("failed to write password prompt: %w", err)
+	}
+	password, err := c.readLine()
+	c.send(c.newline()) // The user's Enter wasn't echoed either
+	if err != nil {
+		return false, false, "", fmt.Errorf("failed to read password: %w", err)
+	}
+	if !reserved.check(nick, strings.TrimSpace(password)) {
+		return false, false, fmt.Sprintf("Incorrect password for '%s'. Please choose another nickname.", nickname), nil
+	}
+	return true, nick.Admin, "", nil
+}
@@ -0,0 +1,181 @@
+package chat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestReservedNicksFile(t *testing.T) {
+	reserved := NewReservedNicks("")
+	err := reserved.AddFile("# regulars\nAlice sha256:" + sha256Hex("hunter2") + " admin\n\nbob\n")
+	if err != nil {
+		t.Fatalf("AddFile() error = %v", err)
+	}
+
+	alice, ok := reserved.lookup("alice")
+	if !ok || !alice.Admin || !reserved.check(alice, "hunter2") || reserved.check(alice, "wrong") {
+		t.Errorf("Unexpected reservation for alice: %+v, %v", alice, ok)
+	}
+	if bob, ok := reserved.lookup("BOB"); !ok || reserved.claimable(bob) {
+		t.Errorf("Expected bob reserved and unclaimable without a shared password, got %+v, %v", bob, ok)
+	}
+	if reserved.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", reserved.Len())
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("swordfish"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	if err := reserved.AddFile("dave bcrypt:" + string(hash)); err != nil {
+		t.Fatalf("AddFile() error = %v", err)
+	}
+	if dave, ok := reserved.lookup("dave"); !ok || !reserved.check(dave, "swordfish") || reserved.check(dave, "wrong") {
+		t.Errorf("Unexpected reservation for dave: %+v, %v", dave, ok)
+	}
+
+	for _, bad := range []string{"carol sha256:abc", "carol bcrypt:abc", "carol owner"} {
+		if err := NewReservedNicks("").AddFile(bad); err == nil {
+			t.Errorf("Expected AddFile(%q) to fail", bad)
+		}
+	}
+}
+
+// loginReserved runs the plain-text nickname prompt with input and returns the
+// client and everything written to it
+func loginReserved(t *testing.T, room *Room, input string) (*Client, string) {
+	t.Helper()
+	conn := &scriptedConn{in: strings.NewReader(input)}
+	client, err := NewPlainTextClient(conn, room, ClientOptions{PlainText: true})
+	if err != nil {
+		t.Fatalf("NewPlainTextClient() error = %v", err)
+	}
+	client.flush()
+	return client, conn.String()
+}
+
+func TestReservedNickBlockedWithoutPassword(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	room.ReservedNicks = NewReservedNicks("")
+	room.ReservedNicks.AddList("alice, carol")
+
+	client, out := loginReserved(t, room, "Alice\nbob\n")
+	if client.Nickname != "bob" {
+		t.Errorf("Nickname = %q, want bob", client.Nickname)
+	}
+	if !strings.Contains(out, "Nickname 'Alice' is reserved. Please choose another nickname.") {
+		t.Errorf("Expected the reserved name to be refused, got %q", out)
+	}
+	if strings.Contains(out, "Password:") {
+		t.Errorf("Expected no password prompt without a password configured, got %q", out)
+	}
+	if !room.ReserveNickname("Alice") {
+		t.Error("Expected the refused name's reservation to be released")
+	}
+}
+
+func TestReservedNickWrongPassword(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	room.ReservedNicks = NewReservedNicks("secret")
+	room.ReservedNicks.AddList("alice")
+
+	client, out := loginReserved(t, room, "alice\nguess\nbob\n")
+	if client.Nickname != "bob" {
+		t.Errorf("Nickname = %q, want bob", client.Nickname)
+	}
+	if !strings.Contains(out, "Incorrect password for 'alice'.") {
+		t.Errorf("Expected the wrong password to be refused, got %q", out)
+	}
+	if client.isAdmin() {
+		t.Error("Expected no admin rights")
+	}
+}
+
+func TestReservedNickGrantedWithPassword(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	audit := &fakeAudit{}
+	room.Audit = audit
+	room.ReservedNicks = NewReservedNicks("shared")
+	room.ReservedNicks.AddFile("alice sha256:" + sha256Hex("hunter2") + " admin")
+
+	// The shared password doesn't unlock a name with its own hash
+	client, out := loginReserved(t, room, "alice\nshared\nalice\nhunter2\n")
+	if client.Nickname != "alice" {
+		t.Fatalf("Nickname = %q, want alice (output %q)", client.Nickname, out)
+	}
+	if !strings.Contains(out, "Nickname 'alice' is reserved. Password: ") {
+		t.Errorf("Expected a password prompt, got %q", out)
+	}
+	if !client.isAdmin() {
+		t.Error("Expected the reserved admin name to grant admin rights")
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != AuditAdmin || audit.events[0].Reason != "reserved nickname" {
+		t.Errorf("Unexpected audit events %+v", audit.events)
+	}
+}
+
+func TestReservedNickHidesPassword(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	room.ReservedNicks = NewReservedNicks("secret")
+	room.ReservedNicks.AddList("alice")
+
+	// The client's replies to the echo negotiation must not end up in the password
+	client, out := loginReserved(t, room, "alice\n\xff\xfd\x01secret\n\xff\xfe\x01")
+	if client.Nickname != "alice" {
+		t.Fatalf("Nickname = %q, want alice (output %q)", client.Nickname, out)
+	}
+
+	off := strings.Index(out, string(telnetEchoOff))
+	prompt := strings.Index(out, "Password: ")
+	on := strings.Index(out, string(telnetEchoOn))
+	if off < 0 || prompt < off || on < prompt {
+		t.Errorf("Expected echo off, the prompt, then echo on, got %q", out)
+	}
+}
+
+func TestReservedNickTUI(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+	room.ReservedNicks = NewReservedNicks("secret")
+	room.ReservedNicks.AddList("alice")
+
+	enter := func(model ChatModel, value string) ChatModel {
+		model.textInput.SetValue(value)
+		updated, _ := model.updateNickname(tea.KeyMsg{Type: tea.KeyEnter})
+		return updated.(ChatModel)
+	}
+
+	model := enter(NewChatModel(NewTUIClient(nil, room)), "alice")
+	if model.pendingNick != "alice" {
+		t.Fatalf("Expected a password prompt for alice, got pending %q, error %q", model.pendingNick, model.errMsg)
+	}
+	// Nothing is held while the password is typed
+	if !room.ReserveNickname("alice") {
+		t.Fatal("Expected alice not to be reserved before the password is checked")
+	}
+	room.ReleaseNickname("alice")
+
+	model = enter(model, "guess")
+	if model.client.Nickname != "" || !strings.Contains(model.errMsg, "Incorrect password") {
+		t.Errorf("Expected the wrong password to be refused, got nickname %q, error %q", model.client.Nickname, model.errMsg)
+	}
+
+	model = enter(enter(model, "alice"), "secret")
+	if model.client.Nickname != "alice" || model.errMsg != "" {
+		t.Errorf("Expected alice to be granted, got nickname %q, error %q", model.client.Nickname, model.errMsg)
+	}
+}
@@ -3,36 +3,207 @@ package chat
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
+
+	"github.com/bscott/ts-chat/internal/ui"
+	"golang.org/x/text/encoding"
 )
 
-// Message represents a chat message
-type Message struct {
-	From      string
-	Content   string
-	Timestamp time.Time
-	IsSystem  bool
-	IsAction  bool
+// RoomStats is a snapshot of room activity counters
+type RoomStats struct {
+	TotalMessages       int64
+	MessagesLastMinute  int
+	Online              int
+	PeakOnline          int
+	MessagesByTransport map[string]int64 // chat sent by local clients, keyed by Client.Transport
+}
+
+// leaveRequest asks the run loop to remove a client and signals done when it has
+type leaveRequest struct {
+	client *Client
+	done   chan struct{}
 }
 
+// subscriberBuffer is the channel capacity given to each Subscribe listener
+const subscriberBuffer = 64
+
+// subscriber is a registered listener for room broadcasts
+type subscriber struct {
+	ch chan Message
+}
+
+// statsWindow is the sliding window used for the per-minute message rate
+const statsWindow = time.Minute
+
 // Room represents a chat room
 type Room struct {
 	Name          string
 	MaxUsers      int
 	clients       map[string]*Client
+	sessions      map[string]*Client  // identity -> client, for clients with a known identity
+	lastSeen      map[string]lastSeen // nickKey -> when that user last left, for /seen; guarded by mu
 	broadcast     chan Message
+	broadcastSync chan syncBroadcast
+	remote        chan Message // messages from other processes sharing the room
+	relay         *relay       // set by AttachBus; nil when the room is local
 	join          chan *Client
-	leave         chan *Client
+	leave         chan leaveRequest
+	drain         chan drainRequest
+	flushAll      chan chan flushBatch
+	sending       *sync.WaitGroup // in-flight Broadcast deliveries; replaced by each Flush
+	leaving       sync.WaitGroup  // Leave calls for dropped clients, waited on by Stop
+	leavingMu     sync.Mutex      // orders leaving.Add against Stop
+	stopping      bool            // set by Stop under leavingMu
 	mu            sync.RWMutex
 	ctx           context.Context
 	cancel        context.CancelFunc
 	done          chan struct{}
 	enableHistory bool
 	historySize   int
-	history       []Message
+	history       *historyBuffer
 	historyMu     sync.RWMutex
+	historyBudget *HistoryBudget // shared memory cap, nil for none; guarded by historyMu
+	historyActive time.Time      // when a message was last added to history; guarded by historyMu
+	historyMaxAge time.Duration
+	pinned        []Message // from SetPinned, replayed ahead of history; guarded by historyMu
 	PlainText     bool
+	Banner        string // custom welcome banner; ui.DefaultBanner when empty
+	MOTD          string // message of the day shown once at join; none when empty
+	BannerWidth   int    // terminal width line-mode banners are centered in; 0 leaves them as is
+
+	// Compact starts clients in compact mode: no banner or boxes and one-line
+	// notices, for small screens. Clients switch with /compact.
+	Compact bool
+
+	// BannerAnimateDelay prints the banner to ANSI line-mode clients one line
+	// at a time with this pause between lines (0 prints it at once)
+	BannerAnimateDelay time.Duration
+
+	statsMu       sync.Mutex
+	totalMessages int64
+	peakOnline    int
+	byTransport   map[string]int64 // messages per client transport, guarded by statsMu
+	recentTimes   []time.Time
+	subscribers   []*subscriber
+	subMu         sync.Mutex
+	lastID        atomic.Uint64
+
+	// AllowNickHandshake lets line-mode clients skip the prompt by sending "NICK <name>" first
+	AllowNickHandshake bool
+	// JoinLeaveCoalesce batches join/leave notices within this window into one summary (0 disables)
+	JoinLeaveCoalesce time.Duration
+	// AdminToken lets clients become admins with /admin <token> (empty disables admins)
+	AdminToken string
+	// Connections lets admins list and drop server connections (nil when unavailable)
+	Connections ConnectionManager
+	// Invites supplies the connect command shown by /invite (nil when unavailable)
+	Invites InviteProvider
+	// Feedback receives /feedback submissions (nil writes them to the server log)
+	Feedback FeedbackSink
+	// Audit records moderation actions such as /disconnect (nil keeps no audit log)
+	Audit AuditSink
+	// ReservedNicks are nicknames that need a password to take (nil reserves none)
+	ReservedNicks *ReservedNicks
+	// JoinCooldown is how long after joining a client must wait before chatting (0 disables)
+	JoinCooldown time.Duration
+	// Clock is the time source for rate limiting, history aging and stats
+	Clock Clock
+	// AnnouncePersist keeps /announce notices in history so late joiners see them
+	AnnouncePersist bool
+	// QuietJoins starts clients in do-not-disturb mode, hiding join/leave notices
+	QuietJoins bool
+	// NickCaseFold treats nicknames differing only in case as the same
+	// nickname, so "Alice" can't join alongside "alice". Set it before anyone joins.
+	NickCaseFold bool
+	// WriteTimeout bounds each write to a client; stuck clients are disconnected (0 disables)
+	WriteTimeout time.Duration
+
+	// Length limits, defaulting to the package constants
+	MinNicknameLen   int
+	MaxNicknameLen   int
+	MaxMessageLength int
+
+	// Scrollback caps how many messages a TUI client keeps on screen (0 keeps all)
+	Scrollback int
+
+	// MaxNicknameAttempts disconnects clients after this many rejected nicknames (0 disables)
+	MaxNicknameAttempts int
+
+	// HistoryReplayCount caps how many stored messages a joining user is shown;
+	// 0 shows none, and a negative count replays everything stored
+	HistoryReplayCount int
+
+	// HistorySystem stores join/leave and other system notices in history;
+	// when false they are still broadcast live but not replayed later
+	HistorySystem bool
+
+	// HistoryKinds limits history to these message kinds (nil keeps every kind)
+	HistoryKinds map[MessageKind]bool
+
+	// ReadPollInterval is the read deadline line-mode clients wake up on to
+	// check for shutdown; lower is more responsive but wakes more often (0 disables)
+	ReadPollInterval time.Duration
+
+	// ReadBufferSize and WriteBufferSize size each line-mode client's buffered
+	// reader and writer in bytes (0 for DefaultBufferSize)
+	ReadBufferSize  int
+	WriteBufferSize int
+
+	// RateLimit caps chat across the whole room in messages per second; system
+	// messages are exempt (0 disables)
+	RateLimit float64
+
+	// FloodKickAfter disconnects a client once it has hit the per-client rate
+	// limit this many times within FloodKickWindow (0 disables)
+	FloodKickAfter  int
+	FloodKickWindow time.Duration
+	// FloodBans is told the address of clients kicked for flooding (nil disables bans)
+	FloodBans FloodBanner
+
+	// Idle disconnect settings from SetIdleTimeout, guarded by mu
+	idleTimeout time.Duration
+	idleWarning time.Duration
+
+	// Current topic and who last changed it, guarded by mu
+	topic      string
+	topicSetBy string
+	topicSetAt time.Time
+
+	// quietHours limits posting to admins during a daily window (nil disables)
+	quietHours *QuietHours
+
+	// Encoding transcodes client traffic for non-UTF-8 terminals (nil for UTF-8)
+	Encoding encoding.Encoding
+
+	// LineEnding terminates line-mode output, CRLF or LF (empty for CRLF)
+	LineEnding string
+
+	// Hyperlinks wraps URLs in chat as OSC 8 links for ANSI clients. Terminals
+	// without OSC 8 support may print the escape sequences, so it is off by default.
+	Hyperlinks bool
+
+	// Accessible renders the room with the color-blind-safe theme and text
+	// prefixes such as [SYS] and [YOU]
+	Accessible bool
+
+	// OnJoinCmd and OnLeaveCmd are commands run with the nickname and address
+	// whenever someone joins or leaves (empty disables)
+	OnJoinCmd  string
+	OnLeaveCmd string
+}
+
+// nicknameAttemptsExceeded reports whether a client has used up its nickname attempts
+func (r *Room) nicknameAttemptsExceeded(attempts int) bool {
+	return r.MaxNicknameAttempts > 0 && attempts >= r.MaxNicknameAttempts
+}
+
+// theme returns the ui theme the room renders with
+func (r *Room) theme() ui.Theme {
+	return ui.Theme{Accessible: r.Accessible}
 }
 
 // NewRoom creates a new chat room
@@ -42,16 +213,34 @@ func NewRoom(name string, maxUsers int, enableHistory bool, historySize int, pla
 		Name:          name,
 		MaxUsers:      maxUsers,
 		clients:       make(map[string]*Client),
+		sessions:      make(map[string]*Client),
 		broadcast:     make(chan Message),
+		broadcastSync: make(chan syncBroadcast),
+		remote:        make(chan Message),
 		join:          make(chan *Client),
-		leave:         make(chan *Client),
+		leave:         make(chan leaveRequest),
+		drain:         make(chan drainRequest),
+		flushAll:      make(chan chan flushBatch),
+		sending:       new(sync.WaitGroup),
 		ctx:           ctx,
 		cancel:        cancel,
 		done:          make(chan struct{}),
 		enableHistory: enableHistory,
 		historySize:   historySize,
-		history:       make([]Message, 0, historySize),
+		history:       newHistoryBuffer(historySize),
 		PlainText:     plainText,
+
+		MinNicknameLen:   MinNicknameLen,
+		MaxNicknameLen:   MaxNicknameLen,
+		MaxMessageLength: MaxMessageLength,
+
+		Scrollback:          DefaultScrollback,
+		MaxNicknameAttempts: MaxNicknameAttempts,
+		ReadPollInterval:    DefaultReadPollInterval,
+		FloodKickWindow:     DefaultFloodKickWindow,
+		HistoryReplayCount:  ReplayAllHistory,
+		HistorySystem:       true,
+		Clock:               realClock{},
 	}
 
 	go room.run()
@@ -61,134 +250,567 @@ func NewRoom(name string, maxUsers int, enableHistory bool, historySize int, pla
 // run handles room events
 func (r *Room) run() {
 	defer close(r.done)
+
+	// Join/leave notices waiting to be coalesced, and the timer that flushes them
+	var pending joinLeaveBatch
+	var flush <-chan time.Time
+	var flood floodGuard
+
+	notify := func(notice Message, joined bool) {
+		if r.JoinLeaveCoalesce <= 0 {
+			r.broadcastMessage(notice)
+			return
+		}
+		pending.add(notice, joined)
+		if flush == nil {
+			flush = time.After(r.JoinLeaveCoalesce)
+		}
+	}
+
+	flushPending := func() {
+		if msg, ok := pending.summary(); ok {
+			r.broadcastMessage(msg)
+		}
+		pending = joinLeaveBatch{}
+		flush = nil
+	}
+
 	for {
 		select {
 		case <-r.ctx.Done():
 			return
 		case client := <-r.join:
-			r.addClient(client)
-		case client := <-r.leave:
-			r.removeClient(client)
+			notice, ok := r.addClient(client)
+			close(client.joinDone)
+			if ok {
+				notify(notice, true)
+				r.runHook(r.OnJoinCmd, "join", client.Nickname, client.remoteAddr)
+			}
+		case req := <-r.leave:
+			notice, ok := r.removeClient(req.client)
+			close(req.done)
+			if ok {
+				notify(notice, false)
+				r.runHook(r.OnLeaveCmd, "leave", req.client.Nickname, req.client.remoteAddr)
+			}
+		case req := <-r.drain:
+			// Pending notices may name users about to be dropped; send them first
+			flushPending()
+			req.done <- r.drainClients(req.notice)
+		case reply := <-r.flushAll:
+			flushPending()
+			reply <- flushBatch{clients: r.lineClients(), sending: r.sending}
+			// Later broadcasts aren't part of this flush, and Flush may already be waiting
+			r.sending = new(sync.WaitGroup)
+		case <-flush:
+			flushPending()
 		case msg := <-r.broadcast:
+			// Keep notices ahead of messages sent after them
+			flushPending()
+			now := r.Clock.Now()
+			if r.admitDuringQuietHours(msg, now) && r.admitBroadcast(&flood, msg, now) {
+				r.broadcastMessage(msg)
+			}
+		case msg := <-r.remote:
+			// Already admitted by the process it came from
+			flushPending()
 			r.broadcastMessage(msg)
+		case req := <-r.broadcastSync:
+			flushPending()
+			now := r.Clock.Now()
+			delivered := new(sync.WaitGroup)
+			if r.admitDuringQuietHours(req.msg, now) && r.admitBroadcast(&flood, req.msg, now) {
+				r.broadcastTracked(req.msg, delivered)
+			}
+			// Wait off the room goroutine so a slow client doesn't stall the room
+			go func() {
+				delivered.Wait()
+				close(req.done)
+			}()
 		}
 	}
 }
 
-// addClient adds a client to the room
-func (r *Room) addClient(c *Client) {
-	r.mu.Lock()
+// joinLeaveBatch collects join/leave notices during a coalescing window
+type joinLeaveBatch struct {
+	notices []Message
+	joined  int
+	left    int
+}
 
-	// Count actual clients (non-nil entries, excluding reservations)
-	activeClients := 0
-	for _, client := range r.clients {
-		if client != nil {
-			activeClients++
+func (b *joinLeaveBatch) add(notice Message, joined bool) {
+	b.notices = append(b.notices, notice)
+	if joined {
+		b.joined++
+	} else {
+		b.left++
+	}
+}
+
+// summary returns the message to broadcast for the batch: the original notice for
+// a single event, or a combined count for several
+func (b *joinLeaveBatch) summary() (Message, bool) {
+	switch len(b.notices) {
+	case 0:
+		return Message{}, false
+	case 1:
+		return b.notices[0], true
+	}
+
+	var parts []string
+	if b.joined > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s joined", b.joined, pluralUsers(b.joined)))
+	}
+	if b.left > 0 {
+		if len(parts) > 0 {
+			parts = append(parts, fmt.Sprintf("%d left", b.left))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d %s left", b.left, pluralUsers(b.left)))
 		}
 	}
 
+	return Message{
+		From:      "System",
+		Content:   strings.Join(parts, ", "),
+		Timestamp: time.Now(),
+		Kind:      KindPresence,
+	}, true
+}
+
+func pluralUsers(n int) string {
+	if n == 1 {
+		return "user"
+	}
+	return "users"
+}
+
+// addClient adds a client to the room and returns the join notice to broadcast.
+// It reports false when the client was rejected.
+func (r *Room) addClient(c *Client) (Message, bool) {
+	r.mu.Lock()
+
+	activeClients := r.onlineLocked()
+
 	// Check if room is full
 	if activeClients >= r.MaxUsers {
 		// Remove the reservation since we can't add them
-		delete(r.clients, c.Nickname)
+		delete(r.clients, r.nickKey(c.Nickname))
 		r.mu.Unlock()
 		// Send message but don't close connection here
 		// Connection handling should be done by the caller
 		c.sendSystemMessage("Sorry, the room is full. Try again later.")
 		// Signal that the client wasn't added by setting a flag
 		c.fullRoomRejection = true
-		return
+		return Message{}, false
 	}
 
 	// Add client to the room (replaces nil reservation with actual client)
-	r.clients[c.Nickname] = c
+	r.clients[r.nickKey(c.Nickname)] = c
+	c.joined = true
+	c.joinedAt = r.Clock.Now()
+	c.touch(c.joinedAt)
+	if c.Identity != "" {
+		r.sessions[c.Identity] = c
+	}
+	online := activeClients + 1
 	r.mu.Unlock()
 
-	// Notify everyone that a new user has joined (outside of lock to avoid deadlock)
-	systemMsg := Message{
+	r.statsMu.Lock()
+	if online > r.peakOnline {
+		r.peakOnline = online
+	}
+	r.statsMu.Unlock()
+
+	return Message{
 		From:      "System",
 		Content:   fmt.Sprintf("%s has joined the room", c.Nickname),
 		Timestamp: time.Now(),
-		IsSystem:  true,
-	}
-	r.broadcastMessage(systemMsg)
+		Kind:      KindJoin,
+	}, true
 }
 
-// removeClient removes a client from the room
-func (r *Room) removeClient(c *Client) {
+// removeClient removes a client from the room and returns the leave notice to broadcast.
+// It reports false when the client was not in the room.
+func (r *Room) removeClient(c *Client) (Message, bool) {
 	r.mu.Lock()
-	_, exists := r.clients[c.Nickname]
+	// Only remove the entry if it belongs to this client; a rejected client must
+	// not evict someone who has since taken the same nickname
+	existing, ok := r.clients[r.nickKey(c.Nickname)]
+	exists := ok && existing == c
 	if exists {
-		delete(r.clients, c.Nickname)
+		delete(r.clients, r.nickKey(c.Nickname))
+		r.recordSeenLocked(c.Nickname, r.Clock.Now())
+	}
+
+	// A client that reserved a nickname but never joined leaves its reservation behind
+	if ok && existing == nil && !c.joined {
+		delete(r.clients, r.nickKey(c.Nickname))
+	}
+	if c.Identity != "" && r.sessions[c.Identity] == c {
+		delete(r.sessions, c.Identity)
 	}
 	r.mu.Unlock()
 
-	if exists {
-		// Notify everyone that a user has left (outside of lock to avoid deadlock)
-		systemMsg := Message{
-			From:      "System",
-			Content:   fmt.Sprintf("%s has left the room", c.Nickname),
-			Timestamp: time.Now(),
-			IsSystem:  true,
-		}
-		r.broadcastMessage(systemMsg)
+	if !exists {
+		return Message{}, false
+	}
+
+	content := fmt.Sprintf("%s has left the room", c.Nickname)
+	if reason := c.getQuitReason(); reason != "" {
+		content = fmt.Sprintf("%s has left the room (%s)", c.Nickname, reason)
 	}
+
+	return Message{
+		From:      "System",
+		Content:   content,
+		Timestamp: time.Now(),
+		Kind:      KindLeave,
+	}, true
 }
 
 // broadcastMessage sends a message to all clients
 func (r *Room) broadcastMessage(msg Message) {
+	r.broadcastTracked(msg, nil)
+}
+
+// broadcastTracked sends a message to all clients. When delivered is non-nil
+// it counts one delivery per client, done once that client has written it.
+func (r *Room) broadcastTracked(msg Message, delivered *sync.WaitGroup) {
+	if msg.ID == 0 {
+		msg.ID = r.lastID.Add(1)
+	}
+
 	// Store in history if enabled (for non-system messages or join/leave messages)
-	if r.enableHistory {
+	if r.enableHistory && (msg.Kind != KindAnnouncement || r.AnnouncePersist) {
 		r.addToHistory(msg)
 	}
 
+	if !msg.IsSystem() {
+		r.recordMessage(r.transportOf(msg))
+	}
+
+	r.notifySubscribers(msg)
+	r.relayMessageOut(msg)
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	for _, client := range r.clients {
-		if client != nil {
-			go client.Send(msg) // Use goroutine to avoid blocking
+		if client == nil {
+			continue
 		}
+		if delivered == nil {
+			r.sendTo(client, msg)
+			continue
+		}
+		delivered.Add(1)
+		go func() {
+			defer delivered.Done()
+			client.Send(msg)
+			client.flush()
+		}()
+	}
+}
+
+// sendTo delivers msg to one client without blocking the run loop; a TUI
+// client's program may not be reading yet. Flush waits for it like a broadcast.
+func (r *Room) sendTo(client *Client, msg Message) {
+	sending := r.sending
+	sending.Add(1)
+	go func() {
+		defer sending.Done()
+		client.Send(msg)
+	}()
+}
+
+// Subscribe registers a listener that receives every message broadcast in the room.
+// The returned function unsubscribes and closes the channel. A subscriber that
+// falls behind and fills its buffer is dropped and its channel closed, as is
+// one that subscribes after the room has stopped.
+func (r *Room) Subscribe() (<-chan Message, func()) {
+	sub := &subscriber{ch: make(chan Message, subscriberBuffer)}
+
+	r.subMu.Lock()
+	if r.ctx.Err() != nil {
+		// Stop has closed, or is about to close, every registered channel
+		close(sub.ch)
+	} else {
+		r.subscribers = append(r.subscribers, sub)
 	}
+	r.subMu.Unlock()
+
+	return sub.ch, func() { r.unsubscribe(sub) }
 }
 
-// addToHistory adds a message to the history buffer
+// unsubscribe removes a listener and closes its channel if it is still registered
+func (r *Room) unsubscribe(sub *subscriber) {
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
+	for i, s := range r.subscribers {
+		if s == sub {
+			r.subscribers = append(r.subscribers[:i], r.subscribers[i+1:]...)
+			close(sub.ch)
+			return
+		}
+	}
+}
+
+// notifySubscribers delivers a message to all listeners without blocking
+func (r *Room) notifySubscribers(msg Message) {
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
+	kept := r.subscribers[:0]
+	for _, sub := range r.subscribers {
+		select {
+		case sub.ch <- msg:
+			kept = append(kept, sub)
+		default:
+			// Slow subscriber, drop it rather than block the room
+			close(sub.ch)
+		}
+	}
+
+	// Clear the tail so dropped subscribers can be collected
+	for i := len(kept); i < len(r.subscribers); i++ {
+		r.subscribers[i] = nil
+	}
+	r.subscribers = kept
+}
+
+// addToHistory adds a message to the history buffer, skipping system notices
+// unless HistorySystem is set and kinds left out of HistoryKinds.
+// Announcements are also governed by AnnouncePersist.
 func (r *Room) addToHistory(msg Message) {
+	if !r.HistorySystem && msg.IsSystem() && msg.Kind != KindAnnouncement {
+		return
+	}
+	if r.HistoryKinds != nil && !r.HistoryKinds[msg.Kind] {
+		return
+	}
+
+	r.historyMu.Lock()
+	r.history.add(msg)
+	r.historyActive = r.Clock.Now()
+	r.compactHistoryLocked()
+	budget := r.historyBudget
+	r.historyMu.Unlock()
+
+	// Outside historyMu: the budget may lock other rooms' histories
+	if budget != nil {
+		budget.enforce()
+	}
+}
+
+// SetHistoryMaxAge evicts history entries older than maxAge regardless of count.
+// A background compaction runs until the room stops. Zero disables age-based eviction.
+func (r *Room) SetHistoryMaxAge(maxAge time.Duration) {
 	r.historyMu.Lock()
-	defer r.historyMu.Unlock()
+	r.historyMaxAge = maxAge
+	r.historyMu.Unlock()
 
-	r.history = append(r.history, msg)
+	if maxAge > 0 {
+		go r.compactHistoryLoop(maxAge / 2)
+	}
+}
 
-	// Trim history if it exceeds the max size
-	if len(r.history) > r.historySize {
-		r.history = r.history[len(r.history)-r.historySize:]
+// SetHeartbeatInterval starts sending keepalives to every member at the given
+// interval until the room stops, disconnecting clients whose writes fail. Zero disables it.
+func (r *Room) SetHeartbeatInterval(interval time.Duration) {
+	if interval > 0 {
+		go r.heartbeatLoop(interval)
 	}
 }
 
-// GetHistory returns the message history
+// heartbeatLoop pings all members on each tick until the room stops.
+// heartbeat only queues the write, so a slow client can't hold up the loop.
+func (r *Room) heartbeatLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			r.mu.RLock()
+			for _, client := range r.clients {
+				if client != nil {
+					client.heartbeat()
+				}
+			}
+			r.mu.RUnlock()
+		}
+	}
+}
+
+// compactHistoryLoop periodically drops expired history until the room stops
+func (r *Room) compactHistoryLoop(interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			r.historyMu.Lock()
+			r.compactHistoryLocked()
+			r.historyMu.Unlock()
+		}
+	}
+}
+
+// compactHistoryLocked drops expired entries; historyMu must be held for writing
+func (r *Room) compactHistoryLocked() {
+	if r.historyMaxAge > 0 {
+		r.history.dropBefore(r.Clock.Now().Add(-r.historyMaxAge))
+	}
+}
+
+// recordMessage counts a chat message, per transport when known
+func (r *Room) recordMessage(transport string) {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	now := r.Clock.Now()
+	r.totalMessages++
+	if transport != "" {
+		if r.byTransport == nil {
+			r.byTransport = make(map[string]int64)
+		}
+		r.byTransport[transport]++
+	}
+	r.recentTimes = append(r.recentTimes, now)
+	r.pruneRecent(now)
+}
+
+// pruneRecent drops timestamps that fell out of the stats window; statsMu must be held
+func (r *Room) pruneRecent(now time.Time) {
+	cutoff := now.Add(-statsWindow)
+	i := 0
+	for i < len(r.recentTimes) && !r.recentTimes[i].After(cutoff) {
+		i++
+	}
+	r.recentTimes = r.recentTimes[i:]
+}
+
+// Stats returns a snapshot of the room activity counters
+func (r *Room) Stats() RoomStats {
+	online := r.OnlineCount()
+
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	r.pruneRecent(r.Clock.Now())
+	byTransport := make(map[string]int64, len(r.byTransport))
+	for transport, n := range r.byTransport {
+		byTransport[transport] = n
+	}
+	return RoomStats{
+		TotalMessages:       r.totalMessages,
+		MessagesLastMinute:  len(r.recentTimes),
+		Online:              online,
+		PeakOnline:          r.peakOnline,
+		MessagesByTransport: byTransport,
+	}
+}
+
+// transportOf returns the transport of the local client that sent msg, or ""
+// for messages relayed from other servers and senders no longer in the room
+func (r *Room) transportOf(msg Message) string {
+	if msg.remote {
+		return ""
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if client := r.clients[r.nickKey(msg.From)]; client != nil {
+		return client.Transport
+	}
+	return ""
+}
+
+// GetHistory returns the message history, oldest first
 func (r *Room) GetHistory() []Message {
 	r.historyMu.RLock()
 	defer r.historyMu.RUnlock()
 
 	// Return a copy to avoid race conditions
-	history := make([]Message, len(r.history))
-	copy(history, r.history)
-	return history
+	return r.history.last(r.history.len())
+}
+
+// FindMessage looks up a message in history by ID
+func (r *Room) FindMessage(id uint64) (Message, bool) {
+	r.historyMu.RLock()
+	defer r.historyMu.RUnlock()
+
+	return r.history.find(id)
+}
+
+// ReplayHistory returns the recent messages to show a joining user, honoring
+// HistoryReplayCount. Pinned messages come first.
+func (r *Room) ReplayHistory() []Message {
+	var history []Message
+	if r.HistoryReplayCount < 0 {
+		history = r.GetHistory()
+	} else {
+		history = r.GetHistoryN(r.HistoryReplayCount)
+	}
+	return append(r.Pinned(), history...)
+}
+
+// GetHistoryN returns up to n of the most recent messages, oldest first
+func (r *Room) GetHistoryN(n int) []Message {
+	r.historyMu.RLock()
+	defer r.historyMu.RUnlock()
+
+	return r.history.last(n)
 }
 
-// Join adds a client to the room
+// Join adds a client to the room. It returns once the join has been processed,
+// so callers can check fullRoomRejection afterwards.
 func (r *Room) Join(client *Client) {
+	client.joinDone = make(chan struct{})
 	select {
 	case r.join <- client:
+		select {
+		case <-client.joinDone:
+		case <-r.ctx.Done():
+		}
 	case <-r.ctx.Done():
 		// Room is shutting down, don't block
 	}
 }
 
-// Leave removes a client from the room
+// leaveAsync calls Leave on a new goroutine, for callers that may be running
+// on the room goroutine. Stop waits for it to return.
+func (r *Room) leaveAsync(client *Client) {
+	r.leavingMu.Lock()
+	defer r.leavingMu.Unlock()
+	if r.stopping {
+		return
+	}
+	r.leaving.Add(1)
+	go func() {
+		defer r.leaving.Done()
+		r.Leave(client)
+	}()
+}
+
+// Leave removes a client from the room. It returns once the removal has been processed.
 func (r *Room) Leave(client *Client) {
+	req := leaveRequest{client: client, done: make(chan struct{})}
 	select {
-	case r.leave <- client:
+	case r.leave <- req:
+		select {
+		case <-req.done:
+		case <-r.ctx.Done():
+		}
 	case <-r.ctx.Done():
 		// Room is shutting down, don't block
 	}
@@ -203,39 +825,166 @@ func (r *Room) Broadcast(msg Message) {
 	}
 }
 
-// GetUserList returns a list of all users in the room
+// syncBroadcast is a BroadcastSync request; done closes once it is delivered
+type syncBroadcast struct {
+	msg  Message
+	done chan struct{}
+}
+
+// BroadcastSync is Broadcast that returns only once every client in the room
+// has the message: written to the connection in line mode, or received by the
+// model in TUI mode. A message dropped by flood control or quiet hours returns
+// at once. It is meant for tests and low-throughput callers; chat should use
+// Broadcast, which never waits on slow clients.
+func (r *Room) BroadcastSync(msg Message) {
+	req := syncBroadcast{msg: msg, done: make(chan struct{})}
+	select {
+	case r.broadcastSync <- req:
+	case <-r.ctx.Done():
+		return
+	}
+	select {
+	case <-req.done:
+	case <-r.ctx.Done():
+	}
+}
+
+// GetClient returns the joined client using nickname, or nil if there is none
+func (r *Room) GetClient(nickname string) *Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.clients[r.nickKey(nickname)]
+}
+
+// OnlineCount returns the number of joined users, not counting reservations
+func (r *Room) OnlineCount() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.onlineLocked()
+}
+
+// onlineLocked counts non-nil clients; r.mu must be held
+func (r *Room) onlineLocked() int {
+	online := 0
+	for _, client := range r.clients {
+		if client != nil {
+			online++
+		}
+	}
+	return online
+}
+
+// GetUserList returns the nicknames of joined users, sorted case-insensitively,
+// including users of other processes sharing the room over a bus.
+// Reservations held by clients still choosing a nickname are not included.
 func (r *Room) GetUserList() []string {
+	users := r.localUserList()
+
+	seen := make(map[string]bool, len(users))
+	for _, user := range users {
+		seen[user] = true
+	}
+	for _, user := range r.remoteUsers() {
+		if !seen[user] {
+			seen[user] = true
+			users = append(users, user)
+		}
+	}
+
+	ui.SortNicknames(users)
+	return users
+}
+
+// localUserList returns the nicknames of users joined to this process, unsorted
+func (r *Room) localUserList() []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	
+
 	users := make([]string, 0, len(r.clients))
-	for nickname := range r.clients {
-		users = append(users, nickname)
+	for _, client := range r.clients {
+		if client != nil {
+			users = append(users, client.Nickname)
+		}
 	}
-	
 	return users
 }
 
+// ReplaceSession disconnects any existing session for identity so a reconnecting
+// user can take over, freeing its nickname. It reports whether a session was evicted.
+func (r *Room) ReplaceSession(identity string) bool {
+	if identity == "" {
+		return false
+	}
+
+	r.mu.RLock()
+	old := r.sessions[identity]
+	r.mu.RUnlock()
+
+	if old == nil {
+		return false
+	}
+
+	old.setQuitReason("connected from another location")
+	old.Send(Message{
+		From:      "System",
+		Content:   "You connected from another location. Disconnecting this session.",
+		Timestamp: time.Now(),
+		Kind:      KindSystem,
+	})
+
+	// Leave waits for the run loop, so the nickname is free once it returns
+	r.Leave(old)
+	old.close()
+	return true
+}
+
+// validateMessageLength checks a message against the room's configured limit
+func (r *Room) validateMessageLength(message string) error {
+	if n := utf8.RuneCountInString(message); n > r.MaxMessageLength {
+		return fmt.Errorf("message too long (%d characters, max %d)", n, r.MaxMessageLength)
+	}
+	return nil
+}
+
+// nickKey is the key nickname is stored under in r.clients: the nickname as
+// is, or lowercased when NickCaseFold is set. Display case is kept on
+// the Client.
+func (r *Room) nickKey(nickname string) string {
+	if r.NickCaseFold {
+		return strings.ToLower(nickname)
+	}
+	return nickname
+}
+
+// sameNick reports whether a and b name the same user
+func (r *Room) sameNick(a, b string) bool {
+	return r.nickKey(a) == r.nickKey(b)
+}
+
 // IsNicknameAvailable checks if a nickname is available
 func (r *Room) IsNicknameAvailable(nickname string) bool {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	_, exists := r.clients[nickname]
-	return !exists
+	_, exists := r.clients[r.nickKey(nickname)]
+	return !exists && !r.isRemoteUser(nickname)
 }
 
 // ReserveNickname atomically checks and reserves a nickname, returning true if successful
 func (r *Room) ReserveNickname(nickname string) bool {
+	if r.isRemoteUser(nickname) {
+		return false
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.clients[nickname]; exists {
+	if _, exists := r.clients[r.nickKey(nickname)]; exists {
 		return false
 	}
 
 	// Reserve with a nil client temporarily - will be replaced by actual client on Join
-	r.clients[nickname] = nil
+	r.clients[r.nickKey(nickname)] = nil
 	return true
 }
 
@@ -244,8 +993,8 @@ func (r *Room) ReleaseNickname(nickname string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if client, exists := r.clients[nickname]; exists && client == nil {
-		delete(r.clients, nickname)
+	if client, exists := r.clients[r.nickKey(nickname)]; exists && client == nil {
+		delete(r.clients, r.nickKey(nickname))
 	}
 }
 
@@ -253,14 +1002,33 @@ func (r *Room) ReleaseNickname(nickname string) {
 func (r *Room) Stop() error {
 	// Cancel the context to signal the run loop to exit
 	r.cancel()
-	
+
 	// Wait for the run goroutine to finish
 	<-r.done
-	
-	// Close all channels
-	close(r.broadcast)
-	close(r.join)
-	close(r.leave)
-	
+
+	// Clients dropped during a final flush may still be leaving; with the
+	// context cancelled their Leave calls return at once
+	r.leavingMu.Lock()
+	r.stopping = true
+	r.leavingMu.Unlock()
+	r.leaving.Wait()
+
+	r.historyMu.RLock()
+	budget := r.historyBudget
+	r.historyMu.RUnlock()
+	if budget != nil {
+		budget.remove(r)
+	}
+
+	// Close any remaining subscriber channels
+	r.subMu.Lock()
+	for _, sub := range r.subscribers {
+		close(sub.ch)
+	}
+	r.subscribers = nil
+	r.subMu.Unlock()
+
+	// The request channels stay open: every sender selects on ctx, so a call
+	// racing Stop, or made after it, returns instead of panicking
 	return nil
-}
\ No newline at end of file
+}
@@ -1,6 +1,12 @@
 package chat
 
 import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -8,27 +14,27 @@ import (
 func TestNewRoom(t *testing.T) {
 	room := NewRoom("Test Room", 10, false, 0, false)
 	defer room.Stop()
-	
+
 	if room.Name != "Test Room" {
 		t.Errorf("Expected room name 'Test Room', got %s", room.Name)
 	}
-	
+
 	if room.MaxUsers != 10 {
 		t.Errorf("Expected max users 10, got %d", room.MaxUsers)
 	}
-	
+
 	if room.clients == nil {
 		t.Error("Expected clients map to be initialized")
 	}
-	
+
 	if room.broadcast == nil {
 		t.Error("Expected broadcast channel to be initialized")
 	}
-	
+
 	if room.join == nil {
 		t.Error("Expected join channel to be initialized")
 	}
-	
+
 	if room.leave == nil {
 		t.Error("Expected leave channel to be initialized")
 	}
@@ -36,16 +42,16 @@ func TestNewRoom(t *testing.T) {
 
 func TestRoomStop(t *testing.T) {
 	room := NewRoom("Test Room", 10, false, 0, false)
-	
+
 	// Give room time to start
 	time.Sleep(10 * time.Millisecond)
-	
+
 	// Stop the room
 	err := room.Stop()
 	if err != nil {
 		t.Errorf("Failed to stop room: %v", err)
 	}
-	
+
 	// Check that done channel is closed
 	select {
 	case <-room.done:
@@ -61,41 +67,738 @@ func TestMessageStruct(t *testing.T) {
 		From:      "Alice",
 		Content:   "Hello",
 		Timestamp: now,
-		IsSystem:  true,
-		IsAction:  false,
+		Kind:      KindSystem,
 	}
-	
+
 	if msg.From != "Alice" {
 		t.Errorf("Expected From='Alice', got %s", msg.From)
 	}
-	
+
 	if msg.Content != "Hello" {
 		t.Errorf("Expected Content='Hello', got %s", msg.Content)
 	}
-	
-	if !msg.IsSystem {
-		t.Error("Expected IsSystem=true")
+
+	if !msg.IsSystem() {
+		t.Error("Expected IsSystem()=true")
 	}
-	
-	if msg.IsAction {
-		t.Error("Expected IsAction=false")
+
+	if msg.IsAction() {
+		t.Error("Expected IsAction()=false")
 	}
 }
 
 func TestRoomChannels(t *testing.T) {
 	room := NewRoom("Test Room", 5, false, 0, false)
 	defer room.Stop()
-	
+
 	// Test that channels are properly initialized
 	if cap(room.broadcast) != 0 {
 		t.Errorf("Expected unbuffered broadcast channel, got capacity %d", cap(room.broadcast))
 	}
-	
+
 	if cap(room.join) != 0 {
 		t.Errorf("Expected unbuffered join channel, got capacity %d", cap(room.join))
 	}
-	
+
 	if cap(room.leave) != 0 {
 		t.Errorf("Expected unbuffered leave channel, got capacity %d", cap(room.leave))
 	}
-}
\ No newline at end of file
+}
+
+func TestRoomStats(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+
+	room.broadcastMessage(Message{From: "Alice", Content: "Hi", Timestamp: time.Now()})
+	room.broadcastMessage(Message{From: "Bob", Content: "Hey", Timestamp: time.Now()})
+	room.broadcastMessage(Message{From: "System", Content: "Bob has joined the room", Timestamp: time.Now(), Kind: KindJoin})
+
+	stats := room.Stats()
+	if stats.TotalMessages != 2 {
+		t.Errorf("Expected 2 total messages, got %d", stats.TotalMessages)
+	}
+
+	if stats.MessagesLastMinute != 2 {
+		t.Errorf("Expected 2 messages in the last minute, got %d", stats.MessagesLastMinute)
+	}
+
+	if stats.Online != 0 || stats.PeakOnline != 0 {
+		t.Errorf("Expected empty room, got online=%d peak=%d", stats.Online, stats.PeakOnline)
+	}
+}
+
+func TestRoomSubscribe(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+
+	events, unsubscribe := room.Subscribe()
+
+	room.Broadcast(Message{From: "Alice", Content: "Hello", Timestamp: time.Now()})
+
+	select {
+	case msg := <-events:
+		if msg.From != "Alice" || msg.Content != "Hello" {
+			t.Errorf("Unexpected message: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for subscribed message")
+	}
+
+	unsubscribe()
+	if _, ok := <-events; ok {
+		t.Error("Expected channel to be closed after unsubscribe")
+	}
+
+	// Unsubscribing twice must not panic
+	unsubscribe()
+}
+
+func TestRoomSlowSubscriberDropped(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+
+	events, unsubscribe := room.Subscribe()
+	defer unsubscribe()
+
+	// Overflow the buffer without reading; broadcasts must not block. The extra
+	// broadcast ensures the overflowing one has been processed before we read.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBuffer+2; i++ {
+			room.Broadcast(Message{From: "Alice", Content: "spam", Timestamp: time.Now()})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Broadcast blocked on a slow subscriber")
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+
+	if received != subscriberBuffer {
+		t.Errorf("Expected %d buffered messages before drop, got %d", subscriberBuffer, received)
+	}
+}
+
+func TestRoomJoinLeaveOrdering(t *testing.T) {
+	room := NewRoom("Test Room", 50, false, 0, false)
+	defer room.Stop()
+
+	events, unsubscribe := room.Subscribe()
+	defer unsubscribe()
+
+	const rounds = 20
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < rounds; i++ {
+			client := NewTUIClient(nil, room)
+			client.Nickname = fmt.Sprintf("user%d", i)
+			room.Join(client)
+			room.Broadcast(Message{From: client.Nickname, Content: "hi", Timestamp: time.Now()})
+			room.Leave(client)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Deadlock: rapid join/leave did not complete")
+	}
+
+	for i := 0; i < rounds; i++ {
+		nick := fmt.Sprintf("user%d", i)
+		want := []string{
+			nick + " has joined the room",
+			"hi",
+			nick + " has left the room",
+		}
+		for _, content := range want {
+			select {
+			case msg := <-events:
+				if msg.Content != content {
+					t.Fatalf("Expected %q, got %q", content, msg.Content)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Timed out waiting for %q", content)
+			}
+		}
+	}
+
+	if users := room.GetUserList(); len(users) != 0 {
+		t.Errorf("Expected empty room, got %v", users)
+	}
+}
+
+func TestRoomOptions(t *testing.T) {
+	tests := []struct {
+		name          string
+		enableHistory bool
+		historySize   int
+		plainText     bool
+		broadcasts    int
+		wantHistory   int
+	}{
+		{"history disabled", false, 10, false, 3, 0},
+		{"history enabled", true, 10, false, 3, 3},
+		{"history trimmed to size", true, 2, false, 5, 2},
+		{"plain text with history", true, 10, true, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := NewRoom("Test Room", 5, tt.enableHistory, tt.historySize, tt.plainText)
+			defer room.Stop()
+
+			if room.PlainText != tt.plainText {
+				t.Errorf("Expected PlainText=%v, got %v", tt.plainText, room.PlainText)
+			}
+
+			client := NewTUIClient(nil, room)
+			if client.usePlainText() != tt.plainText {
+				t.Errorf("Expected new client plainText=%v, got %v", tt.plainText, client.usePlainText())
+			}
+
+			for i := 0; i < tt.broadcasts; i++ {
+				room.broadcastMessage(Message{From: "Alice", Content: fmt.Sprintf("msg%d", i), Timestamp: time.Now()})
+			}
+
+			history := room.GetHistory()
+			if len(history) != tt.wantHistory {
+				t.Fatalf("Expected %d history entries, got %d", tt.wantHistory, len(history))
+			}
+
+			// Trimming keeps the newest messages
+			if tt.wantHistory > 0 {
+				last := history[len(history)-1].Content
+				if want := fmt.Sprintf("msg%d", tt.broadcasts-1); last != want {
+					t.Errorf("Expected newest entry %q, got %q", want, last)
+				}
+			}
+		})
+	}
+}
+
+func TestRoomReservations(t *testing.T) {
+	tests := []struct {
+		name      string
+		steps     func(r *Room) bool
+		wantFree  bool
+		wantUsers int
+	}{
+		{
+			name:      "reserve blocks nickname",
+			steps:     func(r *Room) bool { return r.ReserveNickname("alice") },
+			wantFree:  false,
+			wantUsers: 0,
+		},
+		{
+			name: "duplicate reservation fails",
+			steps: func(r *Room) bool {
+				r.ReserveNickname("alice")
+				return !r.ReserveNickname("alice")
+			},
+			wantFree:  false,
+			wantUsers: 0,
+		},
+		{
+			name: "release frees nickname",
+			steps: func(r *Room) bool {
+				r.ReserveNickname("alice")
+				r.ReleaseNickname("alice")
+				return true
+			},
+			wantFree:  true,
+			wantUsers: 0,
+		},
+		{
+			name: "release ignores joined client",
+			steps: func(r *Room) bool {
+				r.ReserveNickname("alice")
+				client := NewTUIClient(nil, r)
+				client.Nickname = "alice"
+				r.Join(client)
+				r.ReleaseNickname("alice")
+				return !client.fullRoomRejection
+			},
+			wantFree:  false,
+			wantUsers: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := NewRoom("Test Room", 5, false, 0, false)
+			defer room.Stop()
+
+			if !tt.steps(room) {
+				t.Fatal("Reservation steps did not behave as expected")
+			}
+
+			if free := room.IsNicknameAvailable("alice"); free != tt.wantFree {
+				t.Errorf("Expected IsNicknameAvailable=%v, got %v", tt.wantFree, free)
+			}
+
+			if users := room.GetUserList(); len(users) != tt.wantUsers {
+				t.Errorf("Expected %d users, got %v", tt.wantUsers, users)
+			}
+		})
+	}
+}
+
+func TestRoomReplaceSession(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+
+	old := NewTUIClient(nil, room)
+	old.Nickname = "alice"
+	old.Identity = "alice@example.com"
+	room.ReserveNickname("alice")
+	room.Join(old)
+
+	events, unsubscribe := room.Subscribe()
+	defer unsubscribe()
+
+	if room.ReplaceSession("bob@example.com") {
+		t.Error("Expected no session to replace for an unknown identity")
+	}
+
+	if !room.ReplaceSession("alice@example.com") {
+		t.Fatal("Expected previous session to be replaced")
+	}
+
+	if !room.IsNicknameAvailable("alice") {
+		t.Error("Expected nickname to be free after the session was replaced")
+	}
+
+	select {
+	case msg := <-events:
+		if want := "alice has left the room (connected from another location)"; msg.Content != want {
+			t.Errorf("Expected %q, got %q", want, msg.Content)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for leave notice")
+	}
+
+	if room.ReplaceSession("alice@example.com") {
+		t.Error("Expected nothing to replace once the old session is gone")
+	}
+}
+
+func TestRoomDuplicateIdentityWithoutReplace(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	old := NewTUIClient(nil, room)
+	old.Nickname = "alice"
+	old.Identity = "alice@example.com"
+	room.ReserveNickname("alice")
+	room.Join(old)
+
+	// Without single-session the reconnecting user just sees "nickname taken"
+	server, remote := net.Pipe()
+	defer remote.Close()
+	go drain(remote)
+	go remote.Write([]byte("alice\nalice2\n"))
+
+	client, err := NewPlainTextClient(server, room, ClientOptions{PlainText: true, Identity: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer client.close()
+
+	if client.Nickname != "alice2" {
+		t.Errorf("Expected fallback nickname alice2, got %q", client.Nickname)
+	}
+
+	if users := room.GetUserList(); len(users) != 2 {
+		t.Errorf("Expected both sessions in the room, got %v", users)
+	}
+}
+
+func TestLeaveReleasesUnjoinedReservation(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, false)
+	defer room.Stop()
+
+	client := NewTUIClient(nil, room)
+	client.Nickname = "alice"
+	room.ReserveNickname("alice")
+
+	room.Leave(client)
+
+	if !room.IsNicknameAvailable("alice") {
+		t.Error("Expected reservation to be released when a client leaves before joining")
+	}
+}
+
+func TestRoomJoinLeaveCoalesce(t *testing.T) {
+	room := NewRoom("Test Room", 50, false, 0, false)
+	room.JoinLeaveCoalesce = 100 * time.Millisecond
+	defer room.Stop()
+
+	events, unsubscribe := room.Subscribe()
+	defer unsubscribe()
+
+	// Simulate churn: five joins, two of which leave again
+	clients := make([]*Client, 5)
+	for i := range clients {
+		clients[i] = NewTUIClient(nil, room)
+		clients[i].Nickname = fmt.Sprintf("user%d", i)
+		room.Join(clients[i])
+	}
+	room.Leave(clients[0])
+	room.Leave(clients[1])
+
+	// The user map updates immediately even though notices are batched
+	if users := room.GetUserList(); len(users) != 3 {
+		t.Errorf("Expected 3 users right away, got %v", users)
+	}
+
+	select {
+	case msg := <-events:
+		if msg.Content != "5 users joined, 2 left" {
+			t.Errorf("Expected coalesced summary, got %q", msg.Content)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for coalesced notice")
+	}
+
+	select {
+	case msg := <-events:
+		t.Errorf("Expected a single summary, got extra message %q", msg.Content)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// A lone event within a window keeps its individual notice
+	room.Leave(clients[2])
+	select {
+	case msg := <-events:
+		if msg.Content != "user2 has left the room" {
+			t.Errorf("Expected individual notice, got %q", msg.Content)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for single notice")
+	}
+}
+
+func TestRoomCoalesceFlushesBeforeMessages(t *testing.T) {
+	room := NewRoom("Test Room", 50, false, 0, false)
+	room.JoinLeaveCoalesce = time.Hour
+	defer room.Stop()
+
+	events, unsubscribe := room.Subscribe()
+	defer unsubscribe()
+
+	client := NewTUIClient(nil, room)
+	client.Nickname = "alice"
+	room.Join(client)
+	room.Broadcast(Message{From: "alice", Content: "hi", Timestamp: time.Now()})
+
+	for _, want := range []string{"alice has joined the room", "hi"} {
+		select {
+		case msg := <-events:
+			if msg.Content != want {
+				t.Errorf("Expected %q, got %q", want, msg.Content)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for %q", want)
+		}
+	}
+}
+
+func TestGetUserListSortedWithoutReservations(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, false)
+	defer room.Stop()
+
+	for _, nickname := range []string{"carol", "Bob", "alice", "dave"} {
+		client := NewTUIClient(nil, room)
+		client.Nickname = nickname
+		room.ReserveNickname(nickname)
+		room.Join(client)
+	}
+
+	// Reserved while choosing a nickname, but not joined yet
+	room.ReserveNickname("eve")
+
+	want := []string{"alice", "Bob", "carol", "dave"}
+	if users := room.GetUserList(); !reflect.DeepEqual(users, want) {
+		t.Errorf("GetUserList() = %v, want %v", users, want)
+	}
+}
+
+func TestReservationNotCountedOnline(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, false)
+	defer room.Stop()
+
+	client := NewTUIClient(nil, room)
+	client.Nickname = "alice"
+	room.ReserveNickname("alice")
+
+	if n := room.OnlineCount(); n != 0 {
+		t.Errorf("Expected reservation not to count as online, got %d", n)
+	}
+	if stats := room.Stats(); stats.Online != 0 || stats.PeakOnline != 0 {
+		t.Errorf("Expected no one online in stats, got %+v", stats)
+	}
+	if users := room.GetUserList(); len(users) != 0 {
+		t.Errorf("Expected reservation to be hidden from the user list, got %v", users)
+	}
+
+	room.Join(client)
+
+	if n := room.OnlineCount(); n != 1 {
+		t.Errorf("Expected joined client to count as online, got %d", n)
+	}
+	if users := room.GetUserList(); !reflect.DeepEqual(users, []string{"alice"}) {
+		t.Errorf("GetUserList() = %v, want [alice]", users)
+	}
+}
+
+// lockedBufferConn is a bufferConn safe to read while join notices are still
+// being written by other goroutines
+type lockedBufferConn struct {
+	net.Conn
+	mu  sync.Mutex
+	out strings.Builder
+}
+
+func (c *lockedBufferConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.Write(p)
+}
+
+func (c *lockedBufferConn) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.String()
+}
+
+func TestBroadcastSyncDelivers(t *testing.T) {
+	room := NewRoom("Test Room", 5, true, 10, true)
+	defer room.Stop()
+
+	var conns []*lockedBufferConn
+	for _, nickname := range []string{"alice", "bob"} {
+		conn := &lockedBufferConn{}
+		client := &Client{Nickname: nickname, conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+		room.Join(client)
+		conns = append(conns, conn)
+	}
+
+	room.BroadcastSync(Message{From: "carol", Content: "exactly once", Timestamp: time.Now()})
+
+	// No sleeping: BroadcastSync returns only after every client has written it
+	for i, conn := range conns {
+		if got := strings.Count(conn.String(), "carol: exactly once"); got != 1 {
+			t.Errorf("Client %d received the message %d times, want 1: %q", i, got, conn.String())
+		}
+	}
+	if history := room.GetHistory(); len(history) == 0 || history[len(history)-1].Content != "exactly once" {
+		t.Errorf("Expected the message in history right away, got %v", history)
+	}
+}
+
+func TestFlushWritesEarlierBroadcasts(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	conn := &lockedBufferConn{}
+	client := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+	room.Join(client)
+
+	for i := range 20 {
+		room.Broadcast(Message{From: "bob", Content: fmt.Sprintf("line %d", i), Timestamp: time.Now()})
+	}
+	if !room.Flush(time.Second) {
+		t.Fatal("Flush() = false, want every client flushed")
+	}
+
+	// No sleeping: Flush returns only after the writer has caught up
+	out := conn.String()
+	for i := range 20 {
+		if !strings.Contains(out, fmt.Sprintf("bob: line %d", i)) {
+			t.Errorf("Expected line %d to be written before Flush returned, got %q", i, out)
+		}
+	}
+}
+
+// stuckConn is a connection whose writes block until release is closed
+type stuckConn struct {
+	net.Conn
+	release chan struct{}
+}
+
+func (c *stuckConn) Write(p []byte) (int, error) {
+	<-c.release
+	return len(p), nil
+}
+
+func TestFlushGivesUpAfterTimeout(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	conn := &stuckConn{release: make(chan struct{})}
+	defer close(conn.release)
+	client := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+	room.Join(client)
+	room.Broadcast(Message{From: "bob", Content: "hello", Timestamp: time.Now()})
+
+	start := time.Now()
+	if room.Flush(50 * time.Millisecond) {
+		t.Error("Flush() = true, want false for a client that can't write")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Flush() took %s, want about the 50ms timeout", elapsed)
+	}
+}
+
+func TestBroadcastSyncAfterStop(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	room.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		room.BroadcastSync(Message{From: "alice", Content: "hi"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("BroadcastSync blocked on a stopped room")
+	}
+}
+
+func TestNickCaseFold(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	room.NickCaseFold = true
+	defer room.Stop()
+
+	alice := NewTUIClient(nil, room)
+	alice.Nickname = "Alice"
+	if !room.ReserveNickname("Alice") {
+		t.Fatal("Expected to reserve Alice")
+	}
+	room.Join(alice)
+
+	for _, variant := range []string{"alice", "ALICE", "aLiCe"} {
+		if room.ReserveNickname(variant) {
+			t.Errorf("Expected %q to be taken by Alice", variant)
+		}
+		if room.IsNicknameAvailable(variant) {
+			t.Errorf("IsNicknameAvailable(%q) = true, want false", variant)
+		}
+	}
+	if got := room.GetClient("alice"); got != alice {
+		t.Errorf("GetClient(alice) = %v, want Alice's client", got)
+	}
+	if users := room.GetUserList(); !reflect.DeepEqual(users, []string{"Alice"}) {
+		t.Errorf("GetUserList() = %v, want the display case kept", users)
+	}
+
+	msg, _, err := sendPrivate(room, "bob", "ALICE,alice psst")
+	if err != nil {
+		t.Fatalf("sendPrivate() error = %v", err)
+	}
+	if !reflect.DeepEqual(msg.To, []string{"Alice"}) {
+		t.Errorf("Private message recipients = %v, want [Alice] once", msg.To)
+	}
+
+	// A line-mode user typing a case variant is asked again
+	server, remote := net.Pipe()
+	defer remote.Close()
+	go drain(remote)
+	go remote.Write([]byte("alice\nbob\n"))
+	client, err := NewPlainTextClient(server, room, ClientOptions{PlainText: true})
+	if err != nil {
+		t.Fatalf("NewPlainTextClient() error = %v", err)
+	}
+	defer client.close()
+	if client.Nickname != "bob" {
+		t.Errorf("Nickname = %q, want bob after alice was refused", client.Nickname)
+	}
+
+	room.Leave(alice)
+	if !room.ReserveNickname("alice") {
+		t.Error("Expected alice to be free once Alice left")
+	}
+}
+
+func TestNicknamesCaseSensitiveByDefault(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	if !room.ReserveNickname("Alice") || !room.ReserveNickname("alice") {
+		t.Error("Expected Alice and alice to be distinct without NickCaseFold")
+	}
+}
+
+// TestStopDuringTraffic stops a room while other goroutines keep joining,
+// leaving, broadcasting and subscribing. None may panic or block once the room
+// has stopped.
+func TestStopDuringTraffic(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		room := NewRoom("Test Room", 50, true, 10, true)
+
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				for j := 0; j < 50; j++ {
+					msg := Message{From: "alice", Content: fmt.Sprintf("msg %d-%d", i, j), Timestamp: time.Now()}
+					switch j % 4 {
+					case 0:
+						room.Broadcast(msg)
+					case 1:
+						room.BroadcastSync(msg)
+					case 2:
+						client := NewTUIClient(nil, room)
+						client.Nickname = fmt.Sprintf("user%d-%d", i, j)
+						room.Join(client)
+						room.Leave(client)
+					case 3:
+						msgs, unsubscribe := room.Subscribe()
+						unsubscribe()
+						for range msgs {
+						}
+					}
+				}
+			}(i)
+		}
+
+		close(start)
+		time.Sleep(time.Duration(round%5) * time.Millisecond)
+		if err := room.Stop(); err != nil {
+			t.Fatalf("Stop() error = %v", err)
+		}
+
+		finished := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(finished)
+		}()
+		select {
+		case <-finished:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Round %d: senders still blocked after Stop", round)
+		}
+
+		// Calls after Stop return at once, and subscriptions come back closed
+		room.Broadcast(Message{From: "alice", Content: "late"})
+		room.Leave(NewTUIClient(nil, room))
+		msgs, unsubscribe := room.Subscribe()
+		if _, ok := <-msgs; ok {
+			t.Error("Expected a subscription after Stop to be closed")
+		}
+		unsubscribe()
+	}
+}
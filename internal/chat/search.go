@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxSearchResults caps how many matches /search shows
+	MaxSearchResults = 20
+	// searchSnippetLen is the longest message text shown in a search result
+	searchSnippetLen = 80
+)
+
+// SearchHistory returns up to limit of the newest history messages whose text
+// contains term, ignoring case, sent at or after since (zero matches all).
+// Results are ordered oldest to newest.
+func (r *Room) SearchHistory(term string, since time.Time, limit int) []Message {
+	term = strings.ToLower(term)
+
+	r.historyMu.RLock()
+	all := r.history.last(r.history.len())
+	r.historyMu.RUnlock()
+
+	var matches []Message
+	for i := len(all) - 1; i >= 0 && len(matches) < limit; i-- {
+		msg := all[i]
+		if msg.Timestamp.Before(since) {
+			break
+		}
+		if msg.IsPresence() {
+			continue
+		}
+		if strings.Contains(strings.ToLower(msg.Content), term) {
+			matches = append(matches, msg)
+		}
+	}
+
+	// Collected newest first; flip to read in order
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+	return matches
+}
+
+// parseSearch splits "/search <term> [--since <duration>]" arguments
+func parseSearch(args string) (string, time.Duration, error) {
+	term := strings.TrimSpace(args)
+	var window time.Duration
+
+	if before, after, ok := strings.Cut(term, "--since"); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(after))
+		if err != nil || d <= 0 {
+			return "", 0, fmt.Errorf("invalid --since duration %q", strings.TrimSpace(after))
+		}
+		term, window = strings.TrimSpace(before), d
+	}
+
+	if term == "" {
+		return "", 0, errors.New("usage: /search <term> [--since 1h]")
+	}
+	return term, window, nil
+}
+
+// search handles "/search <term> [--since 1h]" and returns the results for
+// the requester alone
+func (c *Client) search(args string, now time.Time) (string, error) {
+	if !c.room.enableHistory {
+		return "Search is unavailable because history is disabled.", errors.New("history disabled")
+	}
+
+	term, window, err := parseSearch(args)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), err
+	}
+
+	var since time.Time
+	if window > 0 {
+		since = now.Add(-window)
+	}
+
+	matches := c.room.SearchHistory(term, since, MaxSearchResults)
+	if len(matches) == 0 {
+		return fmt.Sprintf("No messages matching %q.", term), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Messages matching %q (%d):", term, len(matches))
+	for _, msg := range matches {
+		fmt.Fprintf(&b, "\n  [%s] %s: %s", messageLabel(msg), msg.From, searchSnippet(msg.Content))
+	}
+	return b.String(), nil
+}
+
+// searchSnippet condenses a matched message to one line of bounded length
+func searchSnippet(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > searchSnippetLen {
+		return string(runes[:searchSnippetLen]) + "..."
+	}
+	return text
+}
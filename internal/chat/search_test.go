@@ -0,0 +1,114 @@
+package chat
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSearchRoom returns a history-enabled room seeded with msgs
+func newSearchRoom(t *testing.T, msgs ...Message) *Room {
+	t.Helper()
+	room := NewRoom("Test Room", 10, true, 50, false)
+	t.Cleanup(func() { room.Stop() })
+	room.historyMu.Lock()
+	for _, msg := range msgs {
+		room.history.add(msg)
+	}
+	room.historyMu.Unlock()
+	return room
+}
+
+func TestSearchHistoryTermMatching(t *testing.T) {
+	now := time.Now()
+	room := newSearchRoom(t,
+		Message{From: "alice", Content: "Deploy at 5pm", Timestamp: now, Kind: KindUser},
+		Message{From: "System", Content: "deployer has joined the room", Timestamp: now, Kind: KindJoin},
+		Message{From: "bob", Content: "lunch?", Timestamp: now, Kind: KindUser},
+		Message{From: "carol", Content: "the DEPLOY failed", Timestamp: now, Kind: KindUser},
+	)
+
+	got := room.SearchHistory("deploy", time.Time{}, 10)
+	if len(got) != 2 || got[0].From != "alice" || got[1].From != "carol" {
+		t.Fatalf("SearchHistory(deploy) = %v, want alice then carol", got)
+	}
+
+	if got := room.SearchHistory("deploy", time.Time{}, 1); len(got) != 1 || got[0].From != "carol" {
+		t.Errorf("SearchHistory with limit 1 = %v, want only the newest match", got)
+	}
+	if got := room.SearchHistory("nothing", time.Time{}, 10); len(got) != 0 {
+		t.Errorf("SearchHistory(nothing) = %v, want no matches", got)
+	}
+}
+
+func TestSearchHistorySinceBoundary(t *testing.T) {
+	since := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	room := newSearchRoom(t,
+		Message{From: "alice", Content: "old news", Timestamp: since.Add(-time.Nanosecond), Kind: KindUser},
+		Message{From: "bob", Content: "news at the boundary", Timestamp: since, Kind: KindUser},
+		Message{From: "carol", Content: "fresh news", Timestamp: since.Add(time.Minute), Kind: KindUser},
+	)
+
+	got := room.SearchHistory("news", since, 10)
+	if len(got) != 2 || got[0].From != "bob" || got[1].From != "carol" {
+		t.Errorf("SearchHistory since boundary = %v, want bob and carol", got)
+	}
+}
+
+func TestClientSearch(t *testing.T) {
+	now := time.Now()
+	long := strings.Repeat("x", 200) + " needle"
+	room := newSearchRoom(t,
+		Message{ID: 1, From: "alice", Content: "needle in a haystack", Timestamp: now.Add(-2 * time.Hour), Kind: KindUser},
+		Message{ID: 2, From: "bob", Content: long, Timestamp: now.Add(-time.Minute), Kind: KindUser},
+	)
+	client := NewTUIClient(nil, room)
+
+	reply, err := client.search("needle --since 1h", now)
+	if err != nil {
+		t.Fatalf("search() error = %v", err)
+	}
+	if strings.Contains(reply, "alice") || !strings.Contains(reply, "bob: ") {
+		t.Errorf("search() with --since = %q, want only bob's message", reply)
+	}
+	if strings.Contains(reply, "needle\n") || len(reply) > 200 {
+		t.Errorf("search() did not truncate the long match: %q", reply)
+	}
+
+	if _, err := client.search("", now); err == nil {
+		t.Error("search() with no term should fail")
+	}
+	if _, err := client.search("needle --since soon", now); err == nil {
+		t.Error("search() with a bad --since should fail")
+	}
+}
+
+func TestSearchCommandUsesRoomClock(t *testing.T) {
+	clock := newFakeClock()
+	room := newSearchRoom(t,
+		Message{From: "alice", Content: "old news", Timestamp: clock.Now().Add(-30 * time.Minute), Kind: KindUser},
+		Message{From: "bob", Content: "fresh news", Timestamp: clock.Now().Add(-5 * time.Minute), Kind: KindUser},
+	)
+	room.Clock = clock
+
+	conn := &lockedBufferConn{}
+	carol := &Client{Nickname: "carol", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+	if err := carol.handleCommand("/search news --since 10m"); err != nil {
+		t.Fatalf("/search error = %v", err)
+	}
+	carol.flush()
+	if out := conn.String(); !strings.Contains(out, "fresh news") || strings.Contains(out, "old news") {
+		t.Errorf("/search wrote %q, want only the message within 10m of the room clock", out)
+	}
+
+	dave := NewTUIClient(nil, room)
+	dave.Nickname = "dave"
+	model := NewChatModel(dave)
+	model.initViewport()
+	updated, _ := model.handleCommand("/search news --since 10m")
+	model = *updated.(*ChatModel)
+	if got := model.messages[len(model.messages)-1].Content; !strings.Contains(got, "fresh news") || strings.Contains(got, "old news") {
+		t.Errorf("TUI /search = %q, want only the message within 10m of the room clock", got)
+	}
+}
@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxLastSeen caps how many departed users /seen remembers; the one seen
+// longest ago is forgotten first
+const maxLastSeen = 1000
+
+// lastSeen is when a user last left the room, with their nickname as shown
+type lastSeen struct {
+	nickname string
+	at       time.Time
+}
+
+// recordSeenLocked remembers that nickname left at at; r.mu must be held for writing
+func (r *Room) recordSeenLocked(nickname string, at time.Time) {
+	if r.lastSeen == nil {
+		r.lastSeen = make(map[string]lastSeen)
+	}
+	key := r.nickKey(nickname)
+	if _, ok := r.lastSeen[key]; !ok && len(r.lastSeen) >= maxLastSeen {
+		var oldest string
+		for k, seen := range r.lastSeen {
+			if oldest == "" || seen.at.Before(r.lastSeen[oldest].at) {
+				oldest = k
+			}
+		}
+		delete(r.lastSeen, oldest)
+	}
+	r.lastSeen[key] = lastSeen{nickname: nickname, at: at}
+}
+
+// LastSeen returns when nickname last left the room, and the nickname as it
+// was shown, or false if they haven't left since the server started
+func (r *Room) LastSeen(nickname string) (string, time.Time, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen, ok := r.lastSeen[r.nickKey(nickname)]
+	return seen.nickname, seen.at, ok
+}
+
+// seen answers "/seen <nickname>"
+func (c *Client) seen(arg string, now time.Time) (string, error) {
+	nickname := strings.TrimSpace(arg)
+	if nickname == "" {
+		return "Usage: /seen <nickname>", errors.New("invalid /seen command usage")
+	}
+
+	if c.room.sameNick(nickname, c.Nickname) {
+		return "You're right here.", nil
+	}
+	if client := c.room.GetClient(nickname); client != nil || c.room.isRemoteUser(nickname) {
+		if client != nil {
+			nickname = client.Nickname
+		}
+		return fmt.Sprintf("%s is online now.", nickname), nil
+	}
+
+	name, at, ok := c.room.LastSeen(nickname)
+	if !ok {
+		return fmt.Sprintf("%s hasn't been seen since the server started.", nickname), nil
+	}
+
+	when := at.Format("15:04:05")
+	if now.Sub(at) >= 24*time.Hour {
+		when = at.Format("Jan 2 15:04")
+	}
+	ago := now.Sub(at).Round(time.Second)
+	if ago >= time.Hour {
+		ago = ago.Round(time.Minute)
+	}
+	seen := fmt.Sprintf("%s (%s ago)", when, ago)
+
+	if c.usePlainText() {
+		return c.room.theme().FormatSeenPlain(name, seen), nil
+	}
+	return c.room.theme().FormatSeen(name, seen), nil
+}
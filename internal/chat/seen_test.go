@@ -0,0 +1,120 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeen(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	clock := newFakeClock()
+	room.Clock = clock
+
+	alice := NewTUIClient(nil, room)
+	alice.Nickname = "alice"
+	room.Join(alice)
+	bob := NewTUIClient(nil, room)
+	bob.Nickname = "bob"
+	room.Join(bob)
+
+	if got, _ := bob.seen("alice", clock.Now()); got != "alice is online now." {
+		t.Errorf("seen(alice) while online = %q", got)
+	}
+
+	left := clock.Now()
+	room.Leave(alice)
+	clock.Advance(3 * time.Minute)
+
+	got, err := bob.seen("alice", clock.Now())
+	if err != nil {
+		t.Fatalf("seen(alice) error = %v", err)
+	}
+	want := "alice was last seen " + left.Format("15:04:05") + " (3m0s ago)."
+	if !strings.Contains(got, want) {
+		t.Errorf("seen(alice) = %q, want it to contain %q", got, want)
+	}
+
+	clock.Advance(25 * time.Hour)
+	got, _ = bob.seen("alice", clock.Now())
+	if !strings.Contains(got, left.Format("Jan 2 15:04")+" (25h3m0s ago)") {
+		t.Errorf("seen(alice) a day later = %q, want the date", got)
+	}
+}
+
+func TestSeenCommandUsesRoomClock(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	clock := newFakeClock()
+	room.Clock = clock
+
+	alice := NewTUIClient(nil, room)
+	alice.Nickname = "alice"
+	room.Join(alice)
+	room.Leave(alice)
+	clock.Advance(3 * time.Minute)
+
+	conn := &lockedBufferConn{}
+	bob := &Client{Nickname: "bob", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+	room.Join(bob)
+	room.Flush(time.Second) // Let the join notice land before reading output
+
+	if err := bob.handleCommand("/seen alice"); err != nil {
+		t.Fatalf("/seen error = %v", err)
+	}
+	bob.flush()
+	if out := conn.String(); !strings.Contains(out, "(3m0s ago)") {
+		t.Errorf("/seen wrote %q, want the time measured on the room clock", out)
+	}
+
+	carol := NewTUIClient(nil, room)
+	carol.Nickname = "carol"
+	model := NewChatModel(carol)
+	model.initViewport()
+	updated, _ := model.handleCommand("/seen alice")
+	model = *updated.(*ChatModel)
+	if got := model.messages[len(model.messages)-1].Content; !strings.Contains(got, "(3m0s ago)") {
+		t.Errorf("TUI /seen = %q, want the time measured on the room clock", got)
+	}
+}
+
+func TestSeenEdgeCases(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	bob := NewTUIClient(nil, room)
+	bob.Nickname = "bob"
+	room.Join(bob)
+
+	if _, err := bob.seen("  ", time.Now()); err == nil {
+		t.Error("Expected an error for /seen without a nickname")
+	}
+	if got, _ := bob.seen("bob", time.Now()); got != "You're right here." {
+		t.Errorf("seen(self) = %q", got)
+	}
+	if got, _ := bob.seen("carol", time.Now()); !strings.Contains(got, "hasn't been seen") {
+		t.Errorf("seen(unknown) = %q", got)
+	}
+}
+
+func TestSeenForgetsOldest(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	start := time.Now()
+	room.mu.Lock()
+	for i := 0; i <= maxLastSeen; i++ {
+		room.recordSeenLocked(fmt.Sprintf("user%d", i), start.Add(time.Duration(i)*time.Second))
+	}
+	room.mu.Unlock()
+
+	if _, _, ok := room.LastSeen("user0"); ok {
+		t.Error("Expected the user seen longest ago to be forgotten")
+	}
+	if _, _, ok := room.LastSeen(fmt.Sprintf("user%d", maxLastSeen)); !ok {
+		t.Error("Expected the newest departure to be remembered")
+	}
+}
@@ -0,0 +1,183 @@
+package chat
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Telnet protocol bytes used by the terminal-type probe and window size
+// negotiation (RFC 854, RFC 1091, RFC 1073)
+const (
+	telnetIAC       = 255
+	telnetSB        = 250
+	telnetSE        = 240
+	telnetWill      = 251
+	telnetWont      = 252
+	telnetDo        = 253
+	optEcho         = 1
+	optTerminalType = 24
+	optNAWS         = 31
+	ttypeIs         = 0
+	ttypeSend       = 1
+)
+
+// terminalProbeTimeout bounds how long we wait for a client to answer the probe
+const terminalProbeTimeout = 250 * time.Millisecond
+
+// dumbTerminals lists terminal types that cannot be trusted with ANSI sequences
+var dumbTerminals = map[string]bool{
+	"dumb":    true,
+	"unknown": true,
+	"network": true,
+}
+
+// Terminal is what ProbeTerminal learned about a client
+type Terminal struct {
+	PlainText bool // the terminal type can't be trusted with ANSI sequences
+	Telnet    bool // the client answered telnet negotiation, so IAC bytes are safe to send
+}
+
+// ProbeTerminal asks a telnet client for its terminal type and reports whether
+// it should be served plain text. Clients that don't answer (e.g. netcat) are
+// assumed to be capable terminals. A client that opens with an HTTP request or
+// binary data instead is sent a short notice and ErrProtocolProbe is returned.
+// Anything the user typed during the probe is replayed by the returned conn,
+// which should be used in place of conn from then on.
+func ProbeTerminal(conn net.Conn) (net.Conn, Terminal, error) {
+	termType, received, ok := probeTerminalType(conn)
+	typed := typedInput(received)
+	if len(typed) > 0 {
+		conn = &replayConn{Conn: conn, reader: io.MultiReader(bytes.NewReader(typed), conn)}
+	}
+	if reply, probe := classifyProbe(typed); probe {
+		conn.Write([]byte(reply))
+		return conn, Terminal{}, ErrProtocolProbe
+	}
+	// Any IAC in the reply means a telnet client, even one that refused TTYPE
+	term := Terminal{Telnet: bytes.IndexByte(received, telnetIAC) >= 0}
+	if ok {
+		term.PlainText = dumbTerminals[strings.ToLower(termType)]
+	}
+	return conn, term, nil
+}
+
+// replayConn reads bytes already taken off the connection before reading more
+type replayConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func (c *replayConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// typedInput returns what the user typed among the probe bytes, without the
+// telnet negotiation
+func typedInput(received []byte) []byte {
+	typed := make([]byte, len(received))
+	n, _ := (&telnetFilterReader{reader: bytes.NewReader(received)}).Read(typed)
+	return typed[:n]
+}
+
+// probeTerminalType runs the TTYPE negotiation and returns the reported
+// terminal type along with everything the client sent meanwhile
+func probeTerminalType(conn net.Conn) (string, []byte, bool) {
+	if _, err := conn.Write([]byte{telnetIAC, telnetDo, optTerminalType}); err != nil {
+		return "", nil, false
+	}
+
+	deadline := time.Now().Add(terminalProbeTimeout)
+	conn.SetReadDeadline(deadline)
+	defer conn.SetReadDeadline(time.Time{}) // Clear deadline
+
+	var received []byte
+	requested := false
+	buf := make([]byte, 256)
+
+	for {
+		n, err := conn.Read(buf)
+		received = append(received, buf[:n]...)
+
+		if termType, ok := parseTerminalType(received); ok {
+			return termType, received, true
+		}
+
+		if bytes.Contains(received, []byte{telnetIAC, telnetWont, optTerminalType}) {
+			return "", received, false
+		}
+
+		// Not a telnet client; no need to wait out the deadline
+		if _, probe := classifyProbe(typedInput(received)); probe {
+			return "", received, false
+		}
+
+		if !requested && bytes.Contains(received, []byte{telnetIAC, telnetWill, optTerminalType}) {
+			requested = true
+			send := []byte{telnetIAC, telnetSB, optTerminalType, ttypeSend, telnetIAC, telnetSE}
+			if _, err := conn.Write(send); err != nil {
+				return "", received, false
+			}
+		}
+
+		if err != nil {
+			return "", received, false
+		}
+	}
+}
+
+// parseTerminalType extracts the name from an IAC SB TTYPE IS <name> IAC SE sequence
+func parseTerminalType(data []byte) (string, bool) {
+	start := bytes.Index(data, []byte{telnetIAC, telnetSB, optTerminalType, ttypeIs})
+	if start < 0 {
+		return "", false
+	}
+
+	rest := data[start+4:]
+	end := bytes.Index(rest, []byte{telnetIAC, telnetSE})
+	if end < 0 {
+		return "", false
+	}
+
+	return string(rest[:end]), true
+}
+
+// parseNAWS extracts the window size from an IAC SB NAWS <width> <height> IAC SE
+// sequence, where each dimension is two bytes, big-endian, with a 255 byte
+// doubled. A zero dimension means the client doesn't know it, so ok is only
+// true when both are set.
+func parseNAWS(data []byte) (width, height int, ok bool) {
+	start := bytes.Index(data, []byte{telnetIAC, telnetSB, optNAWS})
+	if start < 0 {
+		return 0, 0, false
+	}
+
+	var size []byte
+	rest := data[start+3:]
+	for i := 0; i < len(rest); i++ {
+		if rest[i] != telnetIAC {
+			size = append(size, rest[i])
+			continue
+		}
+		if i+1 >= len(rest) {
+			return 0, 0, false
+		}
+		switch rest[i+1] {
+		case telnetIAC:
+			size = append(size, telnetIAC)
+			i++
+		case telnetSE:
+			if len(size) != 4 {
+				return 0, 0, false
+			}
+			width = int(size[0])<<8 | int(size[1])
+			height = int(size[2])<<8 | int(size[3])
+			return width, height, width > 0 && height > 0
+		default:
+			return 0, 0, false
+		}
+	}
+	return 0, 0, false
+}
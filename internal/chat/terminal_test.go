@@ -0,0 +1,191 @@
+package chat
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseTerminalType(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		wantType string
+		wantOK   bool
+	}{
+		{"complete", []byte{255, 250, 24, 0, 'x', 't', 'e', 'r', 'm', 255, 240}, "xterm", true},
+		{"after other negotiation", []byte{255, 251, 24, 255, 250, 24, 0, 'D', 'U', 'M', 'B', 255, 240}, "DUMB", true},
+		{"truncated", []byte{255, 250, 24, 0, 'x', 't'}, "", false},
+		{"no subnegotiation", []byte("hello"), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseTerminalType(tt.data)
+			if got != tt.wantType || ok != tt.wantOK {
+				t.Errorf("parseTerminalType() = %q, %v; want %q, %v", got, ok, tt.wantType, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseNAWS(t *testing.T) {
+	tests := []struct {
+		name                  string
+		data                  []byte
+		wantWidth, wantHeight int
+		wantOK                bool
+	}{
+		{"80x24", []byte{255, 250, 31, 0, 80, 0, 24, 255, 240}, 80, 24, true},
+		{"after WILL NAWS", []byte{255, 251, 31, 255, 250, 31, 0, 200, 0, 50, 255, 240}, 200, 50, true},
+		{"escaped 255", []byte{255, 250, 31, 0, 255, 255, 0, 40, 255, 240}, 255, 40, true},
+		{"wide", []byte{255, 250, 31, 1, 44, 0, 60, 255, 240}, 300, 60, true},
+		{"unknown height", []byte{255, 250, 31, 0, 80, 0, 0, 255, 240}, 80, 0, false},
+		{"truncated", []byte{255, 250, 31, 0, 80, 0}, 0, 0, false},
+		{"short payload", []byte{255, 250, 31, 0, 80, 255, 240}, 0, 0, false},
+		{"terminal type instead", []byte{255, 250, 24, 0, 'x', 255, 240}, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height, ok := parseNAWS(tt.data)
+			if width != tt.wantWidth || height != tt.wantHeight || ok != tt.wantOK {
+				t.Errorf("parseNAWS() = %d, %d, %v; want %d, %d, %v", width, height, ok, tt.wantWidth, tt.wantHeight, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTelnetFilterReaderReportsResize(t *testing.T) {
+	input := append([]byte("hi"), 255, 250, 31, 0, 120, 0, 40, 255, 240)
+	input = append(input, []byte("!")...)
+
+	var width, height int
+	r := &telnetFilterReader{
+		reader:   strings.NewReader(string(input)),
+		onResize: func(w, h int) { width, height = w, h },
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(got) != "hi!" {
+		t.Errorf("Filtered input = %q, want the NAWS report stripped", got)
+	}
+	if width != 120 || height != 40 {
+		t.Errorf("onResize got %dx%d, want 120x40", width, height)
+	}
+}
+
+func TestProbeTerminal(t *testing.T) {
+	tests := []struct {
+		name     string
+		termType string
+		want     bool
+	}{
+		{"dumb terminal", "dumb", true},
+		{"unknown terminal", "UNKNOWN", true},
+		{"xterm", "xterm-256color", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer server.Close()
+			defer client.Close()
+
+			// Emulate a telnet client that answers the TTYPE negotiation
+			go func() {
+				buf := make([]byte, 16)
+				client.Read(buf) // IAC DO TTYPE
+				client.Write([]byte{255, 251, 24})
+				client.Read(buf) // IAC SB TTYPE SEND IAC SE
+				reply := append([]byte{255, 250, 24, 0}, tt.termType...)
+				client.Write(append(reply, 255, 240))
+			}()
+
+			_, term, err := ProbeTerminal(server)
+			if term.PlainText != tt.want || err != nil {
+				t.Errorf("ProbeTerminal() plain = %v, %v; want %v", term.PlainText, err, tt.want)
+			}
+			if !term.Telnet {
+				t.Error("Expected a client answering TTYPE to be detected as telnet")
+			}
+		})
+	}
+}
+
+func TestProbeTerminalSilentClient(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	// A raw TCP client (netcat) never answers; drain the probe so the write completes
+	go func() {
+		buf := make([]byte, 16)
+		client.Read(buf)
+	}()
+
+	_, term, err := ProbeTerminal(server)
+	if term.PlainText || err != nil {
+		t.Errorf("Expected silent client to be treated as ANSI-capable, got %v, %v", term.PlainText, err)
+	}
+	if term.Telnet {
+		t.Error("Expected silent client not to be detected as telnet")
+	}
+}
+
+func TestProbeTerminalKeepsTypedInput(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	// The user starts typing a nickname while the terminal type is negotiated
+	go func() {
+		buf := make([]byte, 16)
+		client.Read(buf) // IAC DO TTYPE
+		client.Write([]byte{'a', 'l', 255, 251, 24})
+		client.Read(buf) // IAC SB TTYPE SEND IAC SE
+		client.Write([]byte{255, 250, 24, 0, 'd', 'u', 'm', 'b', 255, 240, 'i', 'c', 'e', '\n'})
+	}()
+
+	conn, term, err := ProbeTerminal(server)
+	if !term.PlainText || err != nil {
+		t.Fatalf("ProbeTerminal() plain = %v, %v; want true", term.PlainText, err)
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+	if line != "alice\n" {
+		t.Errorf("Typed input = %q, want %q", line, "alice\n")
+	}
+}
+
+func TestProbeTerminalRejectsHTTP(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	// An HTTP client ignores the negotiation and sends its request line
+	reply := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 16)
+		client.Read(buf) // IAC DO TTYPE
+		client.Write([]byte("GET / HTTP/1.1\r\nHost: chat\r\n\r\n"))
+		out, _ := io.ReadAll(client)
+		reply <- string(out)
+	}()
+
+	if _, _, err := ProbeTerminal(server); !errors.Is(err, ErrProtocolProbe) {
+		t.Fatalf("Expected ErrProtocolProbe, got %v", err)
+	}
+	server.Close()
+	if got := <-reply; !strings.HasPrefix(got, "HTTP/1.0 400") {
+		t.Errorf("Expected an HTTP 400 reply, got %q", got)
+	}
+}
@@ -0,0 +1,81 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxTopicLength caps the length of a room topic in characters
+const MaxTopicLength = 200
+
+// RoomTopic is the current topic and who set it
+type RoomTopic struct {
+	Text  string
+	SetBy string
+	SetAt time.Time
+}
+
+// Topic returns the room's current topic; Text is empty when none is set
+func (r *Room) Topic() RoomTopic {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return RoomTopic{Text: r.topic, SetBy: r.topicSetBy, SetAt: r.topicSetAt}
+}
+
+// setTopic replaces the topic and records who changed it; empty text clears it
+func (r *Room) setTopic(text, setBy string, now time.Time) {
+	r.mu.Lock()
+	r.topic = text
+	r.topicSetBy = setBy
+	r.topicSetAt = now
+	r.mu.Unlock()
+}
+
+// String formats the topic with its audit trail, e.g.
+// "Topic (set by alice at 15:04): release planning"
+func (t RoomTopic) String() string {
+	if t.Text == "" {
+		return "No topic is set."
+	}
+	return fmt.Sprintf("Topic (set by %s at %s): %s", t.SetBy, t.SetAt.Format("15:04"), t.Text)
+}
+
+// changeTopic handles "/topic", "/topic <text>" and "/topic clear". Anyone can
+// view the topic; only admins can set or clear it.
+func (c *Client) changeTopic(args string, now time.Time) (string, error) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		return c.room.Topic().String(), nil
+	}
+
+	if !c.isAdmin() {
+		return "Permission denied: only admins can change the topic.", ErrPermissionDenied
+	}
+
+	if strings.EqualFold(args, "clear") {
+		c.room.setTopic("", c.Nickname, now)
+		c.room.Broadcast(Message{
+			From:      "System",
+			Content:   fmt.Sprintf("%s cleared the topic", c.Nickname),
+			Timestamp: now,
+			Kind:      KindSystem,
+		})
+		return "Topic cleared.", nil
+	}
+
+	if utf8.RuneCountInString(args) > MaxTopicLength {
+		return fmt.Sprintf("Topic is too long (max %d characters).", MaxTopicLength), errors.New("topic too long")
+	}
+
+	c.room.setTopic(args, c.Nickname, now)
+	c.room.Broadcast(Message{
+		From:      "System",
+		Content:   fmt.Sprintf("%s set the topic: %s", c.Nickname, args),
+		Timestamp: now,
+		Kind:      KindSystem,
+	})
+	return "Topic set.", nil
+}
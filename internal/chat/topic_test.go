@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTopicSetByMetadata(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, false)
+	t.Cleanup(func() { room.Stop() })
+
+	alice := NewTUIClient(nil, room)
+	alice.Nickname = "alice"
+	alice.admin = true
+	carol := NewTUIClient(nil, room)
+	carol.Nickname = "carol"
+	carol.admin = true
+
+	first := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	if _, err := alice.changeTopic("release planning", first); err != nil {
+		t.Fatalf("changeTopic: %v", err)
+	}
+	got := room.Topic()
+	if got.Text != "release planning" || got.SetBy != "alice" || !got.SetAt.Equal(first) {
+		t.Fatalf("Topic() = %+v, want release planning set by alice at %v", got, first)
+	}
+	if want := "Topic (set by alice at 09:30): release planning"; got.String() != want {
+		t.Errorf("String() = %q, want %q", got.String(), want)
+	}
+
+	second := first.Add(time.Hour)
+	if _, err := carol.changeTopic("retro", second); err != nil {
+		t.Fatalf("changeTopic: %v", err)
+	}
+	if got := room.Topic(); got.SetBy != "carol" || !got.SetAt.Equal(second) {
+		t.Errorf("Topic() = %+v, want set by carol at %v", got, second)
+	}
+
+	third := second.Add(time.Hour)
+	if _, err := alice.changeTopic("clear", third); err != nil {
+		t.Fatalf("changeTopic(clear): %v", err)
+	}
+	got = room.Topic()
+	if got.Text != "" || got.SetBy != "alice" || !got.SetAt.Equal(third) {
+		t.Errorf("Topic() after clear = %+v, want empty text cleared by alice", got)
+	}
+	if got.String() != "No topic is set." {
+		t.Errorf("String() after clear = %q", got.String())
+	}
+}
+
+func TestTopicCommandUsesRoomClock(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, true)
+	t.Cleanup(func() { room.Stop() })
+	clock := newFakeClock()
+	room.Clock = clock
+
+	conn := &lockedBufferConn{}
+	alice := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true, admin: true}
+	if err := alice.handleCommand("/topic release planning"); err != nil {
+		t.Fatalf("/topic error = %v", err)
+	}
+	if got := room.Topic().SetAt; !got.Equal(clock.Now()) {
+		t.Errorf("Topic set at %v, want the room clock's %v", got, clock.Now())
+	}
+
+	clock.Advance(time.Hour)
+	carol := NewTUIClient(nil, room)
+	carol.Nickname = "carol"
+	carol.admin = true
+	model := NewChatModel(carol)
+	model.initViewport()
+	model.handleCommand("/topic retro")
+	if got := room.Topic().SetAt; !got.Equal(clock.Now()) {
+		t.Errorf("TUI topic set at %v, want the room clock's %v", got, clock.Now())
+	}
+}
+
+func TestTopicRequiresAdmin(t *testing.T) {
+	room := NewRoom("Test Room", 10, false, 0, false)
+	t.Cleanup(func() { room.Stop() })
+
+	bob := NewTUIClient(nil, room)
+	bob.Nickname = "bob"
+
+	reply, err := bob.changeTopic("hijacked", time.Now())
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("changeTopic by non-admin err = %v, want ErrPermissionDenied", err)
+	}
+	if !strings.Contains(reply, "Permission denied") {
+		t.Errorf("reply = %q, want a permission notice", reply)
+	}
+	if got := room.Topic(); got.Text != "" {
+		t.Errorf("Topic() = %+v, want unchanged", got)
+	}
+
+	if reply, err := bob.changeTopic("", time.Now()); err != nil || reply != "No topic is set." {
+		t.Errorf("viewing topic = %q, %v", reply, err)
+	}
+}
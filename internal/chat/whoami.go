@@ -0,0 +1,27 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/ui"
+)
+
+// whoami renders the /whoami output: the caller's own nickname, color,
+// verified identity, room and join time
+func (c *Client) whoami(now time.Time) string {
+	c.room.mu.RLock()
+	joinedAt := c.joinedAt
+	c.room.mu.RUnlock()
+
+	joined := "not yet"
+	if !joinedAt.IsZero() {
+		joined = fmt.Sprintf("%s (%s ago)", joinedAt.Format("15:04:05"), now.Sub(joinedAt).Round(time.Second))
+	}
+
+	color := c.room.theme().UserColor(c.Nickname)
+	if c.usePlainText() {
+		return ui.FormatWhoamiPlain(c.Nickname, color, c.Identity, c.room.Name, joined)
+	}
+	return ui.FormatWhoami(c.Nickname, color, c.Identity, c.room.Name, joined)
+}
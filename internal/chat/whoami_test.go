@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/ui"
+)
+
+func TestWhoami(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	clock := newFakeClock()
+	room.Clock = clock
+
+	client := NewTUIClient(nil, room)
+	client.Nickname = "alice"
+	client.Identity = "alice@example.com"
+	room.Join(client)
+
+	clock.Advance(90 * time.Second)
+	got := client.whoami(clock.Now())
+
+	for _, want := range []string{
+		"You are alice:",
+		"Color: " + ui.Theme{}.UserColor("alice"),
+		"Identity: alice@example.com",
+		"Room: Test Room",
+		"Joined: " + clock.Now().Add(-90*time.Second).Format("15:04:05") + " (1m30s ago)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("whoami() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestWhoamiUnverified(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+
+	client := NewTUIClient(nil, room)
+	client.Nickname = "bob"
+
+	got := client.whoami(time.Now())
+	if !strings.Contains(got, "Identity: not verified") {
+		t.Errorf("Expected an unverified identity, got %q", got)
+	}
+	if !strings.Contains(got, "Joined: not yet") {
+		t.Errorf("Expected no join time before joining, got %q", got)
+	}
+}
+
+func TestWhoamiCommandUsesRoomClock(t *testing.T) {
+	room := NewRoom("Test Room", 5, false, 0, true)
+	defer room.Stop()
+	clock := newFakeClock()
+	room.Clock = clock
+
+	conn := &lockedBufferConn{}
+	client := &Client{Nickname: "alice", conn: conn, writer: bufio.NewWriter(conn), room: room, plainText: true}
+	room.Join(client)
+	room.Flush(time.Second) // Let the join notice land before reading output
+	clock.Advance(90 * time.Second)
+
+	if err := client.handleCommand("/whoami"); err != nil {
+		t.Fatalf("/whoami error = %v", err)
+	}
+	client.flush()
+	if out := conn.String(); !strings.Contains(out, "(1m30s ago)") {
+		t.Errorf("/whoami wrote %q, want the join time measured on the room clock", out)
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/bscott/ts-chat/internal/chat"
+)
+
+// auditFile appends moderation actions to a file as JSON, one event per line
+type auditFile struct {
+	mu   sync.Mutex
+	path string
+}
+
+// RecordAudit implements chat.AuditSink
+func (f *auditFile) RecordAudit(e chat.AuditEvent) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
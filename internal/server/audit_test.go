@@ -0,0 +1,36 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/chat"
+)
+
+func TestAuditFileAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink := &auditFile{path: path}
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	events := []chat.AuditEvent{
+		{Actor: "alice", Action: chat.AuditDisconnect, Target: "bob (100.64.0.2:5000)", Timestamp: at},
+		{Actor: "server", Action: chat.AuditFloodKick, Target: "mallory", Reason: "flooding", Timestamp: at},
+	}
+	for _, e := range events {
+		if err := sink.RecordAudit(e); err != nil {
+			t.Fatalf("RecordAudit() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"actor":"alice","action":"disconnect","target":"bob (100.64.0.2:5000)","timestamp":"2024-03-01T12:00:00Z"}` + "\n" +
+		`{"actor":"server","action":"flood-kick","target":"mallory","reason":"flooding","timestamp":"2024-03-01T12:00:00Z"}` + "\n"
+	if string(data) != want {
+		t.Errorf("Audit file = %q, want %q", data, want)
+	}
+}
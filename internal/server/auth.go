@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/bscott/ts-chat/internal/chat"
+)
+
+// Supported values for Config.AuthMode
+const (
+	AuthModeNone      = "none"
+	AuthModePassword  = "password"
+	AuthModeTailscale = "tailscale"
+)
+
+// tailscaleAuth admits only peers that Tailscale can identify
+type tailscaleAuth struct {
+	server *Server
+}
+
+// Authenticate resolves the peer's Tailscale login via WhoIs
+func (a tailscaleAuth) Authenticate(conn net.Conn, _ chat.PromptFunc) (string, error) {
+	identity := a.server.identityFor(conn)
+	if identity == "" {
+		return "", chat.ErrAuthFailed
+	}
+	return identity, nil
+}
+
+// newAuthenticator builds the authenticator selected by cfg.AuthMode
+func (s *Server) newAuthenticator(cfg Config) (chat.Authenticator, error) {
+	switch cfg.AuthMode {
+	case "", AuthModeNone:
+		return chat.NoAuth{}, nil
+	case AuthModePassword:
+		if cfg.AuthPassword == "" {
+			return nil, fmt.Errorf("auth mode %q requires a password", AuthModePassword)
+		}
+		return chat.PasswordAuth{Password: cfg.AuthPassword}, nil
+	case AuthModeTailscale:
+		if !cfg.EnableTailscale {
+			return nil, fmt.Errorf("auth mode %q requires Tailscale mode", AuthModeTailscale)
+		}
+		return tailscaleAuth{server: s}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q", cfg.AuthMode)
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAuthKey(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "authkey")
+	if err := os.WriteFile(keyFile, []byte("tskey-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	emptyFile := filepath.Join(dir, "empty")
+	if err := os.WriteFile(emptyFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		env     string
+		wantKey string
+		wantErr bool
+	}{
+		{"file wins over env", keyFile, "tskey-env", "tskey-file", false},
+		{"env fallback", "", "tskey-env", "tskey-env", false},
+		{"neither set", "", "", "", true},
+		{"missing file", filepath.Join(dir, "missing"), "tskey-env", "", true},
+		{"empty file", emptyFile, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TS_AUTHKEY", tt.env)
+
+			key, _, err := loadAuthKey(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadAuthKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if key != tt.wantKey {
+				t.Errorf("loadAuthKey() = %q, want %q", key, tt.wantKey)
+			}
+		})
+	}
+}
@@ -1,13 +1,115 @@
 package server
 
+import (
+	"time"
+
+	"github.com/bscott/ts-chat/internal/chat"
+)
+
 // Config holds the server configuration
 type Config struct {
-	Port            int    // TCP port to listen on
-	RoomName        string // Chat room name
-	MaxUsers        int    // Maximum allowed users
-	EnableTailscale bool   // Whether to enable Tailscale mode
-	HostName        string // Tailscale hostname (only used if EnableTailscale is true)
-	EnableHistory   bool   // Whether to enable message history for new users
-	HistorySize     int    // Number of messages to keep in history
-	PlainText       bool   // Whether to disable ANSI formatting (for Windows telnet compatibility)
-}
\ No newline at end of file
+	Port               int           // TCP port to listen on
+	ExtraPorts         []int         // More ports feeding the same room, e.g. 23 alongside 2323
+	ListenAddr         string        // Address to bind in TCP mode (empty for all interfaces)
+	AlsoListenTCP      bool          // In Tailscale mode, also accept plain TCP on ListenAddr
+	RoomName           string        // Chat room name
+	MaxUsers           int           // Maximum allowed users
+	MaxConnections     int           // Maximum open connections server-wide, including users still logging in (0 disables)
+	EnableTailscale    bool          // Whether to enable Tailscale mode
+	HostName           string        // Tailscale hostname (only used if EnableTailscale is true)
+	AuthKeyFile        string        // File holding the Tailscale auth key (falls back to TS_AUTHKEY)
+	TSConnectTimeout   time.Duration // How long to keep retrying Tailscale bring-up (0 tries once)
+	EnableHistory      bool          // Whether to enable message history for new users
+	HistorySize        int           // Number of messages to keep in history
+	HistoryMaxAge      time.Duration // Drop history older than this regardless of count (0 disables)
+	HistoryBudget      int64         // Estimated bytes of history kept across rooms before the least active are trimmed (0 disables)
+	HistoryReplay      int           // Messages replayed to joining users (0 none, negative all stored)
+	HistorySystem      bool          // Whether join/leave and other system notices are kept in history
+	HistoryKinds       string        // Comma-separated message kinds kept in history, e.g. "user,action" (empty keeps all)
+	PlainText          bool          // Whether to disable ANSI formatting (for Windows telnet compatibility)
+	Encoding           string        // Terminal encoding for client traffic, e.g. cp437 (empty or utf-8 for passthrough)
+	LineEnding         string        // Line terminator for line-mode output, "crlf" or "lf" (empty for crlf)
+	Accessible         bool          // Whether to use the color-blind-safe theme with text prefixes
+	Hyperlinks         bool          // Whether URLs in ANSI output are wrapped in OSC 8 hyperlinks
+	Compact            bool          // Whether clients start in compact mode (no banner or boxes)
+	BannerFile         string        // Path to a custom welcome banner (empty for the default)
+	BannerWidth        int           // Width to center the banner in for line-mode clients (0 leaves it as is)
+	BannerAnimate      bool          // Whether ANSI line-mode clients see the banner printed line by line
+	BannerAnimateDelay time.Duration // Pause between banner lines when BannerAnimate is set
+	MOTDFile           string        // Path to a message-of-the-day file (empty for none)
+	PinnedFile         string        // File whose lines are pinned ahead of every history replay (empty for none)
+	FeedbackFile       string        // File that /feedback submissions are appended to (empty logs them)
+	AuditLog           string        // File that moderation actions are appended to as JSON lines (empty disables)
+	StatusPort         int           // Port for the /debug/vars status endpoint (0 disables)
+	Version            string        // Build version reported by the status endpoint
+	StatsInterval      time.Duration // How often to log connection and message stats (0 disables)
+	NickHandshake      bool          // Whether plain-text clients may send "NICK <name>" instead of answering the prompt
+	WriteTimeout       time.Duration // Disconnect clients whose socket blocks a write for longer than this (0 disables)
+	HeartbeatInterval  time.Duration // How often to send keepalives to detect dead peers (0 disables)
+	IdleTimeout        time.Duration // Disconnect users who send nothing for this long (0 disables)
+	IdleWarning        time.Duration // How long before an idle disconnect users are warned (0 skips the warning)
+	SingleSession      bool          // Whether a reconnecting Tailscale identity replaces its previous session
+	MinNicknameLen     int           // Minimum nickname length
+	MaxNicknameLen     int           // Maximum nickname length
+	MaxMessageLen      int           // Maximum message length in characters
+	Scrollback         int           // Messages kept in each TUI client's viewport (0 keeps all)
+	JoinLeaveCoalesce  time.Duration // Batch join/leave notices within this window (0 disables)
+	QuietJoins         bool          // Whether clients start in do-not-disturb mode (join/leave notices hidden)
+	NickCaseFold       bool          // Whether nicknames differing only in case are treated as the same
+	AdminToken         string        // Token that grants admin rights via /admin (empty disables admins)
+	ReservedNicks      string        // Comma-separated nicknames that need a password to take
+	ReservedNicksFile  string        // File of reserved nicknames, one per line with an optional bcrypt: or sha256: password hash and "admin"
+	ReservedNickPass   string        // Shared password for reserved nicknames without their own hash (empty: those can't be taken)
+	AnnouncePersist    bool          // Whether /announce notices are kept in history
+	AuthMode           string        // How connections authenticate: none, password or tailscale
+	AuthPassword       string        // Shared password for the password auth mode
+	ConnectChallenge   string        // Question for connections without an identity: word or math (empty disables)
+	MaxNickAttempts    int           // Disconnect after this many rejected nicknames (0 disables)
+	ReconnectInterval  time.Duration // Minimum time between connections from one IP (0 disables)
+	ReadPollInterval   time.Duration // Line-mode read deadline between shutdown checks (0 disables)
+	ReadBufferSize     int           // Bytes buffered per line-mode client for reading (0 for the default)
+	WriteBufferSize    int           // Bytes buffered per line-mode client for writing (0 for the default)
+	QuietHours         string        // Daily window when only admins may post, e.g. "22:00-07:00" (empty disables)
+	QuietTimezone      string        // IANA time zone for QuietHours (empty for the server's local zone)
+	RoomRateLimit      float64       // Room-wide chat messages per second before dropping (0 disables)
+	JoinCooldown       time.Duration // Delay after joining before a client's first chat message is accepted (0 disables)
+	FloodKick          int           // Disconnect a client after this many rate-limit violations within FloodKickWindow (0 disables)
+	FloodKickWindow    time.Duration // Window in which rate-limit violations count towards FloodKick
+	FloodBanDuration   time.Duration // Refuse connections from a flooder's IP for this long after a kick (0 disables)
+	OnJoinCmd          string        // Command run with the nickname and address on join (empty disables)
+	OnLeaveCmd         string        // Command run with the nickname and address on leave (empty disables)
+	BusURL             string        // Pub/sub bus shared with other servers, e.g. "redis://host:6379" (empty disables)
+	ShutdownTimeout    time.Duration // How long Stop waits for connections to finish (0 uses DefaultShutdownTimeout)
+}
+
+// DefaultConfig returns the configuration chat-tails runs with when no flags
+// are given. Embedders should start from it rather than a zero Config, which
+// NewServer rejects.
+func DefaultConfig() Config {
+	return Config{
+		Port:               2323,
+		RoomName:           "Chat Room",
+		MaxUsers:           10,
+		HostName:           "chatroom",
+		TSConnectTimeout:   2 * time.Minute,
+		HistorySize:        50,
+		HistoryReplay:      chat.ReplayAllHistory,
+		HistorySystem:      true,
+		Encoding:           "utf-8",
+		LineEnding:         "crlf",
+		BannerAnimateDelay: 60 * time.Millisecond,
+		WriteTimeout:       10 * time.Second,
+		IdleWarning:        chat.DefaultIdleWarning,
+		MinNicknameLen:     chat.MinNicknameLen,
+		MaxNicknameLen:     chat.MaxNicknameLen,
+		MaxMessageLen:      chat.MaxMessageLength,
+		Scrollback:         chat.DefaultScrollback,
+		AuthMode:           AuthModeNone,
+		MaxNickAttempts:    chat.MaxNicknameAttempts,
+		ReadPollInterval:   chat.DefaultReadPollInterval,
+		ReadBufferSize:     chat.DefaultBufferSize,
+		WriteBufferSize:    chat.DefaultBufferSize,
+		FloodKickWindow:    chat.DefaultFloodKickWindow,
+		ShutdownTimeout:    DefaultShutdownTimeout,
+	}
+}
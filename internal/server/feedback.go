@@ -0,0 +1,36 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/chat"
+)
+
+// feedbackFile appends /feedback submissions to a file, one per line
+type feedbackFile struct {
+	mu   sync.Mutex
+	path string
+}
+
+// RecordFeedback implements chat.FeedbackSink
+func (f *feedbackFile) RecordFeedback(fb chat.Feedback) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	// Flatten newlines so each submission stays on one line
+	content := strings.ReplaceAll(fb.Content, "\n", " ")
+	_, err = fmt.Fprintf(file, "%s\t%s\t%s\t%s\n", fb.Timestamp.Format(time.RFC3339), fb.From, fb.RemoteAddr, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
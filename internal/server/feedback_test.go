@@ -0,0 +1,32 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/chat"
+)
+
+func TestFeedbackFileAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feedback.log")
+	sink := &feedbackFile{path: path}
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, content := range []string{"first", "two\nlines"} {
+		if err := sink.RecordFeedback(chat.Feedback{From: "alice", RemoteAddr: "1.2.3.4:5", Content: content, Timestamp: at}); err != nil {
+			t.Fatalf("RecordFeedback() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2024-03-01T12:00:00Z\talice\t1.2.3.4:5\tfirst\n" +
+		"2024-03-01T12:00:00Z\talice\t1.2.3.4:5\ttwo lines\n"
+	if string(data) != want {
+		t.Errorf("Feedback file = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"tailscale.com/ipn/ipnstate"
+)
+
+// dnsNameFromStatus returns the node's MagicDNS name without the trailing dot,
+// or "" when the status doesn't have one yet
+func dnsNameFromStatus(status *ipnstate.Status) string {
+	if status == nil || status.Self == nil {
+		return ""
+	}
+	return strings.TrimSuffix(status.Self.DNSName, ".")
+}
+
+// DNSName returns the Tailscale MagicDNS name recorded at startup, or "" when
+// not in Tailscale mode or the name wasn't known yet
+func (s *Server) DNSName() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dnsName
+}
+
+func (s *Server) setDNSName(name string) {
+	s.mu.Lock()
+	s.dnsName = name
+	s.mu.Unlock()
+}
+
+// ConnectCommand returns a ready-to-copy telnet command for joining this
+// server, for the /invite admin command. It prefers the MagicDNS name, then the
+// Tailscale hostname, then the TCP listen address or this machine's hostname.
+func (s *Server) ConnectCommand() string {
+	host := s.DNSName()
+	if host == "" && s.config.EnableTailscale {
+		host = s.config.HostName
+	}
+	if host == "" {
+		host = s.config.ListenAddr
+	}
+	if host == "" {
+		if name, err := os.Hostname(); err == nil {
+			host = name
+		} else {
+			host = "localhost"
+		}
+	}
+	return fmt.Sprintf("telnet %s %d", host, s.config.Port)
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"testing"
+
+	"tailscale.com/ipn/ipnstate"
+)
+
+func TestDNSNameFromStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status *ipnstate.Status
+		want   string
+	}{
+		{"nil status", nil, ""},
+		{"no self", &ipnstate.Status{}, ""},
+		{"fqdn", &ipnstate.Status{Self: &ipnstate.PeerStatus{DNSName: "chat.tail1234.ts.net."}}, "chat.tail1234.ts.net"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dnsNameFromStatus(tt.status); got != tt.want {
+				t.Errorf("dnsNameFromStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectCommand(t *testing.T) {
+	cfg := testConfig()
+	cfg.Port = 2323
+	cfg.EnableTailscale = true
+	cfg.HostName = "chat"
+	srv, err := NewServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := srv.ConnectCommand(), "telnet chat 2323"; got != want {
+		t.Errorf("ConnectCommand() before status = %q, want %q", got, want)
+	}
+
+	status := &ipnstate.Status{Self: &ipnstate.PeerStatus{DNSName: "chat.tail1234.ts.net."}}
+	srv.setDNSName(dnsNameFromStatus(status))
+	if got, want := srv.DNSName(), "chat.tail1234.ts.net"; got != want {
+		t.Errorf("DNSName() = %q, want %q", got, want)
+	}
+	if got, want := srv.ConnectCommand(), "telnet chat.tail1234.ts.net 2323"; got != want {
+		t.Errorf("ConnectCommand() = %q, want %q", got, want)
+	}
+
+	tcp := testConfig()
+	tcp.Port = 2323
+	tcp.ListenAddr = "10.0.0.5"
+	srv, err = NewServer(tcp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := srv.ConnectCommand(), "telnet 10.0.0.5 2323"; got != want {
+		t.Errorf("ConnectCommand() in TCP mode = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// privilegedPortLimit is the first port an unprivileged process may usually bind
+const privilegedPortLimit = 1024
+
+// ports returns every port the server listens on, Port first
+func (s *Server) ports() []int {
+	return append([]int{s.config.Port}, s.config.ExtraPorts...)
+}
+
+// validatePorts rejects out-of-range and repeated ports. Port 0 (pick any free
+// port) may appear more than once.
+func validatePorts(ports []int) error {
+	seen := make(map[int]bool, len(ports))
+	for _, port := range ports {
+		if port < 0 || port > 65535 {
+			return fmt.Errorf("invalid port %d", port)
+		}
+		if port != 0 && seen[port] {
+			return fmt.Errorf("port %d given more than once", port)
+		}
+		seen[port] = true
+	}
+	return nil
+}
+
+// warnPrivilegedPort logs a hint when a TCP port will likely need extra privileges
+func warnPrivilegedPort(port int) {
+	if port > 0 && port < privilegedPortLimit {
+		log.Printf("Warning: port %d is privileged; binding it usually needs root or CAP_NET_BIND_SERVICE", port)
+	}
+}
+
+// FormatPorts renders ports for log lines, e.g. "port 2323" or "ports 23, 2323"
+func FormatPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+	if len(ports) == 1 {
+		return "port " + parts[0]
+	}
+	return "ports " + strings.Join(parts, ", ")
+}
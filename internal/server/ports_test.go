@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestValidatePorts(t *testing.T) {
+	tests := []struct {
+		ports   []int
+		wantErr bool
+	}{
+		{[]int{2323}, false},
+		{[]int{23, 2323}, false},
+		{[]int{0, 0}, false},
+		{[]int{2323, 2323}, true},
+		{[]int{23, 2323, 23}, true},
+		{[]int{-1}, true},
+		{[]int{65536}, true},
+	}
+	for _, tt := range tests {
+		if err := validatePorts(tt.ports); (err != nil) != tt.wantErr {
+			t.Errorf("validatePorts(%v) error = %v, wantErr %v", tt.ports, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFormatPorts(t *testing.T) {
+	if got := FormatPorts([]int{2323}); got != "port 2323" {
+		t.Errorf("FormatPorts(2323) = %q", got)
+	}
+	if got := FormatPorts([]int{23, 2323}); got != "ports 23, 2323" {
+		t.Errorf("FormatPorts(23, 2323) = %q", got)
+	}
+}
+
+func TestNewServerRejectsDuplicatePorts(t *testing.T) {
+	cfg := testConfig()
+	cfg.Port = 2323
+	cfg.ExtraPorts = []int{2323}
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected duplicate ports to be rejected")
+	}
+}
+
+func TestMultiplePortsShareRoom(t *testing.T) {
+	cfg := testConfig()
+	cfg.ListenAddr = "127.0.0.1"
+	cfg.ExtraPorts = []int{0} // two ephemeral ports
+
+	srv, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer srv.Stop()
+
+	if len(srv.listeners) != 2 {
+		t.Fatalf("Expected 2 listeners, got %d", len(srv.listeners))
+	}
+	if srv.listeners[0].Addr().String() == srv.listeners[1].Addr().String() {
+		t.Fatalf("Expected distinct ports, both are %s", srv.listeners[0].Addr())
+	}
+
+	alice, err := net.Dial("tcp", srv.listeners[0].Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	aliceReader := bufio.NewReader(alice)
+	alice.Write([]byte("alice\r\n"))
+	readUntil(t, alice, aliceReader, "Welcome to Test Room, alice!")
+
+	bob, err := net.Dial("tcp", srv.listeners[1].Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bob.Close()
+	bobReader := bufio.NewReader(bob)
+	bob.Write([]byte("bob\r\n"))
+	readUntil(t, bob, bobReader, "Welcome to Test Room, bob!")
+	go io.Copy(io.Discard, bobReader)
+
+	readUntil(t, alice, aliceReader, "bob has joined the room")
+	bob.Write([]byte("hello across ports\r\n"))
+	readUntil(t, alice, aliceReader, "bob: hello across ports")
+}
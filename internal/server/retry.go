@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Backoff bounds for retrying Tailscale bring-up
+const (
+	initialRetryDelay = time.Second
+	maxRetryDelay     = 30 * time.Second
+)
+
+// retryWithBackoff calls op until it succeeds, ctx is cancelled or timeout has
+// passed, doubling the wait between attempts from initial up to max. Each
+// attempt is logged under name. A zero timeout makes a single attempt with ctx.
+func retryWithBackoff(ctx context.Context, name string, timeout, initial, max time.Duration, op func(context.Context) error) error {
+	if timeout <= 0 {
+		return op(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	delay := initial
+	for attempt := 1; ; attempt++ {
+		err := op(ctx)
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("%s succeeded on attempt %d", name, attempt)
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s gave up after %d attempts: %w", name, attempt, err)
+		}
+
+		log.Printf("%s attempt %d failed: %v; retrying in %s", name, attempt, err, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s gave up after %d attempts: %w", name, attempt, err)
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if delay > max {
+			delay = max
+		}
+	}
+}
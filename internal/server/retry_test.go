@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyOp fails the first failures calls, then succeeds
+type flakyOp struct {
+	failures int
+	calls    int
+}
+
+func (f *flakyOp) run(context.Context) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("coordination server unreachable")
+	}
+	return nil
+}
+
+func TestRetryWithBackoffSucceedsAfterFailures(t *testing.T) {
+	op := &flakyOp{failures: 3}
+	if err := retryWithBackoff(context.Background(), "test", time.Second, time.Millisecond, 4*time.Millisecond, op.run); err != nil {
+		t.Fatalf("retryWithBackoff() error = %v", err)
+	}
+	if op.calls != 4 {
+		t.Errorf("op called %d times, want 4", op.calls)
+	}
+}
+
+func TestRetryWithBackoffZeroTimeoutTriesOnce(t *testing.T) {
+	op := &flakyOp{failures: 1}
+	if err := retryWithBackoff(context.Background(), "test", 0, time.Millisecond, time.Millisecond, op.run); err == nil {
+		t.Fatal("Expected the single attempt's error")
+	}
+	if op.calls != 1 {
+		t.Errorf("op called %d times, want 1", op.calls)
+	}
+}
+
+func TestRetryWithBackoffGivesUp(t *testing.T) {
+	op := &flakyOp{failures: 1 << 30}
+
+	start := time.Now()
+	err := retryWithBackoff(context.Background(), "test", 50*time.Millisecond, time.Millisecond, 5*time.Millisecond, op.run)
+	if err == nil {
+		t.Fatal("Expected an error after the timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("retryWithBackoff took %s, want about the 50ms timeout", elapsed)
+	}
+	if op.calls < 2 {
+		t.Errorf("op called %d times, want several retries", op.calls)
+	}
+}
+
+func TestRetryWithBackoffRespectsCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	op := &flakyOp{failures: 1 << 30}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- retryWithBackoff(ctx, "test", time.Hour, time.Hour, time.Hour, op.run)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected an error after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("retryWithBackoff did not return after cancellation")
+	}
+}
@@ -2,97 +2,563 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/bscott/ts-chat/internal/bus"
 	"github.com/bscott/ts-chat/internal/chat"
 	"tailscale.com/tsnet"
 )
 
+// DefaultShutdownTimeout is how long Stop waits for connection goroutines when
+// Config.ShutdownTimeout is unset
+const DefaultShutdownTimeout = 5 * time.Second
+
+// ErrShutdownTimeout is returned by Stop when connection goroutines were still
+// running after the shutdown timeout
+var ErrShutdownTimeout = errors.New("timed out waiting for connections to close")
+
+// shutdownNotice is broadcast to everyone in the room when the server stops
+const shutdownNotice = "Server is shutting down. Goodbye!"
+
 // Server represents the chat server
 type Server struct {
 	config      Config
-	listener    net.Listener
+	listeners   []listener
 	tsServer    *tsnet.Server
 	chatRoom    *chat.Room
+	auth        chat.Authenticator
+	challenge   *chat.ConnectChallenge // asked of connections without an identity; nil when disabled
+	bus         bus.MessageBus         // shared with other servers; nil when the room is local
+	throttle    *connThrottle
+	floodBans   *banList
+	status      *http.Server
 	ctx         context.Context
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
-	connections map[string]net.Conn
+	connections map[string]trackedConn
+	accepted    map[string]int64 // connections accepted per transport, guarded by mu
+	dnsName     string           // MagicDNS name of the Tailscale node, guarded by mu
 	mu          sync.Mutex
 }
 
 // NewServer creates a new chat server
 func NewServer(cfg Config) (*Server, error) {
+	if cfg.EnableTailscale && cfg.ListenAddr != "" && !cfg.AlsoListenTCP {
+		return nil, fmt.Errorf("listen address %q cannot be used with Tailscale mode", cfg.ListenAddr)
+	}
+
+	if cfg.AlsoListenTCP && !cfg.EnableTailscale {
+		return nil, fmt.Errorf("also listening on TCP requires Tailscale mode")
+	}
+
+	if cfg.MaxUsers < 1 {
+		return nil, fmt.Errorf("max users must be at least 1, got %d (there is no unlimited setting)", cfg.MaxUsers)
+	}
+
+	if cfg.MinNicknameLen < 1 || cfg.MaxNicknameLen < cfg.MinNicknameLen {
+		return nil, fmt.Errorf("invalid nickname length bounds %d-%d", cfg.MinNicknameLen, cfg.MaxNicknameLen)
+	}
+
+	if cfg.MaxMessageLen < 1 {
+		return nil, fmt.Errorf("max message length must be positive, got %d", cfg.MaxMessageLen)
+	}
+
+	for _, buf := range []struct {
+		name string
+		size int
+	}{{"read", cfg.ReadBufferSize}, {"write", cfg.WriteBufferSize}} {
+		if buf.size != 0 && (buf.size < chat.MinBufferSize || buf.size > chat.MaxBufferSize) {
+			return nil, fmt.Errorf("%s buffer size must be between %d and %d bytes, got %d", buf.name, chat.MinBufferSize, chat.MaxBufferSize, buf.size)
+		}
+	}
+
+	if err := validatePorts(append([]int{cfg.Port}, cfg.ExtraPorts...)); err != nil {
+		return nil, err
+	}
+
+	enc, err := chat.LookupEncoding(cfg.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	lineEnding, err := chat.LookupLineEnding(cfg.LineEnding)
+	if err != nil {
+		return nil, err
+	}
+
+	var historyKinds map[chat.MessageKind]bool
+	if cfg.HistoryKinds != "" {
+		historyKinds, err = chat.ParseMessageKinds(cfg.HistoryKinds)
+		if err != nil {
+			return nil, fmt.Errorf("invalid history kinds: %w", err)
+		}
+	}
+
+	var quietHours *chat.QuietHours
+	if cfg.QuietHours != "" {
+		quietHours, err = chat.ParseQuietHours(cfg.QuietHours, cfg.QuietTimezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var challenge *chat.ConnectChallenge
+	if cfg.ConnectChallenge != "" {
+		challenge, err = chat.NewConnectChallenge(cfg.ConnectChallenge)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
-	room := chat.NewRoom(cfg.RoomName, cfg.MaxUsers, cfg.EnableHistory, cfg.HistorySize, cfg.PlainText)
+	banner, err := readTextFile(cfg.BannerFile)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to load banner: %w", err)
+	}
+
+	motd, err := readTextFile(cfg.MOTDFile)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to load message of the day: %w", err)
+	}
+
+	pinned, err := readTextFile(cfg.PinnedFile)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to load pinned messages: %w", err)
+	}
+
+	reservedNicks, err := loadReservedNicks(cfg)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
 
-	return &Server{
+	s := &Server{
 		config:      cfg,
 		ctx:         ctx,
 		cancel:      cancel,
-		chatRoom:    room,
-		connections: make(map[string]net.Conn),
-	}, nil
+		challenge:   challenge,
+		connections: make(map[string]trackedConn),
+		accepted:    make(map[string]int64),
+		throttle:    newConnThrottle(cfg.ReconnectInterval),
+		floodBans:   newBanList(),
+	}
+
+	// Settle the auth mode before the room starts its goroutines, so a bad
+	// configuration has nothing to clean up
+	s.auth, err = s.newAuthenticator(cfg)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	room := chat.NewRoom(cfg.RoomName, cfg.MaxUsers, cfg.EnableHistory, cfg.HistorySize, cfg.PlainText)
+	s.chatRoom = room
+	room.Banner = banner
+	room.HistoryReplayCount = cfg.HistoryReplay
+	room.HistorySystem = cfg.HistorySystem
+	room.HistoryKinds = historyKinds
+	room.Encoding = enc
+	room.Hyperlinks = cfg.Hyperlinks
+	room.Accessible = cfg.Accessible
+	room.LineEnding = lineEnding
+	room.BannerWidth = cfg.BannerWidth
+	room.Compact = cfg.Compact
+	if cfg.BannerAnimate {
+		room.BannerAnimateDelay = cfg.BannerAnimateDelay
+	}
+	room.MOTD = motd
+	if pinned != "" {
+		room.SetPinned(strings.Split(pinned, "\n"))
+	}
+	room.AllowNickHandshake = cfg.NickHandshake
+	room.WriteTimeout = cfg.WriteTimeout
+	room.MinNicknameLen = cfg.MinNicknameLen
+	room.MaxNicknameLen = cfg.MaxNicknameLen
+	room.MaxMessageLength = cfg.MaxMessageLen
+	room.Scrollback = cfg.Scrollback
+	room.JoinLeaveCoalesce = cfg.JoinLeaveCoalesce
+	room.QuietJoins = cfg.QuietJoins
+	room.NickCaseFold = cfg.NickCaseFold
+	room.AdminToken = cfg.AdminToken
+	room.ReservedNicks = reservedNicks
+	room.AnnouncePersist = cfg.AnnouncePersist
+	room.MaxNicknameAttempts = cfg.MaxNickAttempts
+	room.ReadPollInterval = cfg.ReadPollInterval
+	room.ReadBufferSize = cfg.ReadBufferSize
+	room.WriteBufferSize = cfg.WriteBufferSize
+	room.RateLimit = cfg.RoomRateLimit
+	room.JoinCooldown = cfg.JoinCooldown
+	room.FloodKickAfter = cfg.FloodKick
+	if cfg.FloodKickWindow > 0 {
+		room.FloodKickWindow = cfg.FloodKickWindow
+	}
+	room.SetQuietHours(quietHours)
+	room.OnJoinCmd = cfg.OnJoinCmd
+	room.OnLeaveCmd = cfg.OnLeaveCmd
+	if cfg.FeedbackFile != "" {
+		room.Feedback = &feedbackFile{path: cfg.FeedbackFile}
+	}
+	if cfg.AuditLog != "" {
+		room.Audit = &auditFile{path: cfg.AuditLog}
+	}
+	room.SetHeartbeatInterval(cfg.HeartbeatInterval)
+	room.SetIdleTimeout(cfg.IdleTimeout, cfg.IdleWarning)
+	if cfg.EnableHistory && cfg.HistoryBudget > 0 {
+		room.SetHistoryBudget(chat.NewHistoryBudget(cfg.HistoryBudget))
+	}
+	if cfg.EnableHistory && cfg.HistoryMaxAge > 0 {
+		room.SetHistoryMaxAge(cfg.HistoryMaxAge)
+	}
+
+	room.Connections = s
+	room.Invites = s
+
+	if cfg.BusURL != "" {
+		if s.bus, err = bus.Open(cfg.BusURL); err != nil {
+			room.Stop()
+			cancel()
+			return nil, fmt.Errorf("failed to open bus: %w", err)
+		}
+		if err := room.AttachBus(s.bus, busOrigin(), chat.DefaultPresenceInterval); err != nil {
+			s.bus.Close()
+			room.Stop()
+			cancel()
+			return nil, err
+		}
+	}
+	if cfg.FloodBanDuration > 0 {
+		room.FloodBans = s
+	}
+
+	return s, nil
 }
 
-// Start starts the chat server
-func (s *Server) Start() error {
-	var listener net.Listener
-	var err error
+// NewServerWithListener creates a chat server that accepts connections from ln
+// instead of opening its own TCP or Tailscale listener. Start takes ownership of ln.
+func NewServerWithListener(cfg Config, ln net.Listener) (*Server, error) {
+	return NewServerWithListeners(cfg, ln)
+}
 
-	if s.config.EnableTailscale {
-		s.tsServer = &tsnet.Server{
-			Hostname: s.config.HostName,
-			AuthKey:  os.Getenv("TS_AUTHKEY"),
+// NewServerWithListeners creates a chat server that accepts connections from
+// every listener in lns into the same room. Start takes ownership of them.
+func NewServerWithListeners(cfg Config, lns ...net.Listener) (*Server, error) {
+	if len(lns) == 0 {
+		return nil, fmt.Errorf("at least one listener is required")
+	}
+	for _, ln := range lns {
+		if ln == nil {
+			return nil, fmt.Errorf("listener must not be nil")
+		}
+	}
+
+	s, err := NewServer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.listeners = tagListeners(lns)
+	return s, nil
+}
+
+// Room returns the server's chat room, e.g. for broadcasting announcements
+func (s *Server) Room() *chat.Room {
+	return s.chatRoom
+}
+
+// readTextFile loads an optional operator-provided text file, normalizing line endings.
+// An empty path yields an empty string.
+func readTextFile(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	return strings.TrimRight(text, "\n"), nil
+}
+
+// loadReservedNicks collects the nicknames reserved by the list and the file
+// (nil when neither is set)
+func loadReservedNicks(cfg Config) (*chat.ReservedNicks, error) {
+	if cfg.ReservedNicks == "" && cfg.ReservedNicksFile == "" {
+		return nil, nil
+	}
+
+	reserved := chat.NewReservedNicks(cfg.ReservedNickPass)
+	reserved.AddList(cfg.ReservedNicks)
+
+	text, err := readTextFile(cfg.ReservedNicksFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reserved nicknames: %w", err)
+	}
+	if err := reserved.AddFile(text); err != nil {
+		return nil, fmt.Errorf("invalid reserved nicknames file: %w", err)
+	}
+	return reserved, nil
+}
+
+// loadAuthKey returns the Tailscale auth key and a description of where it came from.
+// A key file takes precedence over the TS_AUTHKEY environment variable.
+func loadAuthKey(path string) (key, source string, err error) {
+	if path != "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read auth key file: %w", err)
+		}
+		if info.Mode().Perm()&0o004 != 0 {
+			log.Printf("Warning: auth key file %s is world-readable; consider chmod 600", path)
 		}
 
-		log.Printf("Connecting to Tailscale network...")
-		if _, err := s.tsServer.Up(s.ctx); err != nil {
-			return fmt.Errorf("failed to start Tailscale node: %w", err)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read auth key file: %w", err)
+		}
+
+		key = strings.TrimSpace(string(data))
+		if key == "" {
+			return "", "", fmt.Errorf("auth key file %s is empty", path)
 		}
+		return key, "file " + path, nil
+	}
 
-		ln, err := s.tsServer.LocalClient()
+	if key = os.Getenv("TS_AUTHKEY"); key != "" {
+		return key, "TS_AUTHKEY environment variable", nil
+	}
+
+	return "", "", fmt.Errorf("no Tailscale auth key: set --ts-authkey-file or TS_AUTHKEY")
+}
+
+// Start starts the chat server. Listeners provided via NewServerWithListeners
+// are used as is; otherwise the TCP and/or Tailscale listeners are opened.
+func (s *Server) Start() error {
+	if len(s.listeners) == 0 {
+		listeners, err := s.listen()
 		if err != nil {
-			log.Printf("Warning: unable to get Tailscale local client: %v", err)
-		} else {
-			status, err := ln.Status(s.ctx)
+			return err
+		}
+		s.listeners = listeners
+	}
+
+	for _, ln := range s.listeners {
+		log.Printf("Listening on %s", ln.Addr())
+	}
+
+	log.Printf("Server started on %s (room: %s, max users: %d)", FormatPorts(s.ports()), s.config.RoomName, s.config.MaxUsers)
+
+	for _, ln := range s.listeners {
+		s.wg.Add(1)
+		go s.acceptConnections(ln.Listener, ln.transport)
+	}
+
+	if s.config.StatsInterval > 0 {
+		s.wg.Add(1)
+		go s.logStats(s.config.StatsInterval)
+	}
+
+	if s.config.StatusPort > 0 {
+		if err := s.startStatus(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listen opens the listeners selected by the config, one per port: plain TCP,
+// Tailscale, or both when AlsoListenTCP is set
+func (s *Server) listen() ([]listener, error) {
+	var listeners []listener
+	closeAll := func() {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+	}
+
+	if s.config.EnableTailscale {
+		if err := s.startTailscale(); err != nil {
+			return nil, err
+		}
+		for _, port := range s.ports() {
+			ln, err := s.tsServer.Listen("tcp", fmt.Sprintf(":%d", port))
 			if err != nil {
-				log.Printf("Warning: unable to get Tailscale status: %v", err)
-			} else if status != nil && status.Self != nil && status.Self.DNSName != "" {
-				log.Printf("Tailscale node running as: %s", status.Self.DNSName)
-			} else {
-				log.Printf("Tailscale node running but DNS name not available yet")
+				closeAll()
+				return nil, fmt.Errorf("failed to start Tailscale server on port %d: %w", port, err)
 			}
+			listeners = append(listeners, listener{Listener: ln, transport: TransportTailscale})
+		}
+		if !s.config.AlsoListenTCP {
+			return listeners, nil
 		}
+	}
 
-		listener, err = s.tsServer.Listen("tcp", fmt.Sprintf(":%d", s.config.Port))
+	for _, port := range s.ports() {
+		ln, err := s.listenTCP(port)
 		if err != nil {
-			return fmt.Errorf("failed to start Tailscale server on port %d: %w", s.config.Port, err)
+			closeAll()
+			return nil, err
+		}
+		listeners = append(listeners, listener{Listener: ln, transport: TransportTCP})
+	}
+	return listeners, nil
+}
+
+// listenTCP opens a plain TCP listener on ListenAddr and port
+func (s *Server) listenTCP(port int) (net.Listener, error) {
+	warnPrivilegedPort(port)
+	addr := net.JoinHostPort(s.config.ListenAddr, strconv.Itoa(port))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return listener, nil
+}
+
+// startTailscale brings up the tsnet node that the Tailscale listeners are opened on
+func (s *Server) startTailscale() error {
+	authKey, source, err := loadAuthKey(s.config.AuthKeyFile)
+	if err != nil {
+		return err
+	}
+	log.Printf("Using Tailscale auth key from %s", source)
+
+	log.Printf("Connecting to Tailscale network...")
+	err = retryWithBackoff(s.ctx, "Tailscale connect", s.config.TSConnectTimeout, initialRetryDelay, maxRetryDelay, func(ctx context.Context) error {
+		// A failed start is sticky in tsnet, so each attempt gets a fresh node
+		ts := &tsnet.Server{
+			Hostname: s.config.HostName,
+			AuthKey:  authKey,
+		}
+		if _, err := ts.Up(ctx); err != nil {
+			ts.Close()
+			return err
 		}
+		s.tsServer = ts
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start Tailscale node: %w", err)
+	}
+
+	lc, err := s.tsServer.LocalClient()
+	if err != nil {
+		log.Printf("Warning: unable to get Tailscale local client: %v", err)
 	} else {
-		listener, err = net.Listen("tcp", fmt.Sprintf(":%d", s.config.Port))
+		status, err := lc.Status(s.ctx)
 		if err != nil {
-			return fmt.Errorf("failed to listen on port %d: %w", s.config.Port, err)
+			log.Printf("Warning: unable to get Tailscale status: %v", err)
+		} else if name := dnsNameFromStatus(status); name != "" {
+			s.setDNSName(name)
+			log.Printf("Tailscale node running as: %s", name)
+		} else {
+			log.Printf("Tailscale node running but DNS name not available yet")
 		}
 	}
 
-	s.listener = listener
+	return nil
+}
 
-	log.Printf("Server started on port %d (room: %s, max users: %d)", s.config.Port, s.config.RoomName, s.config.MaxUsers)
+// trackedConn is an open connection and when it was accepted
+type trackedConn struct {
+	conn        net.Conn
+	connectedAt time.Time
+	transport   string // which listener accepted it, e.g. "tcp" or "tailscale"
+}
 
-	s.wg.Add(1)
-	go s.acceptConnections()
+// Connections lists open connections for the /connections admin command
+func (s *Server) Connections() []chat.ConnectionInfo {
+	s.mu.Lock()
+	infos := make([]chat.ConnectionInfo, 0, len(s.connections))
+	for addr, tracked := range s.connections {
+		infos = append(infos, chat.ConnectionInfo{RemoteAddr: addr, ConnectedAt: tracked.connectedAt})
+	}
+	s.mu.Unlock()
 
-	return nil
+	for i := range infos {
+		infos[i].Nickname = s.chatRoom.NicknameForAddr(infos[i].RemoteAddr)
+	}
+	return infos
+}
+
+// Disconnect closes the connection from remoteAddr, reporting whether one existed.
+// The client's handler notices the closed socket and leaves the room.
+func (s *Server) Disconnect(remoteAddr string) bool {
+	s.mu.Lock()
+	tracked, ok := s.connections[remoteAddr]
+	s.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	log.Printf("Disconnecting %s on operator request", remoteAddr)
+	tracked.conn.Close()
+	return true
+}
+
+// busOrigin returns an ID for this process on the bus, unique even when
+// several servers run on one host
+func busOrigin() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "chat-tails"
+	}
+	return fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano())
+}
+
+// BanFlooder refuses new connections from the host of remoteAddr for the
+// configured FloodBanDuration
+func (s *Server) BanFlooder(remoteAddr string) {
+	until := time.Now().Add(s.config.FloodBanDuration)
+	s.floodBans.ban(remoteAddr, until)
+	log.Printf("Banned %s for flooding until %s", remoteAddr, until.Format(time.RFC3339))
+}
+
+// ActiveConnections returns the number of open connections across all rooms
+func (s *Server) ActiveConnections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.connections)
+}
+
+// logStats periodically logs connection and message counters until the server stops.
+// The line format is kept stable so it can be grepped from logs.
+func (s *Server) logStats(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			stats := s.chatRoom.Stats()
+			log.Printf("stats: connections=%d online=%d peak=%d messages=%d messages_last_minute=%d rooms=%d",
+				s.ActiveConnections(), stats.Online, stats.PeakOnline, stats.TotalMessages, stats.MessagesLastMinute, 1)
+		}
+	}
 }
 
-func (s *Server) acceptConnections() {
+// acceptConnections hands connections from ln, which arrive over transport, to
+// the shared room until the server stops
+func (s *Server) acceptConnections(ln net.Listener, transport string) {
 	defer s.wg.Done()
 
 	for {
@@ -100,7 +566,7 @@ func (s *Server) acceptConnections() {
 		case <-s.ctx.Done():
 			return
 		default:
-			conn, err := s.listener.Accept()
+			conn, err := ln.Accept()
 			if err != nil {
 				select {
 				case <-s.ctx.Done():
@@ -112,22 +578,43 @@ func (s *Server) acceptConnections() {
 			}
 
 			s.wg.Add(1)
-			go s.handleConnection(conn)
+			go s.handleConnection(conn, transport)
 		}
 	}
 }
 
-func (s *Server) handleConnection(conn net.Conn) {
+func (s *Server) handleConnection(conn net.Conn, transport string) {
 	defer s.wg.Done()
 	defer conn.Close()
 
 	remoteAddr := conn.RemoteAddr().String()
-	log.Printf("New connection from %s", remoteAddr)
 
+	if !s.throttle.allow(conn.RemoteAddr(), time.Now()) {
+		log.Printf("Throttled connection from %s", remoteAddr)
+		conn.Write([]byte("Too many connections. Please wait a moment and try again.\r\n"))
+		return
+	}
+
+	if s.floodBans.banned(conn.RemoteAddr(), time.Now()) {
+		log.Printf("Refused connection from %s: banned for flooding", remoteAddr)
+		conn.Write([]byte("You are temporarily banned for flooding. Please try again later.\r\n"))
+		return
+	}
+
+	// Check the cap and register under one lock so concurrent accepts can't overshoot
 	s.mu.Lock()
-	s.connections[remoteAddr] = conn
+	if limit := s.config.MaxConnections; limit > 0 && len(s.connections) >= limit {
+		s.mu.Unlock()
+		log.Printf("Rejected connection from %s: server is at capacity (%d connections)", remoteAddr, limit)
+		conn.Write([]byte("The server is busy right now. Please try again later.\r\n"))
+		return
+	}
+	s.connections[remoteAddr] = trackedConn{conn: conn, connectedAt: time.Now(), transport: transport}
+	s.accepted[transport]++
 	s.mu.Unlock()
 
+	log.Printf("New connection from %s", remoteAddr)
+
 	defer func() {
 		s.mu.Lock()
 		delete(s.connections, remoteAddr)
@@ -135,28 +622,95 @@ func (s *Server) handleConnection(conn net.Conn) {
 		log.Printf("Connection from %s closed", remoteAddr)
 	}()
 
-	if s.config.PlainText {
-		s.handlePlainText(conn)
+	// The server-wide flag wins; otherwise ask the client what it can render.
+	// Probe before authenticating so negotiation bytes don't end up in answers.
+	// Without the probe we can't tell telnet clients apart, so none get keepalives.
+	plainText, telnet := s.config.PlainText, false
+	if !plainText {
+		probed, term, err := chat.ProbeTerminal(conn)
+		if err != nil {
+			log.Printf("Rejected non-telnet connection from %s", remoteAddr)
+			return
+		}
+		conn, plainText, telnet = probed, term.PlainText, term.Telnet
+	}
+
+	identity, err := chat.AuthenticateConn(conn, s.auth)
+	if err != nil {
+		log.Printf("Authentication failed for %s: %v", remoteAddr, err)
+		return
+	}
+	if identity == "" {
+		identity = s.identityFor(conn)
+	}
+
+	// Tailscale has vouched for identified peers; only anonymous ones are asked
+	if identity == "" && s.challenge != nil {
+		if err := s.challenge.Run(conn); err != nil {
+			log.Printf("Connect challenge failed for %s: %v", remoteAddr, err)
+			return
+		}
+	}
+
+	if s.config.SingleSession && s.chatRoom.ReplaceSession(identity) {
+		log.Printf("Replaced previous session for %s", identity)
+	}
+
+	if plainText {
+		s.handlePlainText(conn, identity, transport, telnet)
 	} else {
-		s.handleTUI(conn)
+		s.handleTUI(conn, identity, transport, telnet)
+	}
+}
+
+// identityFor returns the Tailscale login of the peer, or "" when unknown or not in Tailscale mode
+func (s *Server) identityFor(conn net.Conn) string {
+	if s.tsServer == nil {
+		return ""
+	}
+
+	lc, err := s.tsServer.LocalClient()
+	if err != nil {
+		return ""
 	}
+
+	who, err := lc.WhoIs(s.ctx, conn.RemoteAddr().String())
+	if err != nil || who.UserProfile == nil {
+		log.Printf("Unable to identify %s: %v", conn.RemoteAddr(), err)
+		return ""
+	}
+
+	return who.UserProfile.LoginName
 }
 
 // handleTUI runs a bubbletea program for the connection.
-func (s *Server) handleTUI(conn net.Conn) {
+func (s *Server) handleTUI(conn net.Conn, identity, transport string, telnet bool) {
 	client := chat.NewTUIClient(conn, s.chatRoom)
+	client.Identity = identity
+	client.Transport = transport
+	client.Telnet = telnet
 
 	client.RunTUI(s.ctx)
 
-	// Leave room on disconnect if nickname was set
+	// Leave room on disconnect if nickname was set. This also releases the
+	// reservation if the user quit before joining.
 	if client.Nickname != "" {
 		s.chatRoom.Leave(client)
 	}
 }
 
 // handlePlainText uses the legacy line-mode handler.
-func (s *Server) handlePlainText(conn net.Conn) {
-	client, err := chat.NewPlainTextClient(conn, s.chatRoom)
+func (s *Server) handlePlainText(conn net.Conn, identity, transport string, telnet bool) {
+	client, err := chat.NewPlainTextClient(conn, s.chatRoom, chat.ClientOptions{
+		PlainText: true,
+		Identity:  identity,
+		Transport: transport,
+		Telnet:    telnet,
+	})
+	if errors.Is(err, chat.ErrProtocolProbe) {
+		log.Printf("Rejected non-telnet connection from %s", conn.RemoteAddr())
+		return
+	}
 	if err != nil {
 		log.Printf("Error creating client: %v", err)
 		return
@@ -165,49 +719,80 @@ func (s *Server) handlePlainText(conn net.Conn) {
 	client.Handle(s.ctx)
 }
 
-// Stop stops the chat server
+// Stop stops the chat server. It broadcasts a shutdown notice and waits for
+// every client to write what it has queued before closing connections; the
+// whole shutdown is bounded by Config.ShutdownTimeout.
 func (s *Server) Stop() error {
 	log.Print("Stopping chat server...")
 
+	timeout := s.config.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	// Broadcast, flush, then close, so the notice isn't cut off mid-write
+	if s.chatRoom != nil {
+		s.chatRoom.Broadcast(chat.Message{
+			From:      "System",
+			Content:   shutdownNotice,
+			Timestamp: time.Now(),
+			Kind:      chat.KindSystem,
+		})
+		if !s.chatRoom.Flush(time.Until(deadline)) {
+			log.Print("Some clients did not flush before the shutdown timeout")
+		}
+	}
+
 	s.cancel()
+	s.stopStatus()
 
-	if s.listener != nil {
-		if err := s.listener.Close(); err != nil {
-			log.Printf("Error closing listener: %v", err)
+	for _, ln := range s.listeners {
+		if err := ln.Close(); err != nil {
+			log.Printf("Error closing listener %s: %v", ln.Addr(), err)
 		}
 	}
 
 	s.mu.Lock()
-	for _, conn := range s.connections {
-		conn.Close()
+	for _, tracked := range s.connections {
+		tracked.conn.Close()
 	}
 	s.mu.Unlock()
 
+	// Handlers leave the room as they exit, so stop it only once they have
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-time.After(time.Until(deadline)):
+		err = fmt.Errorf("stopping chat server: %w", ErrShutdownTimeout)
+	}
+
 	if s.chatRoom != nil {
 		if err := s.chatRoom.Stop(); err != nil {
 			log.Printf("Error stopping chat room: %v", err)
 		}
 	}
 
+	if s.bus != nil {
+		if err := s.bus.Close(); err != nil {
+			log.Printf("Error closing bus: %v", err)
+		}
+	}
+
 	if s.config.EnableTailscale && s.tsServer != nil {
 		if err := s.tsServer.Close(); err != nil {
 			log.Printf("Error closing Tailscale node: %v", err)
 		}
 	}
 
-	done := make(chan struct{})
-	go func() {
-		s.wg.Wait()
-		close(done)
-	}()
-
-	select {
-	case <-done:
+	if err == nil {
 		log.Print("Chat server stopped")
-	case <-time.After(5 * time.Second):
-		log.Print("Chat server stopped (timeout waiting for goroutines)")
 	}
-
-	return nil
+	return err
 }
-
@@ -0,0 +1,561 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/chat"
+)
+
+func testConfig() Config {
+	return Config{
+		RoomName:       "Test Room",
+		MaxUsers:       5,
+		PlainText:      true,
+		MinNicknameLen: chat.MinNicknameLen,
+		MaxNicknameLen: chat.MaxNicknameLen,
+		MaxMessageLen:  chat.MaxMessageLength,
+	}
+}
+
+// readUntil reads from r until the output contains want or the deadline passes
+func readUntil(t *testing.T, conn net.Conn, r *bufio.Reader, want string) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var seen strings.Builder
+	buf := make([]byte, 1024)
+	for {
+		n, err := r.Read(buf)
+		seen.Write(buf[:n])
+		if strings.Contains(seen.String(), want) {
+			return seen.String()
+		}
+		if err != nil {
+			t.Fatalf("did not receive %q (got %q): %v", want, seen.String(), err)
+		}
+	}
+}
+
+func TestNewServerWithListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := NewServerWithListener(testConfig(), ln)
+	if err != nil {
+		t.Fatalf("NewServerWithListener() error = %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer srv.Stop()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	conn.Write([]byte("alice\r\n"))
+	readUntil(t, conn, reader, "Welcome to Test Room, alice!")
+
+	srv.Room().Broadcast(chat.Message{
+		From:      "System",
+		Content:   "maintenance at noon",
+		Timestamp: time.Now(),
+		Kind:      chat.KindSystem,
+	})
+	readUntil(t, conn, reader, "[System] maintenance at noon")
+
+	if users := srv.Room().GetUserList(); len(users) != 1 || users[0] != "alice" {
+		t.Errorf("GetUserList() = %v, want [alice]", users)
+	}
+}
+
+func TestDefaultConfigIsValid(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The README's embedding example starts from DefaultConfig
+	srv, err := NewServerWithListener(DefaultConfig(), ln)
+	if err != nil {
+		t.Fatalf("NewServerWithListener(DefaultConfig()) error = %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer srv.Stop()
+
+	if name := srv.Room().Name; name != "Chat Room" {
+		t.Errorf("Room name = %q, want %q", name, "Chat Room")
+	}
+}
+
+func TestNewServerWithListenerRequiresListener(t *testing.T) {
+	if _, err := NewServerWithListener(testConfig(), nil); err == nil {
+		t.Error("Expected an error for a nil listener")
+	}
+}
+
+func TestServerConnectionsAndDisconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := NewServerWithListener(testConfig(), ln)
+	if err != nil {
+		t.Fatalf("NewServerWithListener() error = %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer srv.Stop()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	conn.Write([]byte("alice\r\n"))
+	readUntil(t, conn, reader, "Welcome to Test Room, alice!")
+
+	conns := srv.Connections()
+	if len(conns) != 1 || conns[0].Nickname != "alice" || conns[0].RemoteAddr != conn.LocalAddr().String() {
+		t.Fatalf("Connections() = %+v, want alice from %s", conns, conn.LocalAddr())
+	}
+
+	if srv.Disconnect("127.0.0.1:1") {
+		t.Error("Expected unknown address not to be disconnected")
+	}
+	if !srv.Disconnect(conns[0].RemoteAddr) {
+		t.Fatal("Expected Disconnect to find the connection")
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		t.Fatalf("Expected connection to be closed cleanly, got %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(srv.Room().GetUserList()) != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if users := srv.Room().GetUserList(); len(users) != 0 {
+		t.Errorf("Expected alice to leave the room, got %v", users)
+	}
+}
+
+func TestMaxConnections(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig()
+	cfg.MaxConnections = 1
+	srv, err := NewServerWithListener(cfg, ln)
+	if err != nil {
+		t.Fatalf("NewServerWithListener() error = %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer srv.Stop()
+
+	first, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, first, bufio.NewReader(first), "Please enter your nickname")
+
+	second, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	readUntil(t, second, bufio.NewReader(second), "The server is busy right now")
+
+	if n := srv.ActiveConnections(); n != 1 {
+		t.Errorf("ActiveConnections() = %d, want 1", n)
+	}
+
+	// Closing the first connection frees its slot
+	first.Close()
+	deadline := time.Now().Add(time.Second)
+	for srv.ActiveConnections() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	third, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer third.Close()
+	readUntil(t, third, bufio.NewReader(third), "Please enter your nickname")
+}
+
+// pipeListener is an in-memory listener whose connections come from dial
+type pipeListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), closed: make(chan struct{})}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// dial hands the server one end of a pipe and returns the other
+func (l *pipeListener) dial(t *testing.T) net.Conn {
+	t.Helper()
+	client, server := net.Pipe()
+	select {
+	case l.conns <- server:
+	case <-time.After(time.Second):
+		t.Fatal("pipe listener was not accepting")
+	}
+	return client
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
+
+func TestMultipleListenersShareRoom(t *testing.T) {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipe := newPipeListener()
+
+	srv, err := NewServerWithListeners(testConfig(), tcp, pipe)
+	if err != nil {
+		t.Fatalf("NewServerWithListeners() error = %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	stopped := false
+	defer func() {
+		if !stopped {
+			srv.Stop()
+		}
+	}()
+
+	alice, err := net.Dial("tcp", tcp.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	aliceReader := bufio.NewReader(alice)
+	alice.Write([]byte("alice\r\n"))
+	readUntil(t, alice, aliceReader, "Welcome to Test Room, alice!")
+
+	bob := pipe.dial(t)
+	defer bob.Close()
+	bobReader := bufio.NewReader(bob)
+	go bob.Write([]byte("bob\r\n"))
+	readUntil(t, bob, bobReader, "Welcome to Test Room, bob!")
+	// net.Pipe is unbuffered, so keep reading or the room blocks writing to bob
+	go io.Copy(io.Discard, bobReader)
+	readUntil(t, alice, aliceReader, "bob has joined the room")
+
+	go bob.Write([]byte("hello from the pipe\r\n"))
+	readUntil(t, alice, aliceReader, "bob: hello from the pipe")
+
+	if users := srv.Room().GetUserList(); len(users) != 2 {
+		t.Errorf("GetUserList() = %v, want alice and bob", users)
+	}
+
+	srv.Stop()
+	stopped = true
+	if _, err := net.Dial("tcp", tcp.Addr().String()); err == nil {
+		t.Error("Expected the TCP listener to be closed after Stop")
+	}
+	if _, err := pipe.Accept(); err == nil {
+		t.Error("Expected the pipe listener to be closed after Stop")
+	}
+}
+
+func TestAlsoListenTCPRequiresTailscale(t *testing.T) {
+	cfg := testConfig()
+	cfg.AlsoListenTCP = true
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected an error for --also-listen-tcp without Tailscale mode")
+	}
+}
+
+func TestNewServerRejectsUnknownEncoding(t *testing.T) {
+	cfg := testConfig()
+	cfg.Encoding = "klingon"
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected an error for an unsupported encoding")
+	}
+}
+
+func TestNewServerRejectsUnknownLineEnding(t *testing.T) {
+	cfg := testConfig()
+	cfg.LineEnding = "cr"
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected an error for an unsupported line ending")
+	}
+}
+
+func TestNewServerLoadsReservedNicks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reserved.txt")
+	if err := os.WriteFile(path, []byte("alice admin\nbob\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig()
+	cfg.ReservedNicks = "carol"
+	cfg.ReservedNicksFile = path
+	s, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer s.Stop()
+	if got := s.chatRoom.ReservedNicks.Len(); got != 3 {
+		t.Errorf("Expected 3 reserved nicknames, got %d", got)
+	}
+
+	if err := os.WriteFile(path, []byte("alice owner\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected an error for an invalid reserved nicknames file")
+	}
+}
+
+func TestNewServerBufferSizeBounds(t *testing.T) {
+	for _, size := range []int{1, chat.MinBufferSize - 1, chat.MaxBufferSize + 1} {
+		cfg := testConfig()
+		cfg.WriteBufferSize = size
+		if _, err := NewServer(cfg); err == nil || !strings.Contains(err.Error(), "write buffer size") {
+			t.Errorf("Expected write buffer size %d to be rejected, got %v", size, err)
+		}
+	}
+
+	cfg := testConfig()
+	cfg.ReadBufferSize = chat.MaxBufferSize
+	cfg.WriteBufferSize = chat.MinBufferSize
+	s, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer s.Stop()
+	if s.chatRoom.ReadBufferSize != chat.MaxBufferSize || s.chatRoom.WriteBufferSize != chat.MinBufferSize {
+		t.Errorf("Room buffer sizes = %d/%d", s.chatRoom.ReadBufferSize, s.chatRoom.WriteBufferSize)
+	}
+}
+
+// assertNoLeak fails if NewServer leaves goroutines running after rejecting cfg
+func assertNoLeak(t *testing.T, cfg Config) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	if _, err := NewServer(cfg); err == nil {
+		t.Fatal("Expected NewServer to fail")
+	}
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("NewServer left %d goroutines running after failing", runtime.NumGoroutine()-before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewServerAuthErrorStartsNoRoom(t *testing.T) {
+	for _, mode := range []string{"bogus", AuthModePassword, AuthModeTailscale} {
+		cfg := testConfig()
+		cfg.AuthMode = mode
+		assertNoLeak(t, cfg)
+	}
+}
+
+func TestNewServerRejectsNonPositiveMaxUsers(t *testing.T) {
+	for _, maxUsers := range []int{0, -1} {
+		cfg := testConfig()
+		cfg.MaxUsers = maxUsers
+		_, err := NewServer(cfg)
+		if err == nil || !strings.Contains(err.Error(), "max users must be at least 1") {
+			t.Errorf("NewServer(MaxUsers=%d) error = %v, want a max users error", maxUsers, err)
+		}
+	}
+}
+
+func TestNewServerRejectsUnknownHistoryKind(t *testing.T) {
+	cfg := testConfig()
+	cfg.HistoryKinds = "user,gossip"
+	if _, err := NewServer(cfg); err == nil || !strings.Contains(err.Error(), "gossip") {
+		t.Errorf("NewServer() error = %v, want an unknown kind error", err)
+	}
+}
+
+func TestNewServerRejectsUnknownChallenge(t *testing.T) {
+	cfg := testConfig()
+	cfg.ConnectChallenge = "riddle"
+	if _, err := NewServer(cfg); err == nil || !strings.Contains(err.Error(), "riddle") {
+		t.Errorf("NewServer() error = %v, want an unknown challenge error", err)
+	}
+	assertNoLeak(t, cfg)
+}
+
+func TestConnectChallengeBeforeNickname(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.ConnectChallenge = chat.ChallengeMath
+	srv, err := NewServerWithListener(cfg, ln)
+	if err != nil {
+		t.Fatalf("NewServerWithListener() error = %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer srv.Stop()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	out := readUntil(t, conn, r, "? ")
+	var a, b int
+	if _, err := fmt.Sscanf(out[strings.Index(out, "What is"):], "What is %d + %d?", &a, &b); err != nil {
+		t.Fatalf("Unexpected challenge %q: %v", out, err)
+	}
+	fmt.Fprintf(conn, "%d\r\n", a+b)
+	readUntil(t, conn, r, "nickname")
+}
+
+func TestStopReportsShutdownTimeout(t *testing.T) {
+	cfg := testConfig()
+	cfg.ShutdownTimeout = 50 * time.Millisecond
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := NewServerWithListener(cfg, ln)
+	if err != nil {
+		t.Fatalf("NewServerWithListener() error = %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// A connection goroutine that ignores shutdown
+	release := make(chan struct{})
+	srv.wg.Add(1)
+	go func() {
+		defer srv.wg.Done()
+		<-release
+	}()
+	defer close(release)
+
+	start := time.Now()
+	if err := srv.Stop(); !errors.Is(err, ErrShutdownTimeout) {
+		t.Errorf("Stop() error = %v, want ErrShutdownTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stop() took %s, want about the 50ms shutdown timeout", elapsed)
+	}
+}
+
+func TestStopCleanReturnsNil(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := NewServerWithListener(testConfig(), ln)
+	if err != nil {
+		t.Fatalf("NewServerWithListener() error = %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := srv.Stop(); err != nil {
+		t.Errorf("Stop() error = %v, want nil", err)
+	}
+}
+
+func TestStopDeliversFinalBroadcast(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := NewServerWithListener(testConfig(), ln)
+	if err != nil {
+		t.Fatalf("NewServerWithListener() error = %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	conn.Write([]byte("alice\r\n"))
+	readUntil(t, conn, r, "Welcome to Test Room, alice!")
+
+	srv.Room().Broadcast(chat.Message{From: "bob", Content: "last words", Timestamp: time.Now()})
+	if err := srv.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	// The connection is closed by now; whatever was flushed is still readable
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	rest, _ := io.ReadAll(r)
+	if !strings.Contains(string(rest), "bob: last words") {
+		t.Errorf("Expected the message broadcast before Stop to be delivered, got %q", rest)
+	}
+	if !strings.Contains(string(rest), shutdownNotice) {
+		t.Errorf("Expected the shutdown notice to be delivered, got %q", rest)
+	}
+}
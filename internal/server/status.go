@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// statusShutdownTimeout bounds how long Stop waits for status requests to finish
+const statusShutdownTimeout = 2 * time.Second
+
+// newStatusVars builds this server's expvar variables. They are computed on
+// each read from the live counters, so a snapshot never mixes old and new values
+// of the same counter. They live in a per-server map rather than the global
+// expvar registry so several servers can run in one process.
+func (s *Server) newStatusVars() *expvar.Map {
+	started := time.Now()
+	vars := new(expvar.Map).Init()
+
+	vars.Set("version", expvar.Func(func() any { return s.config.Version }))
+	vars.Set("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+	vars.Set("active_connections", expvar.Func(func() any { return s.ActiveConnections() }))
+	vars.Set("online", expvar.Func(func() any { return s.chatRoom.OnlineCount() }))
+	vars.Set("messages_total", expvar.Func(func() any { return s.chatRoom.Stats().TotalMessages }))
+	vars.Set("active_connections_by_transport", expvar.Func(func() any { return s.countsByTransport() }))
+	vars.Set("connections_total_by_transport", expvar.Func(func() any { return s.acceptedByTransport() }))
+	vars.Set("messages_by_transport", expvar.Func(func() any { return s.chatRoom.Stats().MessagesByTransport }))
+	vars.Set("uptime_seconds", expvar.Func(func() any { return int64(time.Since(started).Seconds()) }))
+	return vars
+}
+
+// hiddenExpvars are process-wide variables left out of /debug/vars. cmdline
+// holds os.Args, which includes --auth-password and --reserved-nick-password.
+var hiddenExpvars = map[string]bool{"cmdline": true}
+
+// statusHandler serves /debug/vars: the process-wide expvar variables (such as
+// memstats) plus this server's variables under "chat"
+func (s *Server) statusHandler() http.Handler {
+	vars := s.newStatusVars()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/vars", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, "{\n")
+		expvar.Do(func(kv expvar.KeyValue) {
+			if hiddenExpvars[kv.Key] {
+				return
+			}
+			fmt.Fprintf(w, "%q: %s,\n", kv.Key, kv.Value)
+		})
+		fmt.Fprintf(w, "%q: %s\n}\n", "chat", vars)
+	})
+	return mux
+}
+
+// startStatus serves the status endpoint on StatusPort. It binds to
+// ListenAddr, or to loopback when that is empty, so debug data isn't exposed
+// on every interface by default.
+func (s *Server) startStatus() error {
+	host := s.config.ListenAddr
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(s.config.StatusPort))
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start status server on %s: %w", addr, err)
+	}
+
+	s.status = &http.Server{Handler: s.statusHandler(), ReadHeaderTimeout: 10 * time.Second}
+	log.Printf("Status endpoint at http://%s/debug/vars", ln.Addr())
+
+	go func() {
+		if err := s.status.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Status server error: %v", err)
+		}
+	}()
+	return nil
+}
+
+// stopStatus shuts the status server down if it is running
+func (s *Server) stopStatus() {
+	if s.status == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), statusShutdownTimeout)
+	defer cancel()
+	if err := s.status.Shutdown(ctx); err != nil {
+		log.Printf("Error stopping status server: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStatusVars(t *testing.T) {
+	cfg := testConfig()
+	cfg.Version = "1.2.3"
+	srv, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer srv.Stop()
+
+	rec := httptest.NewRecorder()
+	srv.statusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /debug/vars status = %d", rec.Code)
+	}
+
+	var vars struct {
+		Chat struct {
+			Version           *string `json:"version"`
+			ActiveConnections *int    `json:"active_connections"`
+			Goroutines        int     `json:"goroutines"`
+			MessagesTotal     *int64  `json:"messages_total"`
+		} `json:"chat"`
+		MemStats json.RawMessage `json:"memstats"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("/debug/vars is not valid JSON: %v\n%s", err, rec.Body)
+	}
+
+	if vars.Chat.Version == nil || *vars.Chat.Version != "1.2.3" {
+		t.Errorf("Expected version 1.2.3, got %v", vars.Chat.Version)
+	}
+	if vars.Chat.ActiveConnections == nil || *vars.Chat.ActiveConnections != 0 {
+		t.Errorf("Expected active_connections 0, got %v", vars.Chat.ActiveConnections)
+	}
+	if vars.Chat.MessagesTotal == nil || vars.Chat.Goroutines <= 0 {
+		t.Errorf("Expected messages_total and goroutines, got %+v", vars.Chat)
+	}
+	if len(vars.MemStats) == 0 {
+		t.Error("Expected the standard memstats variable")
+	}
+}
+
+func TestStatusVarsHideCommandLine(t *testing.T) {
+	args := os.Args
+	os.Args = append([]string{"chat-tails"}, "--auth-mode", "password", "--auth-password", "hunter2", "--reserved-nick-password", "swordfish")
+	defer func() { os.Args = args }()
+
+	srv, err := NewServer(testConfig())
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer srv.Stop()
+
+	rec := httptest.NewRecorder()
+	srv.statusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	body := rec.Body.String()
+	for _, secret := range []string{"hunter2", "swordfish", "cmdline"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("/debug/vars contains %q:\n%s", secret, body)
+		}
+	}
+	if !json.Valid(rec.Body.Bytes()) {
+		t.Errorf("/debug/vars is not valid JSON:\n%s", body)
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// connThrottle enforces a minimum interval between connections from the same host
+type connThrottle struct {
+	interval time.Duration
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+}
+
+func newConnThrottle(interval time.Duration) *connThrottle {
+	return &connThrottle{
+		interval: interval,
+		lastSeen: make(map[string]time.Time),
+	}
+}
+
+// allow reports whether a connection from addr may proceed at now, recording it if so
+func (t *connThrottle) allow(addr net.Addr, now time.Time) bool {
+	if t == nil || t.interval <= 0 {
+		return true
+	}
+
+	host := hostOf(addr)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.lastSeen[host]; ok && now.Sub(last) < t.interval {
+		return false
+	}
+	// Forget hosts that are past their interval so the map stays small
+	for h, last := range t.lastSeen {
+		if now.Sub(last) >= t.interval {
+			delete(t.lastSeen, h)
+		}
+	}
+	t.lastSeen[host] = now
+
+	return true
+}
+
+// banList refuses connections from hosts until their ban expires
+type banList struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func newBanList() *banList {
+	return &banList{until: make(map[string]time.Time)}
+}
+
+// ban refuses the host of remoteAddr until the given time
+func (b *banList) ban(remoteAddr string, until time.Time) {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.until[host] = until
+}
+
+// banned reports whether addr's host is banned at now, forgetting expired bans
+func (b *banList) banned(addr net.Addr, now time.Time) bool {
+	host := hostOf(addr)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for h, until := range b.until {
+		if !now.Before(until) {
+			delete(b.until, h)
+		}
+	}
+	_, ok := b.until[host]
+	return ok
+}
+
+// hostOf returns the IP part of a network address
+func hostOf(addr net.Addr) string {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP.String()
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConnThrottle(t *testing.T) {
+	throttle := newConnThrottle(time.Second)
+	alice := &net.TCPAddr{IP: net.ParseIP("100.64.0.1"), Port: 40000}
+	aliceAgain := &net.TCPAddr{IP: net.ParseIP("100.64.0.1"), Port: 40001}
+	bob := &net.TCPAddr{IP: net.ParseIP("100.64.0.2"), Port: 40000}
+	now := time.Now()
+
+	if !throttle.allow(alice, now) {
+		t.Fatal("Expected first connection to be allowed")
+	}
+	if throttle.allow(aliceAgain, now.Add(100*time.Millisecond)) {
+		t.Error("Expected rapid reconnect from the same IP to be throttled")
+	}
+	if !throttle.allow(bob, now.Add(100*time.Millisecond)) {
+		t.Error("Expected connection from another IP to be allowed")
+	}
+	if !throttle.allow(aliceAgain, now.Add(time.Second)) {
+		t.Error("Expected reconnect after the interval to be allowed")
+	}
+}
+
+func TestConnThrottleDisabled(t *testing.T) {
+	throttle := newConnThrottle(0)
+	addr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1}
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if !throttle.allow(addr, now) {
+			t.Fatal("Expected disabled throttle to allow every connection")
+		}
+	}
+}
+
+func TestBanList(t *testing.T) {
+	bans := newBanList()
+	alice := &net.TCPAddr{IP: net.ParseIP("100.64.0.1"), Port: 40001}
+	bob := &net.TCPAddr{IP: net.ParseIP("100.64.0.2"), Port: 40000}
+	now := time.Now()
+
+	bans.ban("100.64.0.1:40000", now.Add(time.Minute))
+
+	if !bans.banned(alice, now) {
+		t.Error("Expected a new connection from the banned IP to be refused")
+	}
+	if bans.banned(bob, now) {
+		t.Error("Expected another IP to be allowed")
+	}
+	if bans.banned(alice, now.Add(time.Minute)) {
+		t.Error("Expected the ban to expire")
+	}
+}
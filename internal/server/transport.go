@@ -0,0 +1,49 @@
+package server
+
+import "net"
+
+// Transport labels for connection and message metrics
+const (
+	TransportTCP       = "tcp"
+	TransportTailscale = "tailscale"
+)
+
+// listener is a net.Listener tagged with the transport its connections arrive over
+type listener struct {
+	net.Listener
+	transport string
+}
+
+// tagListeners labels caller-provided listeners by their address network,
+// e.g. "tcp" for a plain TCP listener
+func tagListeners(lns []net.Listener) []listener {
+	tagged := make([]listener, len(lns))
+	for i, ln := range lns {
+		tagged[i] = listener{Listener: ln, transport: ln.Addr().Network()}
+	}
+	return tagged
+}
+
+// countsByTransport tallies open connections per transport
+func (s *Server) countsByTransport() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, tracked := range s.connections {
+		counts[tracked.transport]++
+	}
+	return counts
+}
+
+// acceptedByTransport returns how many connections each transport has accepted since start
+func (s *Server) acceptedByTransport() map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int64, len(s.accepted))
+	for transport, n := range s.accepted {
+		counts[transport] = n
+	}
+	return counts
+}
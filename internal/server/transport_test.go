@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransportLabels(t *testing.T) {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipe := newPipeListener()
+
+	srv, err := NewServerWithListeners(testConfig(), tcp, pipe)
+	if err != nil {
+		t.Fatalf("NewServerWithListeners() error = %v", err)
+	}
+	if srv.listeners[0].transport != TransportTCP || srv.listeners[1].transport != "pipe" {
+		t.Fatalf("listener transports = %q, %q", srv.listeners[0].transport, srv.listeners[1].transport)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer srv.Stop()
+
+	alice, err := net.Dial("tcp", tcp.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	aliceReader := bufio.NewReader(alice)
+	alice.Write([]byte("alice\r\n"))
+	readUntil(t, alice, aliceReader, "Welcome to Test Room, alice!")
+
+	bob := pipe.dial(t)
+	defer bob.Close()
+	bobReader := bufio.NewReader(bob)
+	go bob.Write([]byte("bob\r\n"))
+	readUntil(t, bob, bobReader, "Welcome to Test Room, bob!")
+	go io.Copy(io.Discard, bobReader)
+
+	if c := srv.Room().GetClient("alice"); c == nil || c.Transport != TransportTCP {
+		t.Errorf("alice's transport = %v, want tcp", c)
+	}
+	if c := srv.Room().GetClient("bob"); c == nil || c.Transport != "pipe" {
+		t.Errorf("bob's transport = %v, want pipe", c)
+	}
+
+	alice.Write([]byte("hello over tcp\r\n"))
+	readUntil(t, alice, aliceReader, "hello over tcp")
+	go bob.Write([]byte("hello over a pipe\r\n"))
+	readUntil(t, alice, aliceReader, "bob: hello over a pipe")
+
+	rec := httptest.NewRecorder()
+	srv.statusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	var vars struct {
+		Chat struct {
+			Active   map[string]int   `json:"active_connections_by_transport"`
+			Accepted map[string]int64 `json:"connections_total_by_transport"`
+			Messages map[string]int64 `json:"messages_by_transport"`
+		} `json:"chat"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("/debug/vars is not valid JSON: %v\n%s", err, rec.Body)
+	}
+
+	for _, transport := range []string{TransportTCP, "pipe"} {
+		if vars.Chat.Active[transport] != 1 {
+			t.Errorf("active_connections_by_transport[%s] = %d, want 1", transport, vars.Chat.Active[transport])
+		}
+		if vars.Chat.Accepted[transport] != 1 {
+			t.Errorf("connections_total_by_transport[%s] = %d, want 1", transport, vars.Chat.Accepted[transport])
+		}
+		if vars.Chat.Messages[transport] != 1 {
+			t.Errorf("messages_by_transport[%s] = %d, want 1", transport, vars.Chat.Messages[transport])
+		}
+	}
+}
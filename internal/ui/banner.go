@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// DefaultBanner is the ASCII art shown to line-mode clients when no custom banner is configured
+const DefaultBanner = `
+╔════════════════════════════════════════════════════════════╗
+║                                                            ║
+║      _____ _           _     _____     _ _                 ║
+║     / ____| |         | |   |_   _|   (_) |                ║
+║    | |    | |__   __ _| |_    | | __ _ _| |___             ║
+║    | |    | '_ \ / _' | __|   | |/ _' | | / __|            ║
+║    | |____| | | | (_| | |_    | | (_| | | \__ \            ║
+║     \_____|_| |_|\__,_|\__|   |_|\__,_|_|_|___/            ║
+║                                                            ║
+╚════════════════════════════════════════════════════════════╝
+`
+
+// LogoBanner is the compact logo shown on the TUI nickname screen
+const LogoBanner = `  _____ _           _     _____     _ _
+ / ____| |         | |   |_   _|   (_) |
+| |    | |__   __ _| |_    | | __ _ _| |___
+| |    | '_ \ / _' | __|   | |/ _' | | / __|
+| |____| | | | (_| | |_    | | (_| | | \__ \
+ \_____|_| |_|\__,_|\__|   |_|\__,_|_|_|___/`
+
+// CompactTitle replaces the banner when the terminal is too narrow for it
+const CompactTitle = "=== Chat Tails ==="
+
+// FormatBanner renders the default banner for a terminal width columns wide
+func FormatBanner(width int) string {
+	return FitBanner(DefaultBanner, width)
+}
+
+// FitBanner centers a multi-line banner within width columns, falling back to a
+// one-line title when the banner doesn't fit. A width of 0 or less means the
+// width is unknown and the banner is returned unchanged.
+func FitBanner(banner string, width int) string {
+	if width <= 0 {
+		return banner
+	}
+
+	lines := strings.Split(banner, "\n")
+	bannerWidth := 0
+	for _, line := range lines {
+		bannerWidth = max(bannerWidth, lipgloss.Width(line))
+	}
+
+	if bannerWidth > width {
+		return centerLine(CompactTitle, width)
+	}
+
+	pad := strings.Repeat(" ", (width-bannerWidth)/2)
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// centerLine centers one line within width columns, trimming it if necessary
+func centerLine(line string, width int) string {
+	if w := lipgloss.Width(line); w < width {
+		return strings.Repeat(" ", (width-w)/2) + line
+	}
+	if runes := []rune(line); len(runes) > width {
+		return string(runes[:width])
+	}
+	return line
+}
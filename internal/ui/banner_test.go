@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestFormatBanner(t *testing.T) {
+	tests := []struct {
+		name        string
+		width       int
+		wantCompact bool
+		wantPad     int
+	}{
+		{"unknown width", 0, false, 0},
+		{"narrow", 40, true, 0},
+		{"normal", 80, false, 9},
+		{"wide", 120, false, 29},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatBanner(tt.width)
+
+			if tt.wantCompact {
+				if strings.Contains(got, "\n") || !strings.Contains(got, CompactTitle) {
+					t.Fatalf("Expected one-line title, got %q", got)
+				}
+				if w := lipgloss.Width(got); w > tt.width {
+					t.Errorf("Compact title is %d columns, wider than %d", w, tt.width)
+				}
+				return
+			}
+
+			for _, line := range strings.Split(got, "\n") {
+				if line == "" {
+					continue
+				}
+				if pad := len(line) - len(strings.TrimLeft(line, " ")); pad < tt.wantPad {
+					t.Errorf("Expected at least %d columns of padding, got %d in %q", tt.wantPad, pad, line)
+				}
+				if tt.width > 0 && lipgloss.Width(line) > tt.width {
+					t.Errorf("Line is wider than %d columns: %q", tt.width, line)
+				}
+			}
+			if !strings.HasPrefix(strings.TrimLeft(got, "\n "), "╔") {
+				t.Errorf("Expected the full banner, got %q", got)
+			}
+		})
+	}
+}
+
+func TestFitBannerCustom(t *testing.T) {
+	got := FitBanner("ab\nabcd", 8)
+	if want := "  ab\n  abcd"; got != want {
+		t.Errorf("FitBanner() = %q, want %q", got, want)
+	}
+}
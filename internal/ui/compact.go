@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Compact formatters for small screens such as mobile SSH apps. They drop
+// boxes, banners and descriptions, and say what they need on as few lines as
+// possible. They carry no ANSI codes, so they suit plain and ANSI clients alike.
+
+// FormatWelcomeMessageCompact formats the welcome message on one line
+func FormatWelcomeMessageCompact(roomName, nickname string) string {
+	return fmt.Sprintf("Welcome to %s, %s! /help for commands.", roomName, nickname)
+}
+
+// FormatHelpCompact lists the commands by name only
+func FormatHelpCompact(hasMOTD bool) string {
+	motd := ""
+	if hasMOTD {
+		motd = " /motd"
+	}
+	return "Commands: /who /me /reply /msg /roll /flip /stats /whoami /seen /topic /ping /feedback /more /search /export /plain /compact /dnd /mute /unmute" + motd + " /help /quit\n" +
+		"Admin: /admin /announce /connections /invite /disconnect /closeroom"
+}
+
+// FormatUserListCompact formats one page of the user list as a single line
+func FormatUserListCompact(users []string, maxUsers, page int) string {
+	pageUsers, page, pages := UserListPage(users, page)
+	line := fmt.Sprintf("Users (%d/%d): %s", len(users), maxUsers, strings.Join(pageUsers, ", "))
+	if footer := UserListFooter(page, pages); footer != "" {
+		line += " (" + footer + ")"
+	}
+	return line
+}
+
+// FormatAnnouncementCompact formats an admin notice on one line
+func FormatAnnouncementCompact(message string) string {
+	return "*** " + message
+}
+
+// FormatMOTDCompact formats the message of the day without a header or box
+func FormatMOTDCompact(motd string) string {
+	return "MOTD: " + motd
+}
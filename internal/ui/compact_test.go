@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// hasBoxDrawing reports whether s contains characters from the Unicode box-drawing block
+func hasBoxDrawing(s string) bool {
+	return strings.ContainsFunc(s, func(r rune) bool { return r >= 0x2500 && r <= 0x257F })
+}
+
+func TestCompactFormattersAreShorter(t *testing.T) {
+	withTrueColor(t)
+
+	users := []string{"carol", "alice", "bob"}
+	tests := []struct {
+		name    string
+		full    string
+		compact string
+	}{
+		{"help", FormatHelp(true), FormatHelpCompact(true)},
+		{"who", Theme{}.FormatUserList("Lobby", users, 10, 1), FormatUserListCompact(users, 10, 1)},
+		{"welcome", DefaultBanner + "\n" + FormatWelcomeMessage("Lobby", "alice"), FormatWelcomeMessageCompact("Lobby", "alice")},
+		{"announcement", FormatAnnouncement("Restart at noon"), FormatAnnouncementCompact("Restart at noon")},
+		{"motd", FormatMOTD("Be nice"), FormatMOTDCompact("Be nice")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.compact)*2 > len(tt.full) {
+				t.Errorf("compact output is %d bytes, want under half of %d:\n%s", len(tt.compact), len(tt.full), tt.compact)
+			}
+			if hasBoxDrawing(tt.compact) {
+				t.Errorf("compact output contains box drawing: %q", tt.compact)
+			}
+			if strings.Contains(tt.compact, "\x1b[") {
+				t.Errorf("compact output contains ANSI codes: %q", tt.compact)
+			}
+		})
+	}
+}
+
+func TestFormatUserListCompact(t *testing.T) {
+	if got, want := FormatUserListCompact([]string{"carol", "alice", "bob"}, 10, 1), "Users (3/10): alice, bob, carol"; got != want {
+		t.Errorf("FormatUserListCompact() = %q, want %q", got, want)
+	}
+
+	var many []string
+	for i := 0; i < UserListPageSize+1; i++ {
+		many = append(many, fmt.Sprintf("user%02d", i))
+	}
+	if got := FormatUserListCompact(many, 50, 2); got != "Users (21/50): user20 (page 2/2)" {
+		t.Errorf("FormatUserListCompact() page 2 = %q", got)
+	}
+}
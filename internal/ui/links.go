@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// urlPattern finds candidate http(s) URLs: the scheme, "://" and everything
+// up to whitespace, quotes, angle brackets or a control character
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'\x00-\x1f\x7f]+`)
+
+// trailingPunctuation is trimmed off a match, since it usually ends the sentence
+const trailingPunctuation = ".,;:!?'\")]}"
+
+// LinkifyURLs wraps each well-formed http or https URL in text in an OSC 8
+// hyperlink, keeping the URL itself as the visible text. Trailing sentence
+// punctuation stays outside the link, and anything that doesn't parse as a URL
+// with a host is left alone.
+func LinkifyURLs(text string) string {
+	return urlPattern.ReplaceAllStringFunc(text, func(match string) string {
+		link := strings.TrimRight(match, trailingPunctuation)
+		// Keep a closing parenthesis that belongs to the URL, as in Wikipedia links
+		for strings.Count(link, "(") > strings.Count(link, ")") && len(link) < len(match) && match[len(link)] == ')' {
+			link += ")"
+		}
+		rest := match[len(link):]
+
+		u, err := url.Parse(link)
+		if err != nil || u.Host == "" {
+			return match
+		}
+		return "\x1b]8;;" + link + "\x1b\\" + link + "\x1b]8;;\x1b\\" + rest
+	})
+}
@@ -0,0 +1,34 @@
+package ui
+
+import "testing"
+
+func TestLinkifyURLs(t *testing.T) {
+	link := func(u string) string {
+		return "\x1b]8;;" + u + "\x1b\\" + u + "\x1b]8;;\x1b\\"
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain url", "see https://example.com/a?b=c", "see " + link("https://example.com/a?b=c")},
+		{"http", "http://example.com", link("http://example.com")},
+		{"trailing period", "go to https://example.com.", "go to " + link("https://example.com") + "."},
+		{"in parentheses", "(https://example.com/x)", "(" + link("https://example.com/x") + ")"},
+		{"parenthesis in url", "https://en.wikipedia.org/wiki/Go_(game)", link("https://en.wikipedia.org/wiki/Go_(game)")},
+		{"two urls", "https://a.example and https://b.example", link("https://a.example") + " and " + link("https://b.example")},
+		{"colons", "meeting at 12:30: bring notes", "meeting at 12:30: bring notes"},
+		{"scheme without host", "http:// is not a link", "http:// is not a link"},
+		{"other scheme", "ftp://example.com stays", "ftp://example.com stays"},
+		{"no escape injection", "https://example.com/\x1b]8;;evil", link("https://example.com/") + "\x1b]8;;evil"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LinkifyURLs(tt.in); got != tt.want {
+				t.Errorf("LinkifyURLs(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
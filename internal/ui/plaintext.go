@@ -1,12 +1,26 @@
 package ui
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // PlainText formatters for Windows telnet and other clients with limited ANSI support
 
 // FormatSystemMessagePlain formats a system message without ANSI codes
-func FormatSystemMessagePlain(message string) string {
-	return "[System] " + message
+func (t Theme) FormatSystemMessagePlain(message string) string {
+	return t.systemLabel() + message
+}
+
+// FormatJoinMessagePlain formats a join notice without ANSI codes. It uses an
+// ASCII arrow because legacy telnet code pages have no "→".
+func (t Theme) FormatJoinMessagePlain(message string) string {
+	return t.tag(SystemTag) + "-> " + message
+}
+
+// FormatLeaveMessagePlain formats a leave notice without ANSI codes
+func (t Theme) FormatLeaveMessagePlain(message string) string {
+	return t.tag(SystemTag) + "<- " + message
 }
 
 // FormatUserMessagePlain formats a user message without ANSI codes
@@ -14,14 +28,24 @@ func FormatUserMessagePlain(username, message, timestamp string) string {
 	return "[" + timestamp + "] " + username + ": " + message
 }
 
+// FormatReplyMessagePlain formats a reply with a condensed quote without ANSI codes
+func FormatReplyMessagePlain(username, quote, message, timestamp string) string {
+	return "[" + timestamp + "] " + username + " (re: '" + QuoteSnippet(quote) + "'): " + message
+}
+
+// FormatPrivateMessagePlain formats a direct message without ANSI codes
+func (t Theme) FormatPrivateMessagePlain(username string, recipients []string, message, timestamp string) string {
+	return t.tag(PrivateTag) + "[" + timestamp + "] " + username + " (private to " + strings.Join(recipients, ", ") + "): " + message
+}
+
 // FormatSelfMessagePlain formats the user's own message without ANSI codes
-func FormatSelfMessagePlain(message, timestamp string) string {
-	return "[" + timestamp + "] You: " + message
+func (t Theme) FormatSelfMessagePlain(message, timestamp string) string {
+	return t.tag(SelfTag) + "[" + timestamp + "] You: " + message
 }
 
 // FormatActionMessagePlain formats an action message without ANSI codes
-func FormatActionMessagePlain(username, action string) string {
-	return "* " + username + " " + action
+func (t Theme) FormatActionMessagePlain(username, action string) string {
+	return t.tag(ActionTag) + "* " + username + " " + action
 }
 
 // FormatTitlePlain formats a title without ANSI codes
@@ -29,23 +53,57 @@ func FormatTitlePlain(title string) string {
 	return "=== " + title + " ==="
 }
 
-// FormatHelpPlain formats the help message without ANSI codes
-func FormatHelpPlain() string {
+// FormatHelpPlain formats the help message without ANSI codes; /motd is
+// listed only when the server has one
+func FormatHelpPlain(hasMOTD bool) string {
+	motd := ""
+	if hasMOTD {
+		motd = "  /motd - Show the message of the day again\n"
+	}
 	return `
 Available Commands:
-  /who - Show all users in the room
+  /who [page] - Show users in the room
   /me <action> - Perform an action
-  /help - Show this help message
-  /quit - Leave the chat
+  /reply <id> <message> - Reply to a message by its #ID
+  /msg <nick>[,<nick>...] <message> - Send a private message
+  /roll [NdM] - Roll dice (default 1d6)
+  /flip - Flip a coin
+  /stats - Show room activity
+  /whoami - Show your nickname, color and identity
+  /seen <nick> - Show when a user was last here
+  /topic [text|clear] - Show or change the topic
+  /ping - Measure round-trip time (press Enter to answer)
+  /feedback <text> - Send feedback to the server operator
+  /more - Show the next page of long output
+  /search <term> [--since 1h] - Search history
+  /export [text|json] - Dump history for saving
+  /plain on|off - Toggle plain-text output
+  /compact on|off - Dense output for small screens
+  /dnd on|off - Hide join/leave notices
+  /mute, /unmute - Pause and resume incoming chat
+  /admin <token> - Become an admin
+  /announce <text> - Broadcast a notice (admins only)
+  /connections - List connections (admins only)
+  /invite - Show a connect command to share (admins only)
+  /disconnect <addr> - Drop a connection (admins only)
+  /closeroom <name> - Disconnect everyone from the room (admins only)
+` + motd + `  /help - Show this help message
+  /quit [reason] - Leave the chat
 `
 }
 
 // FormatUserListPlain formats the user list without ANSI codes
-func FormatUserListPlain(roomName string, users []string, maxUsers int) string {
+func FormatUserListPlain(roomName string, users []string, maxUsers, page int) string {
 	content := fmt.Sprintf("Users in %s (%d/%d):\n", roomName, len(users), maxUsers)
-	for _, user := range users {
+
+	pageUsers, page, pages := UserListPage(users, page)
+	for _, user := range pageUsers {
 		content += "- " + user + "\n"
 	}
+
+	if footer := UserListFooter(page, pages); footer != "" {
+		content += footer + "\n"
+	}
 	return content
 }
 
@@ -53,3 +111,38 @@ func FormatUserListPlain(roomName string, users []string, maxUsers int) string {
 func FormatWelcomeMessagePlain(roomName, nickname string) string {
 	return fmt.Sprintf("Welcome to %s, %s!\n\nType a message and press Enter to send. Use /help to see available commands.", roomName, nickname)
 }
+
+// FormatStatsPlain formats the room activity statistics without ANSI codes
+func FormatStatsPlain(roomName string, total int64, lastMinute, online, peak int) string {
+	return fmt.Sprintf("Stats for %s:\n- Messages since start: %d\n- Messages last minute: %d\n- Online now: %d\n- Peak online: %d\n",
+		roomName, total, lastMinute, online, peak)
+}
+
+// FormatWhoamiPlain formats the caller's own session details without ANSI codes
+func FormatWhoamiPlain(nickname, color, identity, roomName, joined string) string {
+	if identity == "" {
+		identity = "not verified"
+	}
+	return fmt.Sprintf("You are %s:\n- Color: %s\n- Identity: %s\n- Room: %s\n- Joined: %s\n",
+		nickname, color, identity, roomName, joined)
+}
+
+// FormatSeenPlain formats a /seen answer without ANSI codes
+func (t Theme) FormatSeenPlain(nickname, seen string) string {
+	return t.systemLabel() + fmt.Sprintf("%s was last seen %s.", nickname, seen)
+}
+
+// FormatPinnedMessagePlain formats a pinned operator note without ANSI codes
+func (t Theme) FormatPinnedMessagePlain(message string) string {
+	return t.tag(SystemTag) + "[pinned] " + message
+}
+
+// FormatAnnouncementPlain formats a server-wide admin notice without ANSI codes
+func FormatAnnouncementPlain(message string) string {
+	return "*** ANNOUNCEMENT: " + message + " ***"
+}
+
+// FormatMOTDPlain formats the message of the day without ANSI codes
+func FormatMOTDPlain(motd string) string {
+	return "--- Message of the Day ---\n" + motd + "\n---"
+}
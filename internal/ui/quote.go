@@ -0,0 +1,15 @@
+package ui
+
+import "strings"
+
+// maxQuoteLen is the number of characters kept when quoting a message in a reply
+const maxQuoteLen = 30
+
+// QuoteSnippet condenses a message to a single short line for reply quotes
+func QuoteSnippet(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxQuoteLen {
+		return string(runes[:maxQuoteLen]) + "..."
+	}
+	return text
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -13,9 +14,10 @@ var (
 	special   = lipgloss.AdaptiveColor{Light: "#43BF6D", Dark: "#2B5F3A"}
 	accent    = lipgloss.AdaptiveColor{Light: "#1D9BF0", Dark: "#1D9BF0"}
 	warning   = lipgloss.AdaptiveColor{Light: "#F25D94", Dark: "#F25D94"}
+	dim       = lipgloss.AdaptiveColor{Light: "#767676", Dark: "#8A8A8A"}
 )
 
-// UserColors is a list of colors for different users
+// UserColors is the default palette for nicknames
 var UserColors = []string{
 	"#1D9BF0", // Blue
 	"#F25D94", // Pink
@@ -27,8 +29,9 @@ var UserColors = []string{
 	"#FF595E", // Red
 }
 
-// GetUserColor returns a consistent color for a username based on hash
-func GetUserColor(username string) string {
+// UserColor returns a consistent color for a username based on hash
+func (t Theme) UserColor(username string) string {
+	colors := t.UserColors()
 	hash := 0
 	for _, c := range username {
 		hash = int(c) + ((hash << 5) - hash)
@@ -36,73 +39,114 @@ func GetUserColor(username string) string {
 	if hash < 0 {
 		hash = -hash
 	}
-	return UserColors[hash%len(UserColors)]
+	return colors[hash%len(colors)]
 }
 
 // Style definitions
 var (
 	// Base styles
 	BaseStyle = lipgloss.NewStyle().
-		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(subtle)
+			BorderStyle(lipgloss.RoundedBorder()).
+			BorderForeground(subtle)
 
 	// Headers
 	HeaderStyle = lipgloss.NewStyle().
-		Bold(true).
-		Foreground(highlight).
-		Padding(0, 1)
+			Bold(true).
+			Foreground(highlight).
+			Padding(0, 1)
 
 	// Text styles
 	SystemStyle = lipgloss.NewStyle().
-		Foreground(special).
-		Bold(true)
+			Foreground(special).
+			Bold(true)
 
 	UserStyle = lipgloss.NewStyle().
-		Foreground(accent).
-		Bold(true)
+			Foreground(accent).
+			Bold(true)
 
 	SelfStyle = lipgloss.NewStyle().
-		Foreground(highlight).
-		Bold(true)
+			Foreground(highlight).
+			Bold(true)
 
 	ActionStyle = lipgloss.NewStyle().
-		Foreground(warning).
-		Italic(true)
+			Foreground(warning).
+			Italic(true)
+
+	// Presence notices are quieter than other system messages so they're easy to skim past
+	JoinStyle = lipgloss.NewStyle().
+			Foreground(special)
+
+	LeaveStyle = lipgloss.NewStyle().
+			Foreground(dim).
+			Faint(true)
+
+	// Pinned notes stand out from the history replayed after them
+	PinnedStyle = lipgloss.NewStyle().
+			Foreground(accent).
+			Bold(true)
 
 	// UI components
 	BoxStyle = lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(subtle).
-		Padding(0, 1)
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(subtle).
+			Padding(0, 1)
 
 	InputStyle = lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(highlight).
-		Padding(0, 1)
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(highlight).
+			Padding(0, 1)
 )
 
 // FormatSystemMessage formats a system message
-func FormatSystemMessage(message string) string {
-	return SystemStyle.Render("[System] " + message)
+func (t Theme) FormatSystemMessage(message string) string {
+	return t.SystemStyle().Render(t.systemLabel() + message)
+}
+
+// FormatJoinMessage formats a join notice as a green "→ " line
+func (t Theme) FormatJoinMessage(message string) string {
+	return JoinStyle.Render(t.tag(SystemTag) + "→ " + message)
+}
+
+// FormatLeaveMessage formats a leave notice as a dim "← " line
+func (t Theme) FormatLeaveMessage(message string) string {
+	return LeaveStyle.Render(t.tag(SystemTag) + "← " + message)
 }
 
 // FormatUserMessage formats a user message
-func FormatUserMessage(username, message, timestamp string) string {
-	userColor := GetUserColor(username)
+func (t Theme) FormatUserMessage(username, message, timestamp string) string {
+	userColor := t.UserColor(username)
 	style := lipgloss.NewStyle().Foreground(lipgloss.Color(userColor)).Bold(true)
 	return style.Render("["+timestamp+"] "+username+": ") + message
 }
 
+// FormatReplyMessage formats a reply with a condensed quote of the original message
+func (t Theme) FormatReplyMessage(username, quote, message, timestamp string) string {
+	userColor := t.UserColor(username)
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(userColor)).Bold(true)
+	quoteStyle := lipgloss.NewStyle().Foreground(subtle).Italic(true)
+	return style.Render("["+timestamp+"] "+username) + " " +
+		quoteStyle.Render("(re: '"+QuoteSnippet(quote)+"')") + style.Render(": ") + message
+}
+
+// FormatPrivateMessage formats a direct message, listing everyone it was sent to
+func (t Theme) FormatPrivateMessage(username string, recipients []string, message, timestamp string) string {
+	userColor := t.UserColor(username)
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(userColor)).Bold(true)
+	privateStyle := lipgloss.NewStyle().Foreground(warning).Italic(true)
+	return style.Render(t.tag(PrivateTag)+"["+timestamp+"] "+username) + " " +
+		privateStyle.Render("(private to "+strings.Join(recipients, ", ")+")") + style.Render(": ") + message
+}
+
 // FormatSelfMessage formats the user's own message
-func FormatSelfMessage(message, timestamp string) string {
-	return SelfStyle.Render("["+timestamp+"] You: ") + message
+func (t Theme) FormatSelfMessage(message, timestamp string) string {
+	return SelfStyle.Render(t.tag(SelfTag)+"["+timestamp+"] You: ") + message
 }
 
 // FormatActionMessage formats an action message
-func FormatActionMessage(username, action string) string {
-	userColor := GetUserColor(username)
+func (t Theme) FormatActionMessage(username, action string) string {
+	userColor := t.UserColor(username)
 	style := lipgloss.NewStyle().Foreground(lipgloss.Color(userColor)).Italic(true)
-	return style.Render("* " + username + " " + action)
+	return style.Render(t.tag(ActionTag) + "* " + username + " " + action)
 }
 
 // FormatTitle formats a title
@@ -115,31 +159,64 @@ func CreateColoredBox(title, content string, width int) string {
 	box := BoxStyle.Width(width)
 	return box.Render(
 		HeaderStyle.Render(title) + "\n\n" +
-		content,
+			content,
 	)
 }
 
-// FormatHelp formats the help message
-func FormatHelp() string {
+// FormatHelp formats the help message; /motd is listed only when the server has one
+func FormatHelp(hasMOTD bool) string {
+	motd := ""
+	if hasMOTD {
+		motd = "/motd - Show the message of the day again\n"
+	}
 	return BoxStyle.Render(
 		HeaderStyle.Render("Available Commands:") + "\n" +
-			"/who - Show all users in the room\n" +
+			"/who [page] - Show users in the room\n" +
 			"/me <action> - Perform an action\n" +
+			"/reply <id> <message> - Reply to a message by its #ID\n" +
+			"/msg <nick>[,<nick>...] <message> - Send a private message\n" +
+			"/roll [NdM] - Roll dice (default 1d6)\n" +
+			"/flip - Flip a coin\n" +
+			"/stats - Show room activity\n" +
+			"/whoami - Show your nickname, color and identity\n" +
+			"/seen <nick> - Show when a user was last here\n" +
+			"/topic [text|clear] - Show or change the topic\n" +
+			"/ping - Measure round-trip time (press Enter to answer)\n" +
+			"/feedback <text> - Send feedback to the server operator\n" +
+			"/more - Show the next page of long output\n" +
+			"/search <term> [--since 1h] - Search history\n" +
+			"/export [text|json] - Dump history for saving\n" +
+			"/plain on|off - Toggle plain-text output\n" +
+			"/compact on|off - Dense output for small screens\n" +
+			"/dnd on|off - Hide join/leave notices\n" +
+			"/mute, /unmute - Pause and resume incoming chat\n" +
+			"/admin <token> - Become an admin\n" +
+			"/announce <text> - Broadcast a notice (admins only)\n" +
+			"/connections - List connections (admins only)\n" +
+			"/invite - Show a connect command to share (admins only)\n" +
+			"/disconnect <addr> - Drop a connection (admins only)\n" +
+			"/closeroom <name> - Disconnect everyone from the room (admins only)\n" +
+			motd +
 			"/help - Show this help message\n" +
-			"/quit - Leave the chat",
+			"/quit [reason] - Leave the chat",
 	)
 }
 
 // FormatUserList formats the user list
-func FormatUserList(roomName string, users []string, maxUsers int) string {
+func (t Theme) FormatUserList(roomName string, users []string, maxUsers, page int) string {
 	content := HeaderStyle.Render("Users in "+roomName+" ("+lipgloss.NewStyle().Foreground(accent).Render(fmt.Sprintf("%d/%d", len(users), maxUsers))+"):") + "\n"
 
-	for _, user := range users {
-		userColor := GetUserColor(user)
+	pageUsers, page, pages := UserListPage(users, page)
+	for _, user := range pageUsers {
+		userColor := t.UserColor(user)
 		style := lipgloss.NewStyle().Foreground(lipgloss.Color(userColor)).Bold(true)
 		content += "- " + style.Render(user) + "\n"
 	}
 
+	if footer := UserListFooter(page, pages); footer != "" {
+		content += t.SystemStyle().Render(footer) + "\n"
+	}
+
 	return BoxStyle.Render(content)
 }
 
@@ -147,4 +224,54 @@ func FormatUserList(roomName string, users []string, maxUsers int) string {
 func FormatWelcomeMessage(roomName, nickname string) string {
 	return HeaderStyle.Render("Welcome to "+roomName+", "+nickname+"!") + "\n\n" +
 		"Type a message and press Enter to send. Use /help to see available commands."
-}
\ No newline at end of file
+}
+
+// FormatStats formats the room activity statistics
+func FormatStats(roomName string, total int64, lastMinute, online, peak int) string {
+	return BoxStyle.Render(
+		HeaderStyle.Render("Stats for "+roomName+":") + "\n" +
+			fmt.Sprintf("Messages since start: %d\n", total) +
+			fmt.Sprintf("Messages last minute: %d\n", lastMinute) +
+			fmt.Sprintf("Online now: %d\n", online) +
+			fmt.Sprintf("Peak online: %d", peak),
+	)
+}
+
+// FormatSeen formats a /seen answer with the nickname in its user color
+func (t Theme) FormatSeen(nickname, seen string) string {
+	name := lipgloss.NewStyle().Foreground(lipgloss.Color(t.UserColor(nickname))).Bold(true).Render(nickname)
+	return t.SystemStyle().Render(t.systemLabel()) + name + t.SystemStyle().Render(" was last seen "+seen+".")
+}
+
+// FormatWhoami formats the caller's own session details for /whoami
+func FormatWhoami(nickname, color, identity, roomName, joined string) string {
+	if identity == "" {
+		identity = "not verified"
+	}
+	name := lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Bold(true).Render(nickname)
+	return BoxStyle.Render(
+		HeaderStyle.Render("You are "+name+":") + "\n" +
+			fmt.Sprintf("Color: %s\n", color) +
+			fmt.Sprintf("Identity: %s\n", identity) +
+			fmt.Sprintf("Room: %s\n", roomName) +
+			fmt.Sprintf("Joined: %s", joined),
+	)
+}
+
+// FormatPinnedMessage formats a pinned operator note, set apart from other system messages
+func (t Theme) FormatPinnedMessage(message string) string {
+	return PinnedStyle.Render(t.tag(SystemTag) + "[pinned] " + message)
+}
+
+// announcementWidth is the box width used for admin announcements
+const announcementWidth = 60
+
+// FormatAnnouncement formats a server-wide admin notice in a bold bordered box
+func FormatAnnouncement(message string) string {
+	return CreateColoredBox("Announcement", lipgloss.NewStyle().Bold(true).Render(message), announcementWidth)
+}
+
+// FormatMOTD formats the message of the day
+func FormatMOTD(motd string) string {
+	return BoxStyle.Render(HeaderStyle.Render("Message of the Day") + "\n" + motd)
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// withTrueColor renders styles with 24-bit color on a dark background for the test
+func withTrueColor(t *testing.T) {
+	t.Helper()
+	profile, dark := lipgloss.ColorProfile(), lipgloss.HasDarkBackground()
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	lipgloss.SetHasDarkBackground(true)
+	t.Cleanup(func() {
+		lipgloss.SetColorProfile(profile)
+		lipgloss.SetHasDarkBackground(dark)
+	})
+}
+
+func TestJoinLeaveMessages(t *testing.T) {
+	withTrueColor(t)
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		// Join is green, leave is faint gray
+		{"join", Theme{}.FormatJoinMessage("alice has joined the room"), "\x1b[38;2;43;95;58m→ alice has joined the room\x1b[0m"},
+		{"leave", Theme{}.FormatLeaveMessage("alice has left the room"), "\x1b[2;38;2;138;138;138m← alice has left the room\x1b[0m"},
+		{"join plain", Theme{}.FormatJoinMessagePlain("alice has joined the room"), "-> alice has joined the room"},
+		{"leave plain", Theme{}.FormatLeaveMessagePlain("alice has left the room"), "<- alice has left the room"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	accessible := Theme{Accessible: true}
+	if got := accessible.FormatJoinMessagePlain("alice has joined the room"); !strings.HasPrefix(got, SystemTag+"-> ") {
+		t.Errorf("Accessible join notice = %q, want the %q tag", got, SystemTag)
+	}
+}
@@ -0,0 +1,72 @@
+package ui
+
+import "github.com/charmbracelet/lipgloss"
+
+// Theme selects how messages are rendered. The zero Theme is the default look;
+// Accessible switches to a color-blind-safe palette, high-contrast system text
+// and text prefixes on system, action, private and own messages. It applies to
+// ANSI and plain-text output alike.
+type Theme struct {
+	Accessible bool
+}
+
+// accessibleUserColors is the Okabe-Ito color-blind-safe palette. It leaves
+// out green so no nickname can be confused with system messages.
+var accessibleUserColors = []string{
+	"#56B4E9", // Sky blue
+	"#E69F00", // Orange
+	"#F0E442", // Yellow
+	"#0072B2", // Blue
+	"#D55E00", // Vermillion
+	"#CC79A7", // Reddish purple
+}
+
+// accessibleSystemStyle is the high-contrast system text used by the accessibility theme
+var accessibleSystemStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.AdaptiveColor{Light: "#000000", Dark: "#FFFFFF"}).
+	Bold(true)
+
+// Text prefixes used in accessibility mode so meaning never depends on color
+const (
+	SystemTag  = "[SYS] "
+	SelfTag    = "[YOU] "
+	ActionTag  = "[ACT] "
+	PrivateTag = "[PM] "
+)
+
+// UserColors returns the nickname palette for the theme
+func (t Theme) UserColors() []string {
+	if t.Accessible {
+		return accessibleUserColors
+	}
+	return UserColors
+}
+
+// SystemStyle returns the style used for system text in the theme
+func (t Theme) SystemStyle() lipgloss.Style {
+	if t.Accessible {
+		return accessibleSystemStyle
+	}
+	return SystemStyle
+}
+
+// tag returns prefix in accessibility mode and an empty string otherwise
+func (t Theme) tag(prefix string) string {
+	if t.Accessible {
+		return prefix
+	}
+	return ""
+}
+
+// systemLabel is the label that starts every system message
+func (t Theme) systemLabel() string {
+	if t.Accessible {
+		return SystemTag
+	}
+	return "[System] "
+}
+
+// MarkSelf prefixes a line written by the reader themselves in accessibility mode
+func (t Theme) MarkSelf(line string) string {
+	return t.tag(SelfTag) + line
+}
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAccessiblePrefixes(t *testing.T) {
+	theme := Theme{Accessible: true}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"system", theme.FormatSystemMessage("alice joined"), SystemTag},
+		{"system plain", theme.FormatSystemMessagePlain("alice joined"), SystemTag},
+		{"self", theme.FormatSelfMessage("hi", "12:00"), SelfTag},
+		{"self plain", theme.FormatSelfMessagePlain("hi", "12:00"), SelfTag},
+		{"action", theme.FormatActionMessage("alice", "waves"), ActionTag},
+		{"action plain", theme.FormatActionMessagePlain("alice", "waves"), ActionTag},
+		{"private", theme.FormatPrivateMessage("alice", []string{"bob"}, "hi", "12:00"), PrivateTag},
+		{"private plain", theme.FormatPrivateMessagePlain("alice", []string{"bob"}, "hi", "12:00"), PrivateTag},
+		{"marked self", theme.MarkSelf("[12:00] alice: hi"), SelfTag},
+	}
+	for _, tt := range tests {
+		if !strings.Contains(tt.got, tt.want) {
+			t.Errorf("%s: %q does not contain %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	for _, color := range theme.UserColors() {
+		if color == "#43BF6D" {
+			t.Errorf("accessible palette contains the system green %s", color)
+		}
+	}
+}
+
+func TestDefaultThemeHasNoPrefixes(t *testing.T) {
+	var theme Theme
+
+	if got := theme.FormatSystemMessagePlain("hi"); got != "[System] hi" {
+		t.Errorf("FormatSystemMessagePlain() = %q, want %q", got, "[System] hi")
+	}
+	if got := theme.FormatActionMessagePlain("alice", "waves"); got != "* alice waves" {
+		t.Errorf("FormatActionMessagePlain() = %q, want %q", got, "* alice waves")
+	}
+	if got := theme.MarkSelf("line"); got != "line" {
+		t.Errorf("MarkSelf() = %q, want %q", got, "line")
+	}
+	if !slices.Equal(theme.UserColors(), UserColors) {
+		t.Errorf("UserColors() = %v, want the default palette", theme.UserColors())
+	}
+}
+
+func TestThemesDoNotShareState(t *testing.T) {
+	// Two rooms on one server can use different themes at the same time
+	accessible := Theme{Accessible: true}
+	var standard Theme
+
+	if got := accessible.FormatSystemMessagePlain("hi"); got != SystemTag+"hi" {
+		t.Errorf("accessible FormatSystemMessagePlain() = %q, want %q", got, SystemTag+"hi")
+	}
+	if got := standard.FormatSystemMessagePlain("hi"); got != "[System] hi" {
+		t.Errorf("default FormatSystemMessagePlain() = %q, want %q", got, "[System] hi")
+	}
+}
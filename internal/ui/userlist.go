@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// UserListPageSize is the number of nicknames shown per /who page
+const UserListPageSize = 20
+
+// SortNicknames orders nicknames case-insensitively, breaking ties by exact value
+func SortNicknames(names []string) {
+	sort.Slice(names, func(i, j int) bool {
+		a, b := strings.ToLower(names[i]), strings.ToLower(names[j])
+		if a != b {
+			return a < b
+		}
+		return names[i] < names[j]
+	})
+}
+
+// UserListPage sorts users alphabetically and returns the requested 1-based page,
+// clamped to the valid range, along with the page number used and the page count
+func UserListPage(users []string, page int) ([]string, int, int) {
+	sorted := append([]string(nil), users...)
+	SortNicknames(sorted)
+
+	pages := (len(sorted) + UserListPageSize - 1) / UserListPageSize
+	if pages == 0 {
+		pages = 1
+	}
+	if page < 1 {
+		page = 1
+	}
+	if page > pages {
+		page = pages
+	}
+
+	start := (page - 1) * UserListPageSize
+	end := min(start+UserListPageSize, len(sorted))
+	return sorted[start:end], page, pages
+}
+
+// UserListFooter describes the current page, or is empty when everything fits on one
+func UserListFooter(page, pages int) string {
+	if pages <= 1 {
+		return ""
+	}
+	if page < pages {
+		return fmt.Sprintf("page %d/%d — /who %d for more", page, pages, page+1)
+	}
+	return fmt.Sprintf("page %d/%d", page, pages)
+}
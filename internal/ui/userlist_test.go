@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUserListPage(t *testing.T) {
+	users := make([]string, 0, 45)
+	for i := 45; i > 0; i-- {
+		users = append(users, fmt.Sprintf("user%02d", i))
+	}
+
+	tests := []struct {
+		name      string
+		page      int
+		wantFirst string
+		wantLen   int
+		wantPage  int
+		wantPages int
+	}{
+		{"first page", 1, "user01", 20, 1, 3},
+		{"second page", 2, "user21", 20, 2, 3},
+		{"last page", 3, "user41", 5, 3, 3},
+		{"past the end clamps", 9, "user41", 5, 3, 3},
+		{"zero clamps", 0, "user01", 20, 1, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, page, pages := UserListPage(users, tt.page)
+			if len(got) != tt.wantLen || got[0] != tt.wantFirst || page != tt.wantPage || pages != tt.wantPages {
+				t.Errorf("UserListPage(%d) = %d users from %q, page %d/%d; want %d from %q, page %d/%d",
+					tt.page, len(got), got[0], page, pages, tt.wantLen, tt.wantFirst, tt.wantPage, tt.wantPages)
+			}
+		})
+	}
+
+	if users[0] != "user45" {
+		t.Error("UserListPage must not reorder the caller's slice")
+	}
+}
+
+func TestFormatUserListPlainSorted(t *testing.T) {
+	got := FormatUserListPlain("Lobby", []string{"carol", "alice", "bob"}, 10, 1)
+	want := "Users in Lobby (3/10):\n- alice\n- bob\n- carol\n"
+	if got != want {
+		t.Errorf("FormatUserListPlain() = %q, want %q", got, want)
+	}
+
+	pageUsers, _, _ := UserListPage([]string{"b", "a"}, 1)
+	if !reflect.DeepEqual(pageUsers, []string{"a", "b"}) {
+		t.Errorf("Expected alphabetical order, got %v", pageUsers)
+	}
+}
+
+func TestFormatUserListPlainFooter(t *testing.T) {
+	users := make([]string, UserListPageSize+1)
+	for i := range users {
+		users[i] = fmt.Sprintf("u%02d", i)
+	}
+
+	first := FormatUserListPlain("Lobby", users, 50, 1)
+	if !strings.Contains(first, "(21/50)") || !strings.HasSuffix(first, "page 1/2 — /who 2 for more\n") {
+		t.Errorf("Unexpected first page: %q", first)
+	}
+
+	last := FormatUserListPlain("Lobby", users, 50, 2)
+	if !strings.HasSuffix(last, "- u20\npage 2/2\n") {
+		t.Errorf("Unexpected last page: %q", last)
+	}
+}